@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/database"
+)
+
+const usage = `Usage: migrate <command> [version]
+
+Commands:
+  up [version]     apply pending migrations, up to version if given
+  down [version]   undo migrations above version (default: undo the latest one)
+  status           list migrations and whether each is applied`
+
+func main() {
+	// Same .env lookup as the server
+	for _, path := range []string{".env", "../.env", "../../.env"} {
+		if err := godotenv.Load(path); err == nil {
+			log.Printf("Loaded environment variables from: %s", path)
+			break
+		}
+	}
+
+	if len(os.Args) < 2 || len(os.Args) > 3 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	version := -1
+	if len(os.Args) == 3 {
+		v, err := strconv.Atoi(os.Args[2])
+		if err != nil || v < 0 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		version = v
+	}
+
+	cfg := config.LoadDatabaseConfig()
+	db, err := database.Open(&cfg)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	switch os.Args[1] {
+	case "up":
+		if version < 0 {
+			version = 0
+		}
+		err = db.MigrateUp(version)
+	case "down":
+		if version < 0 {
+			version, err = previousVersion(db)
+			if err != nil {
+				break
+			}
+		}
+		err = db.MigrateDown(version)
+	case "status":
+		err = printStatus(db)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+}
+
+// previousVersion is the version below the latest applied migration
+func previousVersion(db *database.DB) (int, error) {
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		return 0, err
+	}
+	latest, previous := 0, 0
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			previous, latest = latest, status.Version
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("no migrations are applied")
+	}
+	return previous, nil
+}
+
+func printStatus(db *database.DB) error {
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Drifted {
+			state += " (changed since applied)"
+		}
+		fmt.Printf("%04d  %-30s %s\n", status.Version, status.Name, state)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	_ "time/tzdata" // embedded zone database for digest scheduling on minimal images
 
 	"github.com/joho/godotenv"
 	"shares-alert-backend/internal/app"
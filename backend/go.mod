@@ -3,15 +3,18 @@ module shares-alert-backend
 go 1.21
 
 require (
+	github.com/emersion/go-msgauth v0.6.8
 	github.com/go-chi/chi/v5 v5.0.10
 	github.com/go-chi/cors v1.2.1
 	github.com/go-chi/render v1.0.3
+	github.com/go-sql-driver/mysql v1.7.1
 	github.com/golang-jwt/jwt/v5 v5.2.0
 	github.com/google/uuid v1.5.0
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.19
 	github.com/redis/go-redis/v9 v9.3.1
+	golang.org/x/crypto v0.16.0
 	golang.org/x/oauth2 v0.15.0
 )
 
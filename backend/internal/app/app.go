@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,15 +16,21 @@ import (
 	"shares-alert-backend/internal/config"
 	"shares-alert-backend/internal/database"
 	"shares-alert-backend/internal/handlers"
+	"shares-alert-backend/internal/models"
 	"shares-alert-backend/internal/repository"
 	"shares-alert-backend/internal/services"
 )
 
 type App struct {
-	config       *config.Config
-	db           *database.DB
-	router       *chi.Mux
-	alertService *services.AlertService
+	config           *config.Config
+	db               *database.DB
+	router           *chi.Mux
+	alertService     *services.AlertService
+	digestService    *services.DigestService
+	outboxService    *services.OutboxService
+	lifecycleService *services.LifecycleService
+	accountService   *services.AccountService
+	portfolioService *services.PortfolioService
 }
 
 func New(cfg *config.Config) (*App, error) {
@@ -47,37 +54,105 @@ func New(cfg *config.Config) (*App, error) {
 	}
 
 	// Initialize repositories
-	userRepo := repository.NewUserRepository(db.DB)
-	alertRepo := repository.NewAlertRepository(db.DB)
+	userRepo := repository.NewUserRepository(db)
+	alertRepo := repository.NewAlertRepository(db)
+	digestRepo := repository.NewDigestRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	lifecycleRepo := repository.NewLifecycleRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	oauthStateRepo := repository.NewOAuthStateRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	magicLinkRepo := repository.NewMagicLinkRepository(db)
+	streamTicketRepo := repository.NewStreamTicketRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	portfolioRepo := repository.NewPortfolioRepository(db)
+	watchlistRepo := repository.NewWatchlistRepository(db)
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, &cfg.Auth)
-	emailService := services.NewEmailService(&cfg.Email)
+	actionLinks := services.NewActionLinks(&cfg.Email)
+	emailSender, err := services.NewEmailSender(&cfg.Email)
+	if err != nil {
+		return nil, err
+	}
+	emailService, err := services.NewEmailService(&cfg.Email, actionLinks, emailSender)
+	if err != nil {
+		return nil, err
+	}
+	lifecycleService := services.NewLifecycleService(lifecycleRepo, userRepo, emailService, &cfg.Lifecycle)
+	jwtKeys, err := services.NewJWTKeySet(&cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+	authService := services.NewAuthService(userRepo, sessionRepo, oauthStateRepo, identityRepo, magicLinkRepo, streamTicketRepo, db,
+		lifecycleService, emailService, jwtKeys, &cfg.Auth)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo)
+	if err := authService.BootstrapAdmins(context.Background()); err != nil {
+		log.Printf("Failed to bootstrap admins: %v", err)
+	}
 	stockCacheTTL := time.Duration(cfg.Cache.StockCacheTTL) * time.Minute
 	stockService := services.NewStockService(&cfg.External, redisCache, stockCacheTTL)
-	alertService := services.NewAlertService(alertRepo, userRepo, stockService, emailService)
+	outboxService := services.NewOutboxService(outboxRepo, emailService, &cfg.Outbox)
+	digestService := services.NewDigestService(digestRepo, userRepo, alertRepo, stockService, emailService, &cfg.Digest)
+	notificationService := services.NewNotificationService(notificationRepo)
+	emailActionService := services.NewEmailActionService(alertRepo, userRepo, emailService, digestService, actionLinks)
+	portfolioService := services.NewPortfolioService(portfolioRepo, stockService, &cfg.Portfolio)
+	alertService := services.NewAlertService(alertRepo, userRepo, stockService, emailService, digestService, notificationService,
+		portfolioService, db)
+	watchlistService := services.NewWatchlistService(watchlistRepo, alertRepo, stockService)
 	cacheService := services.NewCacheService(redisCache)
+	accountService := services.NewAccountService(accountRepo, userRepo, alertRepo, digestRepo, notificationRepo, outboxRepo,
+		lifecycleRepo, identityRepo, apiKeyRepo, portfolioRepo, watchlistRepo, emailService, outboxService, &cfg.Account)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, apiKeyService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
 	stockHandler := handlers.NewStockHandler(stockService)
 	alertHandler := handlers.NewAlertHandler(alertService)
 	userHandler := handlers.NewUserHandler(userRepo)
 	cacheHandler := handlers.NewCacheHandler(cacheService, stockService)
+	outboxHandler := handlers.NewOutboxHandler(outboxService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	emailHandler := handlers.NewEmailHandler(emailService)
+	emailActionHandler := handlers.NewEmailActionHandler(emailActionService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	portfolioHandler := handlers.NewPortfolioHandler(portfolioService)
+	watchlistHandler := handlers.NewWatchlistHandler(watchlistService)
 
 	// Setup router
-	router := setupRouter(cfg, authHandler, stockHandler, alertHandler, userHandler, cacheHandler)
+	router := setupRouter(cfg, authHandler, stockHandler, alertHandler, userHandler, cacheHandler, outboxHandler, notificationHandler, emailHandler, emailActionHandler, apiKeyHandler, accountHandler, portfolioHandler, watchlistHandler)
 
 	app := &App{
-		config:       cfg,
-		db:           db,
-		router:       router,
-		alertService: alertService,
+		config:           cfg,
+		db:               db,
+		router:           router,
+		alertService:     alertService,
+		digestService:    digestService,
+		outboxService:    outboxService,
+		lifecycleService: lifecycleService,
+		accountService:   accountService,
+		portfolioService: portfolioService,
 	}
 
 	// Start alert monitoring in background
 	go app.alertService.StartMonitoring()
 
+	// Start digest email scheduler in background
+	go app.digestService.StartScheduler()
+
+	// Start notification delivery worker in background
+	go app.outboxService.StartWorker()
+
+	// Start lifecycle email scheduler in background
+	go app.lifecycleService.StartScheduler()
+
+	// Start deleted account purger in background
+	go app.accountService.StartPurger()
+
+	// Start daily portfolio snapshots in background
+	go app.portfolioService.StartSnapshotter()
+
 	return app, nil
 }
 
@@ -94,6 +169,14 @@ func setupRouter(
 	alertHandler *handlers.AlertHandler,
 	userHandler *handlers.UserHandler,
 	cacheHandler *handlers.CacheHandler,
+	outboxHandler *handlers.OutboxHandler,
+	notificationHandler *handlers.NotificationHandler,
+	emailHandler *handlers.EmailHandler,
+	emailActionHandler *handlers.EmailActionHandler,
+	apiKeyHandler *handlers.APIKeyHandler,
+	accountHandler *handlers.AccountHandler,
+	portfolioHandler *handlers.PortfolioHandler,
+	watchlistHandler *handlers.WatchlistHandler,
 ) *chi.Mux {
 	r := chi.NewRouter()
 
@@ -102,7 +185,6 @@ func setupRouter(
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Timeout(time.Duration(cfg.Server.RequestTimeout) * time.Second))
 
 	// CORS configuration
 	r.Use(cors.Handler(cors.Options{
@@ -110,12 +192,25 @@ func setupRouter(
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
+		AllowCredentials: true, // the Google login sends its state cookie
 		MaxAge:           300,
 	}))
 
+	// Every route but the notification stream is cut off after RequestTimeout
+	timeout := middleware.Timeout(time.Duration(cfg.Server.RequestTimeout) * time.Second)
+
+	// Public keys for verifying access tokens
+	r.With(timeout).Get("/.well-known/jwks.json", authHandler.JWKS)
+
+	// Live notifications (Server-Sent Events). The connection stays open, so
+	// it sits outside the timeout. EventSource can't send headers, so the
+	// stream is opened with a single-use ticket instead of the access token.
+	r.With(authHandler.StreamTicketMiddleware).Get("/api/v1/notifications/stream", notificationHandler.Stream)
+
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(timeout)
+
 		// Health check
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 			render.JSON(w, r, map[string]string{
@@ -129,42 +224,141 @@ func setupRouter(
 		r.Route("/auth", func(r chi.Router) {
 			r.Get("/google", authHandler.GetGoogleAuthURL)
 			r.Post("/google/callback", authHandler.GoogleCallback)
-			r.With(authHandler.AuthMiddleware).Post("/logout", authHandler.Logout)
-			r.With(authHandler.AuthMiddleware).Get("/profile", authHandler.GetProfile)
+			r.Post("/email/request", authHandler.RequestMagicLink)
+			r.Post("/email/verify", authHandler.VerifyMagicLink)
+			r.Post("/refresh", authHandler.Refresh)
+			r.With(authHandler.AuthMiddleware, authHandler.RequireSession).Post("/logout", authHandler.Logout)
+			r.With(authHandler.AuthMiddleware, authHandler.RequireSession).Post("/logout-all", authHandler.LogoutAll)
+			r.With(authHandler.AuthMiddleware, authHandler.RequireSession).Get("/profile", authHandler.GetProfile)
+
+			// Linked sign-in methods
+			r.Group(func(r chi.Router) {
+				r.Use(authHandler.AuthMiddleware)
+				r.Use(authHandler.RequireSession)
+				r.Get("/identities", authHandler.ListIdentities)
+				r.Post("/identities/google", authHandler.LinkGoogle)
+				r.Delete("/identities/{id}", authHandler.UnlinkIdentity)
+			})
 		})
 
 		// Stock routes (public, but can be enhanced with auth)
 		r.Route("/stocks", func(r chi.Router) {
 			r.Use(authHandler.OptionalAuthMiddleware)
+			r.Use(authHandler.RequireScope(models.ScopeReadStocks))
 			r.Get("/", stockHandler.GetAllStocks)
 			r.Get("/{symbol}", stockHandler.GetStock)
 			r.Get("/{symbol}/details", stockHandler.GetStockDetails)
 		})
 
+		// Signed action links from notification emails (public, token-verified)
+		r.Route("/email-actions", func(r chi.Router) {
+			r.Get("/", emailActionHandler.Confirm)
+			r.Post("/", emailActionHandler.Perform)
+		})
+
+		// In-app notification inbox
+		r.Route("/notifications", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(authHandler.AuthMiddleware)
+				r.Use(authHandler.RequireSession)
+				r.Get("/", notificationHandler.GetNotifications)
+				r.Get("/unread-count", notificationHandler.GetUnreadCount)
+				r.Post("/stream/ticket", authHandler.IssueStreamTicket)
+				r.Post("/read-all", notificationHandler.MarkAllRead)
+				r.Post("/{id}/read", notificationHandler.MarkRead)
+			})
+		})
+
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(authHandler.AuthMiddleware)
+			adminOnly := authHandler.RequireRole(models.RoleAdmin)
 
-			// Alert routes
+			// Alert routes (also open to API keys with the matching scope)
 			r.Route("/alerts", func(r chi.Router) {
-				r.Get("/", alertHandler.GetAlerts)
-				r.Post("/", alertHandler.CreateAlert)
-				r.Get("/{id}", alertHandler.GetAlert)
-				r.Put("/{id}", alertHandler.UpdateAlert)
-				r.Delete("/{id}", alertHandler.DeleteAlert)
+				readAlerts := authHandler.RequireScope(models.ScopeReadAlerts)
+				writeAlerts := authHandler.RequireScope(models.ScopeWriteAlerts)
+				r.With(readAlerts).Get("/", alertHandler.GetAlerts)
+				r.With(writeAlerts).Post("/", alertHandler.CreateAlert)
+				r.With(readAlerts).Get("/{id}", alertHandler.GetAlert)
+				r.With(writeAlerts).Put("/{id}", alertHandler.UpdateAlert)
+				r.With(writeAlerts).Delete("/{id}", alertHandler.DeleteAlert)
 			})
 
 			// User routes
 			r.Route("/user", func(r chi.Router) {
+				r.Use(authHandler.RequireSession)
 				r.Get("/preferences", userHandler.GetPreferences)
 				r.Put("/preferences", userHandler.UpdatePreferences)
+
+				// Data export and account deletion
+				r.Get("/export", accountHandler.Export)
+				r.Delete("/", accountHandler.DeleteAccount)
+				r.Post("/deletion/cancel", accountHandler.CancelDeletion)
+
+				// Personal API keys
+				r.Get("/api-keys", apiKeyHandler.ListKeys)
+				r.Post("/api-keys", apiKeyHandler.CreateKey)
+				r.Delete("/api-keys/{id}", apiKeyHandler.RevokeKey)
 			})
 
-			// Cache management routes (admin only in production)
+			// Portfolio routes
+			r.Route("/portfolios", func(r chi.Router) {
+				r.Use(authHandler.RequireSession)
+				r.Get("/", portfolioHandler.ListPortfolios)
+				r.Post("/", portfolioHandler.CreatePortfolio)
+				r.Get("/import/formats", portfolioHandler.ListImportFormats)
+				r.Get("/{id}", portfolioHandler.GetPortfolio)
+				r.Put("/{id}", portfolioHandler.UpdatePortfolio)
+				r.Delete("/{id}", portfolioHandler.DeletePortfolio)
+				r.Get("/{id}/pnl", portfolioHandler.GetPnL)
+				r.Get("/{id}/snapshots", portfolioHandler.ListSnapshots)
+				r.Get("/{id}/transactions", portfolioHandler.ListTransactions)
+				r.Post("/{id}/transactions", portfolioHandler.AddTransaction)
+				r.Delete("/{id}/transactions/{transactionId}", portfolioHandler.DeleteTransaction)
+				r.Post("/{id}/import", portfolioHandler.ImportTransactions)
+			})
+
+			// Watchlist routes
+			r.Route("/watchlists", func(r chi.Router) {
+				r.Use(authHandler.RequireSession)
+				r.Get("/", watchlistHandler.ListWatchlists)
+				r.Post("/", watchlistHandler.CreateWatchlist)
+				r.Put("/order", watchlistHandler.ReorderWatchlists)
+				r.Get("/{id}", watchlistHandler.GetWatchlist)
+				r.Put("/{id}", watchlistHandler.UpdateWatchlist)
+				r.Delete("/{id}", watchlistHandler.DeleteWatchlist)
+				r.Get("/{id}/quotes", watchlistHandler.GetQuotes)
+				r.Post("/{id}/items", watchlistHandler.AddItem)
+				r.Put("/{id}/items/order", watchlistHandler.ReorderItems)
+				r.Put("/{id}/items/{symbol}", watchlistHandler.UpdateItem)
+				r.Delete("/{id}/items/{symbol}", watchlistHandler.RemoveItem)
+				r.Post("/{id}/alerts", watchlistHandler.CreateAlerts)
+			})
+
+			// Cache management routes; changing the cache is admin only
 			r.Route("/cache", func(r chi.Router) {
+				r.Use(authHandler.RequireSession)
 				r.Get("/stats", cacheHandler.GetCacheStats)
-				r.Post("/invalidate", cacheHandler.InvalidateCache)
-				r.Post("/warmup", cacheHandler.WarmupCache)
+				r.With(adminOnly).Post("/invalidate", cacheHandler.InvalidateCache)
+				r.With(adminOnly).Post("/warmup", cacheHandler.WarmupCache)
+			})
+
+			// Notification outbox routes (admin only)
+			r.Route("/admin/outbox", func(r chi.Router) {
+				r.Use(authHandler.RequireSession)
+				r.Use(adminOnly)
+				r.Get("/", outboxHandler.ListMessages)
+				r.Get("/{id}", outboxHandler.GetMessage)
+				r.Post("/{id}/replay", outboxHandler.ReplayMessage)
+			})
+
+			// Email template preview routes (admin only)
+			r.Route("/admin/emails", func(r chi.Router) {
+				r.Use(authHandler.RequireSession)
+				r.Use(adminOnly)
+				r.Get("/locales", emailHandler.GetLocales)
+				r.Get("/preview/{template}", emailHandler.PreviewTemplate)
 			})
 		})
 	})
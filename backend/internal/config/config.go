@@ -1,25 +1,36 @@
 package config
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+
+	"golang.org/x/crypto/hkdf"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Auth     AuthConfig
-	Email    EmailConfig
-	External ExternalConfig
-	Cache    CacheConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	Email     EmailConfig
+	External  ExternalConfig
+	Cache     CacheConfig
+	Digest    DigestConfig
+	Outbox    OutboxConfig
+	Lifecycle LifecycleConfig
+	Account   AccountConfig
+	Portfolio PortfolioConfig
 }
 
 type ServerConfig struct {
-	Port            string
-	AllowedOrigins  []string
-	RequestTimeout  int
+	Port           string
+	AllowedOrigins []string
+	RequestTimeout int
+	DevMode        bool // relaxes production safety checks, such as the default JWT secret
 }
 
 type DatabaseConfig struct {
@@ -34,11 +45,18 @@ type DatabaseConfig struct {
 }
 
 type AuthConfig struct {
-	GoogleClientID     string
-	GoogleClientSecret string
-	RedirectURL        string
-	JWTSecret          string
-	JWTExpirationHours int
+	GoogleClientID      string
+	GoogleClientSecret  string
+	RedirectURL         string
+	JWTSecret           string   // HS256 key, used when no signing keys are configured
+	JWTKeysDir          string   // directory of <kid>.pem RSA or Ed25519 private keys
+	JWTActiveKeyID      string   // kid that signs new tokens; the others only verify
+	AccessTokenMinutes  int      // lifetime of the JWT access token
+	RefreshTokenDays    int      // sliding lifetime of a session's refresh token
+	OAuthStateMinutes   int      // how long a login attempt's state and PKCE verifier stay valid
+	OAuthCookieSameSite string   // SameSite mode of the login state cookie: lax, or none for a frontend on another site
+	MagicLinkMinutes    int      // how long an emailed sign-in link stays valid
+	AdminEmails         []string // users with these (verified) emails are promoted to admin
 }
 
 type EmailConfig struct {
@@ -48,6 +66,23 @@ type EmailConfig struct {
 	SMTPPassword string
 	FromEmail    string
 	FromName     string
+
+	Transport              string // smtp, file or memory
+	CaptureDir             string // where the file transport writes .eml files
+	SMTPTLSMode            string // starttls, tls (implicit) or none
+	SMTPTimeoutSeconds     int
+	SMTPPoolSize           int // idle connections kept open for reuse
+	SMTPIdleTimeoutSeconds int
+
+	DKIMDomain         string
+	DKIMSelector       string
+	DKIMPrivateKey     string // PEM, takes precedence over DKIMPrivateKeyFile
+	DKIMPrivateKeyFile string
+
+	AppURL             string // frontend URL that emails link users back to
+	PublicURL          string // externally reachable API base URL used in email links
+	ActionSecret       string // HMAC key for signed action links, defaults to a key derived from JWTSecret
+	ActionLinkTTLHours int
 }
 
 type ExternalConfig struct {
@@ -65,8 +100,61 @@ type CacheConfig struct {
 	StockCacheTTL int // in minutes
 }
 
+type DigestConfig struct {
+	Timezone  string // IANA zone used to schedule digests, e.g. Africa/Accra
+	Hour      int    // local hour of day at which digests are sent
+	WeeklyDay string // day of week for weekly digests, e.g. Friday
+}
+
+type OutboxConfig struct {
+	PollIntervalSeconds int
+	BatchSize           int
+	MaxAttempts         int // attempts before a message is dead-lettered
+	BaseBackoffSeconds  int // delay after the first failure, doubled on each retry
+	MaxBackoffMinutes   int
+}
+
+type LifecycleConfig struct {
+	Enabled              bool
+	NudgeAfterDays       int // days after sign-up with no alerts before the nudge email
+	DormantAfterDays     int // days without a login before the re-engagement email
+	CheckIntervalMinutes int
+}
+
+type AccountConfig struct {
+	DeletionGraceDays    int // days between a deletion request and the data being purged
+	PurgeIntervalMinutes int
+}
+
+type PortfolioConfig struct {
+	SnapshotTimezone string // IANA zone that decides which trading day a snapshot belongs to
+	SnapshotHour     int    // local hour after which each weekday's closing values are recorded
+}
+
+// DefaultJWTSecret is the placeholder secret. The server refuses to start with
+// it, or any other placeholder from the docs, unless DEV_MODE is set.
+const DefaultJWTSecret = "your-secret-key-change-in-production"
+
+// placeholderSecrets are the example secrets published in .env.example and
+// the README
+var placeholderSecrets = []string{
+	DefaultJWTSecret,
+	"your-very-secure-jwt-secret-change-in-production",
+	"your-very-secure-jwt-secret",
+}
+
+// isPlaceholderSecret reports whether secret is one of the published examples
+func isPlaceholderSecret(secret string) bool {
+	for _, placeholder := range placeholderSecrets {
+		if secret == placeholder {
+			return true
+		}
+	}
+	return false
+}
+
 func Load() (*Config, error) {
-	return &Config{
+	cfg := &Config{
 		Server: ServerConfig{
 			Port: getEnv("PORT", "10000"),
 			AllowedOrigins: []string{
@@ -76,14 +164,22 @@ func Load() (*Config, error) {
 				"https://stock-alert-gh.onrender.com",
 			},
 			RequestTimeout: getEnvAsInt("REQUEST_TIMEOUT", 60),
+			DevMode:        getEnvAsBool("DEV_MODE", false),
 		},
-		Database: loadDatabaseConfig(),
+		Database: LoadDatabaseConfig(),
 		Auth: AuthConfig{
-			GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
-			GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
-			RedirectURL:        getEnv("OAUTH_REDIRECT_URL", "http://localhost:5173/"),
-			JWTSecret:          getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-			JWTExpirationHours: getEnvAsInt("JWT_EXPIRATION_HOURS", 24),
+			GoogleClientID:      getEnv("GOOGLE_CLIENT_ID", ""),
+			GoogleClientSecret:  getEnv("GOOGLE_CLIENT_SECRET", ""),
+			RedirectURL:         getEnv("OAUTH_REDIRECT_URL", "http://localhost:5173/"),
+			JWTSecret:           getEnv("JWT_SECRET", DefaultJWTSecret),
+			JWTKeysDir:          getEnv("JWT_KEYS_DIR", ""),
+			JWTActiveKeyID:      getEnv("JWT_ACTIVE_KID", ""),
+			AccessTokenMinutes:  getEnvAsInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+			RefreshTokenDays:    getEnvAsInt("REFRESH_TOKEN_TTL_DAYS", 30),
+			OAuthStateMinutes:   getEnvAsInt("OAUTH_STATE_TTL_MINUTES", 10),
+			OAuthCookieSameSite: getEnv("OAUTH_COOKIE_SAMESITE", "lax"),
+			MagicLinkMinutes:    getEnvAsInt("MAGIC_LINK_TTL_MINUTES", 15),
+			AdminEmails:         getEnvAsList("ADMIN_EMAILS"),
 		},
 		Email: EmailConfig{
 			SMTPHost:     getEnv("SMTP_HOST", "smtp.gmail.com"),
@@ -92,6 +188,23 @@ func Load() (*Config, error) {
 			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
 			FromEmail:    getEnv("FROM_EMAIL", ""),
 			FromName:     getEnv("FROM_NAME", "Shares Alert Ghana"),
+
+			Transport:              getEnv("EMAIL_TRANSPORT", "smtp"),
+			CaptureDir:             getEnv("EMAIL_CAPTURE_DIR", "./data/mail"),
+			SMTPTLSMode:            getEnv("SMTP_TLS_MODE", defaultSMTPTLSMode(getEnv("SMTP_PORT", "587"))),
+			SMTPTimeoutSeconds:     getEnvAsInt("SMTP_TIMEOUT_SECONDS", 30),
+			SMTPPoolSize:           getEnvAsInt("SMTP_POOL_SIZE", 2),
+			SMTPIdleTimeoutSeconds: getEnvAsInt("SMTP_IDLE_TIMEOUT_SECONDS", 60),
+
+			DKIMDomain:         getEnv("DKIM_DOMAIN", ""),
+			DKIMSelector:       getEnv("DKIM_SELECTOR", ""),
+			DKIMPrivateKey:     getEnv("DKIM_PRIVATE_KEY", ""),
+			DKIMPrivateKeyFile: getEnv("DKIM_PRIVATE_KEY_FILE", ""),
+
+			AppURL:             strings.TrimSuffix(getEnv("FRONTEND_URL", "http://localhost:3000"), "/"),
+			PublicURL:          strings.TrimSuffix(getEnv("PUBLIC_API_URL", "http://localhost:10000"), "/"),
+			ActionSecret:       getEnv("EMAIL_ACTION_SECRET", ""),
+			ActionLinkTTLHours: getEnvAsInt("EMAIL_ACTION_LINK_TTL_HOURS", 720),
 		},
 		External: ExternalConfig{
 			GSEBaseURL: getEnv("GSE_BASE_URL", "https://dev.kwayisi.org/apis/gse"),
@@ -106,7 +219,68 @@ func Load() (*Config, error) {
 			Enabled:       getEnvAsBool("REDIS_ENABLED", true),
 			StockCacheTTL: getEnvAsInt("STOCK_CACHE_TTL_MINUTES", 5),
 		},
-	}, nil
+		Digest: DigestConfig{
+			Timezone:  getEnv("DIGEST_TIMEZONE", "Africa/Accra"),
+			Hour:      getEnvAsInt("DIGEST_HOUR", 17),
+			WeeklyDay: getEnv("DIGEST_WEEKLY_DAY", "Friday"),
+		},
+		Outbox: OutboxConfig{
+			PollIntervalSeconds: getEnvAsInt("OUTBOX_POLL_INTERVAL_SECONDS", 15),
+			BatchSize:           getEnvAsInt("OUTBOX_BATCH_SIZE", 50),
+			MaxAttempts:         getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 6),
+			BaseBackoffSeconds:  getEnvAsInt("OUTBOX_BASE_BACKOFF_SECONDS", 30),
+			MaxBackoffMinutes:   getEnvAsInt("OUTBOX_MAX_BACKOFF_MINUTES", 60),
+		},
+		Lifecycle: LifecycleConfig{
+			Enabled:              getEnvAsBool("LIFECYCLE_EMAILS_ENABLED", true),
+			NudgeAfterDays:       getEnvAsInt("LIFECYCLE_NUDGE_AFTER_DAYS", 3),
+			DormantAfterDays:     getEnvAsInt("LIFECYCLE_DORMANT_AFTER_DAYS", 30),
+			CheckIntervalMinutes: getEnvAsInt("LIFECYCLE_CHECK_INTERVAL_MINUTES", 60),
+		},
+		Account: AccountConfig{
+			DeletionGraceDays:    getEnvAsInt("ACCOUNT_DELETION_GRACE_DAYS", 14),
+			PurgeIntervalMinutes: getEnvAsInt("ACCOUNT_PURGE_INTERVAL_MINUTES", 60),
+		},
+		Portfolio: PortfolioConfig{
+			SnapshotTimezone: getEnv("PORTFOLIO_SNAPSHOT_TIMEZONE", "Africa/Accra"),
+			SnapshotHour:     getEnvAsInt("PORTFOLIO_SNAPSHOT_HOUR", 16),
+		},
+	}
+
+	// The default secret is public, so anything keyed with it can be forged
+	if !cfg.Server.DevMode {
+		if cfg.Auth.JWTKeysDir == "" && isPlaceholderSecret(cfg.Auth.JWTSecret) {
+			return nil, fmt.Errorf("JWT_SECRET is set to the default or an example value; set a real secret, configure JWT_KEYS_DIR, or set DEV_MODE=true")
+		}
+		if isPlaceholderSecret(cfg.Email.ActionSecret) || (cfg.Email.ActionSecret == "" && isPlaceholderSecret(cfg.Auth.JWTSecret)) {
+			return nil, fmt.Errorf("email action links would be signed with a default or example secret; set JWT_SECRET or EMAIL_ACTION_SECRET, or set DEV_MODE=true")
+		}
+	}
+
+	// Without a secret of their own, action links get a key derived from
+	// JWT_SECRET, so no one key signs both tokens and links
+	if cfg.Email.ActionSecret == "" {
+		cfg.Email.ActionSecret = deriveKey(cfg.Auth.JWTSecret, "email action links")
+	}
+
+	return cfg, nil
+}
+
+// deriveKey expands secret into a 32-byte key for one purpose with HKDF-SHA256
+func deriveKey(secret, purpose string) string {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte("shares-alert "+purpose)), key); err != nil {
+		panic(err) // only fails past 255 blocks of output
+	}
+	return string(key)
+}
+
+// defaultSMTPTLSMode picks implicit TLS for the SMTPS port and STARTTLS otherwise
+func defaultSMTPTLSMode(port string) string {
+	if port == "465" {
+		return "tls"
+	}
+	return "starttls"
 }
 
 func getEnv(key, defaultValue string) string {
@@ -134,12 +308,26 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
-func loadDatabaseConfig() DatabaseConfig {
+// getEnvAsList splits a comma-separated variable, dropping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// LoadDatabaseConfig reads just the database settings, for tools such as
+// the migrate command that don't need the rest of the config
+func LoadDatabaseConfig() DatabaseConfig {
 	// Primary: Use individual environment variables (more secure)
+	dbType := getEnv("DB_TYPE", "sqlite")
 	config := DatabaseConfig{
-		Type:     getEnv("DB_TYPE", "sqlite"),
+		Type:     dbType,
 		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
+		Port:     getEnv("DB_PORT", defaultDatabasePort(dbType)),
 		Name:     getEnv("DB_NAME", "shares_alert"),
 		User:     getEnv("DB_USER", ""),
 		Password: getEnv("DB_PASSWORD", ""),
@@ -149,13 +337,22 @@ func loadDatabaseConfig() DatabaseConfig {
 
 	// Fallback: Check if DATABASE_URL is provided (for compatibility)
 	if databaseURL := getEnv("DATABASE_URL", ""); databaseURL != "" && config.Type == "sqlite" {
-		return parseDatabaseURL(databaseURL)
+		return ParseDatabaseURL(databaseURL)
 	}
 
 	return config
 }
 
-func parseDatabaseURL(databaseURL string) DatabaseConfig {
+func defaultDatabasePort(dbType string) string {
+	if dbType == "mysql" {
+		return "3306"
+	}
+	return "5432"
+}
+
+// ParseDatabaseURL reads a postgres:// or mysql:// URL. An sslmode query
+// parameter overrides the default of require.
+func ParseDatabaseURL(databaseURL string) DatabaseConfig {
 	u, err := url.Parse(databaseURL)
 	if err != nil {
 		// Fallback to default config if URL parsing fails
@@ -180,20 +377,22 @@ func parseDatabaseURL(databaseURL string) DatabaseConfig {
 		}
 	}
 
-	// Handle PostgreSQL scheme variations
-	if config.Type == "postgresql" {
+	if sslMode := u.Query().Get("sslmode"); sslMode != "" {
+		config.SSLMode = sslMode
+	}
+
+	// Handle scheme variations
+	switch config.Type {
+	case "postgresql":
 		config.Type = "postgres"
+	case "mariadb":
+		config.Type = "mysql"
 	}
 
 	// Set default port if not specified
 	if config.Port == "" {
-		switch config.Type {
-		case "postgres":
-			config.Port = "5432"
-		case "mysql":
-			config.Port = "3306"
-		}
+		config.Port = defaultDatabasePort(config.Type)
 	}
 
 	return config
-}
\ No newline at end of file
+}
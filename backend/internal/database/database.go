@@ -1,27 +1,62 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"path/filepath"
+	"time"
 
 	"shares-alert-backend/internal/config"
 
+	"github.com/go-sql-driver/mysql"
 	_ "github.com/mattn/go-sqlite3"
 	_ "github.com/lib/pq"
 )
 
+// DB is the connection pool. Its query methods pass queries through the
+// dialect, so repositories can use them with Postgres-style SQL whatever the
+// database, and the Context variants join any transaction started by WithTx.
 type DB struct {
 	*sql.DB
-	config *config.DatabaseConfig
+	config  *config.DatabaseConfig
+	dialect Dialect
 }
 
+// Tx is a transaction whose queries also pass through the dialect
+type Tx struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+// New connects to the database and migrates its schema to the latest version
 func New(cfg *config.DatabaseConfig) (*DB, error) {
-	var db *sql.DB
-	var err error
+	dbInstance, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run migrations
+	if err := dbInstance.Migrate(); err != nil {
+		dbInstance.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	log.Printf("Connected to %s database successfully", cfg.Type)
+	return dbInstance, nil
+}
+
+// Open connects to the database without touching its schema
+func Open(cfg *config.DatabaseConfig) (*DB, error) {
+	dialect, err := dialectFor(cfg.Type)
+	if err != nil {
+		return nil, err
+	}
 
+	var db *sql.DB
 	switch cfg.Type {
 	case "sqlite":
 		// Ensure data directory exists
@@ -29,7 +64,10 @@ func New(cfg *config.DatabaseConfig) (*DB, error) {
 			return nil, fmt.Errorf("failed to create data directory: %w", err)
 		}
 		
-		db, err = sql.Open("sqlite3", cfg.FilePath+"?_foreign_keys=on")
+		// Transactions take the write lock when they begin, so concurrent
+		// writers (including two instances migrating) wait their turn
+		// instead of failing part way through
+		db, err = sql.Open("sqlite3", cfg.FilePath+"?_foreign_keys=on&_txlock=immediate")
 		if err != nil {
 			return nil, fmt.Errorf("failed to open SQLite database: %w", err)
 		}
@@ -53,8 +91,11 @@ func New(cfg *config.DatabaseConfig) (*DB, error) {
 			return nil, fmt.Errorf("failed to open PostgreSQL database: %w", err)
 		}
 
-	default:
-		return nil, fmt.Errorf("unsupported database type: %s", cfg.Type)
+	case "mysql":
+		db, err = sql.Open("mysql", mysqlDSN(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open MySQL database: %w", err)
+		}
 	}
 
 	// Test the connection
@@ -62,144 +103,122 @@ func New(cfg *config.DatabaseConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	dbInstance := &DB{
-		DB:     db,
-		config: cfg,
+	return &DB{
+		DB:      db,
+		config:  cfg,
+		dialect: dialect,
+	}, nil
+}
+
+// mysqlDSN builds the MySQL/MariaDB connection string. Timestamps are read
+// and written in UTC. Multiple statements are allowed so that a migration
+// file can run in one call. Affected-row counts include rows an UPDATE
+// matched but left unchanged, as Postgres and SQLite count them.
+func mysqlDSN(cfg *config.DatabaseConfig) string {
+	mc := mysql.NewConfig()
+	mc.User = cfg.User
+	mc.Passwd = cfg.Password
+	mc.Net = "tcp"
+	mc.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+	mc.DBName = cfg.Name
+	mc.Params = map[string]string{"charset": "utf8mb4", "time_zone": "'+00:00'"}
+	mc.Loc = time.UTC
+	mc.ParseTime = true
+	mc.MultiStatements = true
+	mc.ClientFoundRows = true
+
+	// DB_SSL_MODE uses the Postgres names
+	switch cfg.SSLMode {
+	case "", "disable":
+	case "allow", "prefer":
+		mc.TLSConfig = "preferred"
+	case "verify-ca", "verify-full":
+		mc.TLSConfig = "true"
+	default: // require
+		mc.TLSConfig = "skip-verify"
 	}
 
-	// Run migrations
-	if err := dbInstance.Migrate(); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	return mc.FormatDSN()
+}
+
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+// ExecContext runs in the transaction ctx carries, if WithTx gave it one
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.ExecContext(ctx, query, args...)
 	}
+	query, args = db.dialect.Rebind(query, args)
+	return db.DB.ExecContext(ctx, query, args...)
+}
 
-	log.Printf("Connected to %s database successfully", cfg.Type)
-	return dbInstance, nil
+// QueryContext runs in the transaction ctx carries, if WithTx gave it one
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.QueryContext(ctx, query, args...)
+	}
+	query, args = db.dialect.Rebind(query, args)
+	return db.DB.QueryContext(ctx, query, args...)
 }
 
-func (db *DB) Migrate() error {
-	var migrations []string
-	
-	// Use different migrations based on database type
-	switch db.config.Type {
-	case "postgres":
-		migrations = []string{
-			createUsersTablePostgres,
-			createUserPreferencesTablePostgres,
-			createAlertsTablePostgres,
-			createIndexesPostgres,
-		}
-	default: // sqlite
-		migrations = []string{
-			createUsersTable,
-			createUserPreferencesTable,
-			createAlertsTable,
-			createIndexes,
-		}
+// QueryRowContext runs in the transaction ctx carries, if WithTx gave it one
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.QueryRowContext(ctx, query, args...)
 	}
+	query, args = db.dialect.Rebind(query, args)
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
 
-	for i, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
-			return fmt.Errorf("migration %d failed: %w", i+1, err)
-		}
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
+	return &Tx{Tx: tx, dialect: db.dialect}, nil
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args = tx.dialect.Rebind(query, args)
+	return tx.Tx.ExecContext(ctx, query, args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	query, args = tx.dialect.Rebind(query, args)
+	return tx.Tx.QueryContext(ctx, query, args...)
+}
 
-	log.Println("Database migrations completed successfully")
-	return nil
-}
-
-const createUsersTable = `
-CREATE TABLE IF NOT EXISTS users (
-	id TEXT PRIMARY KEY,
-	email TEXT UNIQUE NOT NULL,
-	name TEXT NOT NULL,
-	picture TEXT,
-	google_id TEXT UNIQUE NOT NULL,
-	email_verified BOOLEAN DEFAULT FALSE,
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-);`
-
-const createUserPreferencesTable = `
-CREATE TABLE IF NOT EXISTS user_preferences (
-	id TEXT PRIMARY KEY,
-	user_id TEXT NOT NULL,
-	email_notifications BOOLEAN DEFAULT TRUE,
-	push_notifications BOOLEAN DEFAULT TRUE,
-	notification_frequency TEXT DEFAULT 'immediate',
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);`
-
-const createAlertsTable = `
-CREATE TABLE IF NOT EXISTS alerts (
-	id TEXT PRIMARY KEY,
-	user_id TEXT NOT NULL,
-	stock_symbol TEXT NOT NULL,
-	stock_name TEXT NOT NULL,
-	alert_type TEXT NOT NULL,
-	threshold_price REAL,
-	current_price REAL,
-	status TEXT DEFAULT 'active',
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	triggered_at DATETIME,
-	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);`
-
-const createIndexes = `
-CREATE INDEX IF NOT EXISTS idx_alerts_user_id ON alerts(user_id);
-CREATE INDEX IF NOT EXISTS idx_alerts_status ON alerts(status);
-CREATE INDEX IF NOT EXISTS idx_alerts_stock_symbol ON alerts(stock_symbol);
-CREATE INDEX IF NOT EXISTS idx_alerts_alert_type ON alerts(alert_type);
-CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
-CREATE INDEX IF NOT EXISTS idx_users_google_id ON users(google_id);
-`
-
-// PostgreSQL-specific table definitions
-const createUsersTablePostgres = `
-CREATE TABLE IF NOT EXISTS shares_alert_users (
-	id TEXT PRIMARY KEY,
-	email TEXT UNIQUE NOT NULL,
-	name TEXT NOT NULL,
-	picture TEXT,
-	google_id TEXT UNIQUE NOT NULL,
-	email_verified BOOLEAN DEFAULT FALSE,
-	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);`
-
-const createUserPreferencesTablePostgres = `
-CREATE TABLE IF NOT EXISTS shares_alert_user_preferences (
-	id TEXT PRIMARY KEY,
-	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
-	email_notifications BOOLEAN DEFAULT TRUE,
-	push_notifications BOOLEAN DEFAULT TRUE,
-	notification_frequency TEXT DEFAULT 'immediate',
-	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);`
-
-const createAlertsTablePostgres = `
-CREATE TABLE IF NOT EXISTS shares_alert_alerts (
-	id TEXT PRIMARY KEY,
-	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
-	stock_symbol TEXT NOT NULL,
-	stock_name TEXT NOT NULL,
-	alert_type TEXT NOT NULL,
-	threshold_price REAL,
-	current_price REAL,
-	status TEXT DEFAULT 'active',
-	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	triggered_at TIMESTAMP
-);`
-
-const createIndexesPostgres = `
-CREATE INDEX IF NOT EXISTS idx_shares_alert_alerts_user_id ON shares_alert_alerts(user_id);
-CREATE INDEX IF NOT EXISTS idx_shares_alert_alerts_status ON shares_alert_alerts(status);
-CREATE INDEX IF NOT EXISTS idx_shares_alert_alerts_stock_symbol ON shares_alert_alerts(stock_symbol);
-CREATE INDEX IF NOT EXISTS idx_shares_alert_alerts_alert_type ON shares_alert_alerts(alert_type);
-CREATE INDEX IF NOT EXISTS idx_shares_alert_users_email ON shares_alert_users(email);
-CREATE INDEX IF NOT EXISTS idx_shares_alert_users_google_id ON shares_alert_users(google_id);
-`
\ No newline at end of file
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	query, args = tx.dialect.Rebind(query, args)
+	return tx.Tx.QueryRowContext(ctx, query, args...)
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
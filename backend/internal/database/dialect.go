@@ -0,0 +1,256 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect covers the differences between the supported databases. Queries
+// are written once, Postgres style: shares_alert_ table names and $1, $2...
+// placeholders. The dialect rewrites them for its database as they run.
+type Dialect interface {
+	// Name is the DB_TYPE the dialect serves, which also names its
+	// migrations directory
+	Name() string
+
+	// Rebind rewrites the query's placeholders into the dialect's style and
+	// returns the arguments in the form and order the driver expects
+	Rebind(query string, args []interface{}) (string, []interface{})
+
+	// Upsert builds an INSERT of columns into table, with $1... placeholders
+	// in column order. If a row with the same conflict columns exists, its
+	// update columns are overwritten instead, or it's left alone if update is
+	// empty.
+	Upsert(table string, columns, conflict, update []string) string
+
+	// tableExistsQuery counts tables named $1 in the current schema
+	tableExistsQuery() string
+
+	// columnExistsQuery counts columns named $2 on table $1
+	columnExistsQuery() string
+
+	// lockMigrations stops other instances migrating until unlock is called
+	lockMigrations(ctx context.Context, conn *sql.Conn) (unlock func(), err error)
+}
+
+func dialectFor(dbType string) (Dialect, error) {
+	switch dbType {
+	case "postgres":
+		return postgresDialect{}, nil
+	case "sqlite":
+		return sqliteDialect{}, nil
+	case "mysql":
+		return mysqlDialect{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Rebind(query string, args []interface{}) (string, []interface{}) {
+	return query, args
+}
+
+func (postgresDialect) Upsert(table string, columns, conflict, update []string) string {
+	return onConflictUpsert(table, columns, conflict, update)
+}
+
+func (postgresDialect) tableExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name = $1`
+}
+
+func (postgresDialect) columnExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2`
+}
+
+// lockMigrations takes a session advisory lock, so a second instance waits
+// for the first to finish and then finds nothing left to do
+func (postgresDialect) lockMigrations(ctx context.Context, conn *sql.Conn) (func(), error) {
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return nil, err
+	}
+	return func() {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}, nil
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+// Rebind turns $N into ?N. SQLite reads $N as a named parameter and numbers
+// them by first appearance, so a query that used $2 before $1 would bind
+// the wrong values; ?N always binds the Nth argument.
+//
+// Timestamps are stored as text and compared as strings, which only orders
+// them correctly if they share an offset, so they're all written in UTC.
+func (sqliteDialect) Rebind(query string, args []interface{}) (string, []interface{}) {
+	query = rewritePlaceholders(query, func(n int) string { return "?" + strconv.Itoa(n) })
+
+	bound := make([]interface{}, len(args))
+	for i, arg := range args {
+		bound[i] = utcTime(arg)
+	}
+	return query, bound
+}
+
+func (sqliteDialect) Upsert(table string, columns, conflict, update []string) string {
+	return onConflictUpsert(table, columns, conflict, update)
+}
+
+func (sqliteDialect) tableExistsQuery() string {
+	return `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = $1`
+}
+
+func (sqliteDialect) columnExistsQuery() string {
+	return `SELECT COUNT(*) FROM pragma_table_info($1) WHERE name = $2`
+}
+
+// lockMigrations has nothing to do: SQLite transactions take the database's
+// write lock when they begin, and each migration checks it is still pending
+// inside its transaction
+func (sqliteDialect) lockMigrations(ctx context.Context, conn *sql.Conn) (func(), error) {
+	return func() {}, nil
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+// Rebind turns each $N into ?, which MySQL binds in order of appearance, and
+// lays the arguments out to match, repeating any that are used twice
+func (mysqlDialect) Rebind(query string, args []interface{}) (string, []interface{}) {
+	bound := make([]interface{}, 0, len(args))
+	query = rewritePlaceholders(query, func(n int) string {
+		if n >= 1 && n <= len(args) {
+			bound = append(bound, args[n-1])
+		}
+		return "?"
+	})
+	return query, bound
+}
+
+// Upsert uses ON DUPLICATE KEY UPDATE, which fires on any unique key rather
+// than just the conflict columns. Leaving the row alone is INSERT IGNORE, as
+// a no-op update would count as an affected row.
+func (mysqlDialect) Upsert(table string, columns, conflict, update []string) string {
+	if len(update) == 0 {
+		return "INSERT IGNORE" + strings.TrimPrefix(insertInto(table, columns), "INSERT")
+	}
+
+	sets := make([]string, len(update))
+	for i, column := range update {
+		sets[i] = fmt.Sprintf("%s = VALUES(%s)", column, column)
+	}
+	return insertInto(table, columns) + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+func (mysqlDialect) tableExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name = $1`
+}
+
+func (mysqlDialect) columnExistsQuery() string {
+	return `SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = $1 AND column_name = $2`
+}
+
+// lockMigrations takes a named lock, held by the connection, with the same
+// effect as the Postgres advisory lock
+func (mysqlDialect) lockMigrations(ctx context.Context, conn *sql.Conn) (func(), error) {
+	var acquired int
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, -1)`, migrationLockName).Scan(&acquired); err != nil {
+		return nil, err
+	}
+	if acquired != 1 {
+		return nil, fmt.Errorf("GET_LOCK returned %d", acquired)
+	}
+	return func() {
+		if _, err := conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, migrationLockName); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}, nil
+}
+
+func insertInto(table string, columns []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+}
+
+func onConflictUpsert(table string, columns, conflict, update []string) string {
+	query := insertInto(table, columns) + " ON CONFLICT (" + strings.Join(conflict, ", ") + ")"
+	if len(update) == 0 {
+		return query + " DO NOTHING"
+	}
+
+	sets := make([]string, len(update))
+	for i, column := range update {
+		sets[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+	}
+	return query + " DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+// rewritePlaceholders replaces each $N placeholder in query with replace(N),
+// leaving quoted strings and identifiers alone
+func rewritePlaceholders(query string, replace func(n int) string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if j > i+1 {
+				n, _ := strconv.Atoi(query[i+1 : j])
+				b.WriteString(replace(n))
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+func utcTime(arg interface{}) interface{} {
+	switch v := arg.(type) {
+	case time.Time:
+		return v.UTC()
+	case *time.Time:
+		if v != nil {
+			return v.UTC()
+		}
+	case sql.NullTime:
+		if v.Valid {
+			return v.Time.UTC()
+		}
+	}
+	return arg
+}
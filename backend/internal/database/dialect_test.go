@@ -0,0 +1,77 @@
+package database
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRewritePlaceholders(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`SELECT * FROM t WHERE a = $1 AND b = $2`, `SELECT * FROM t WHERE a = ?1 AND b = ?2`},
+		{`UPDATE t SET a = $2 WHERE id = $1 OR b = $2`, `UPDATE t SET a = ?2 WHERE id = ?1 OR b = ?2`},
+		{`SELECT * FROM t LIMIT $10`, `SELECT * FROM t LIMIT ?10`},
+		{`SELECT 'costs $1' || "col$2", $1`, `SELECT 'costs $1' || "col$2", ?1`},
+		{`SELECT 'it''s $1', $1`, `SELECT 'it''s $1', ?1`},
+		{`SELECT $ FROM t`, `SELECT $ FROM t`},
+	}
+
+	for _, tt := range tests {
+		got := rewritePlaceholders(tt.query, func(n int) string { return "?" + strconv.Itoa(n) })
+		if got != tt.want {
+			t.Errorf("rewritePlaceholders(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteRebindStoresTimesInUTC(t *testing.T) {
+	zone := time.FixedZone("UTC+5", 5*60*60)
+	at := time.Date(2026, 3, 14, 14, 30, 0, 0, zone)
+
+	_, args := sqliteDialect{}.Rebind(`SELECT $1, $2, $3`, []interface{}{at, &at, "text"})
+	if got := args[0].(time.Time); got.Location() != time.UTC || !got.Equal(at) {
+		t.Errorf("time.Time bound as %v, want %v in UTC", got, at)
+	}
+	if got := args[1].(time.Time); got.Location() != time.UTC || !got.Equal(at) {
+		t.Errorf("*time.Time bound as %v, want %v in UTC", got, at)
+	}
+	if args[2] != "text" {
+		t.Errorf("string bound as %v, want it unchanged", args[2])
+	}
+}
+
+func TestMySQLRebindOrdersArguments(t *testing.T) {
+	query, args := mysqlDialect{}.Rebind(`UPDATE t SET a = $2, b = '$1' WHERE id = $1 OR a = $2`, []interface{}{"id", "a"})
+
+	if want := `UPDATE t SET a = ?, b = '$1' WHERE id = ? OR a = ?`; query != want {
+		t.Errorf("query = %q, want %q", query, want)
+	}
+	if len(args) != 3 || args[0] != "a" || args[1] != "id" || args[2] != "a" {
+		t.Errorf("args = %v, want [a id a]", args)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	columns := []string{"id", "user_id", "kind"}
+	conflict := []string{"user_id", "kind"}
+
+	tests := []struct {
+		dialect Dialect
+		update  []string
+		want    string
+	}{
+		{postgresDialect{}, []string{"id"}, `INSERT INTO t (id, user_id, kind) VALUES ($1, $2, $3) ON CONFLICT (user_id, kind) DO UPDATE SET id = excluded.id`},
+		{sqliteDialect{}, nil, `INSERT INTO t (id, user_id, kind) VALUES ($1, $2, $3) ON CONFLICT (user_id, kind) DO NOTHING`},
+		{mysqlDialect{}, []string{"id"}, `INSERT INTO t (id, user_id, kind) VALUES ($1, $2, $3) ON DUPLICATE KEY UPDATE id = VALUES(id)`},
+		{mysqlDialect{}, nil, `INSERT IGNORE INTO t (id, user_id, kind) VALUES ($1, $2, $3)`},
+	}
+
+	for _, tt := range tests {
+		if got := tt.dialect.Upsert("t", columns, conflict, tt.update); got != tt.want {
+			t.Errorf("%s Upsert(%v) = %q, want %q", tt.dialect.Name(), tt.update, got, tt.want)
+		}
+	}
+}
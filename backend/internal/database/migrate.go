@@ -0,0 +1,451 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations live in migrations/<dialect>/ as <version>_<name>.up.sql with
+// an optional matching .down.sql. Versions are applied in numeric order,
+// each in its own transaction, and recorded in schema_migrations.
+//
+// MySQL commits DDL implicitly, so there the transaction only covers the
+// schema_migrations row. A migration that fails partway leaves the earlier
+// statements applied but the version unrecorded, and the next run starts it
+// again from the top. MySQL migrations must therefore be safe to re-run: use
+// IF NOT EXISTS forms, or put each statement that can't be in its own file.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migrationLockKey identifies this app's Postgres advisory lock, and
+// migrationLockName its MySQL named lock
+const (
+	migrationLockKey  = 8143264597
+	migrationLockName = "shares_alert_migrations"
+)
+
+const createSchemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`
+
+// ErrMigrationDrift means an applied migration's file has changed since it
+// ran, or the database has migrations this build doesn't know about
+var ErrMigrationDrift = errors.New("schema migrations have drifted")
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string // empty if the migration can't be undone
+	Checksum string // SHA-256 of Up
+}
+
+// MigrationStatus is a migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Drifted   bool // applied from a different version of the file
+}
+
+type appliedMigration struct {
+	version   int
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// loadMigrations reads the dialect's migrations, ordered by version
+func loadMigrations(dialect string) ([]*Migration, error) {
+	dir := "migrations/" + dialect
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s/%s", dir, entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(migrationFiles, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrate applies every pending migration
+func (db *DB) Migrate() error {
+	return db.MigrateUp(0)
+}
+
+// MigrateUp applies pending migrations up to and including target, or all
+// of them if target is 0
+func (db *DB) MigrateUp(target int) error {
+	return db.withMigrationLock(func(conn *sql.Conn, migrations []*Migration, applied map[int]*appliedMigration) error {
+		// A database created before versioned migrations has tables but no
+		// history; it's adopted at the baseline
+		adopting := false
+		if len(applied) == 0 {
+			var err error
+			if adopting, err = db.hasLegacySchema(conn); err != nil {
+				return err
+			}
+		}
+
+		for _, m := range migrations {
+			if target > 0 && m.Version > target {
+				break
+			}
+			if applied[m.Version] != nil {
+				continue
+			}
+			if err := db.applyMigration(conn, m, adopting && m.Version == 1); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// MigrateDown undoes applied migrations above target, newest first
+func (db *DB) MigrateDown(target int) error {
+	return db.withMigrationLock(func(conn *sql.Conn, migrations []*Migration, applied map[int]*appliedMigration) error {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if m.Version <= target || applied[m.Version] == nil {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s can't be undone", m.Version, m.Name)
+			}
+			if err := db.revertMigration(conn, m); err != nil {
+				return fmt.Errorf("undoing migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// MigrationStatus lists every known migration and whether it has been applied
+func (db *DB) MigrationStatus() ([]*MigrationStatus, error) {
+	migrations, err := loadMigrations(db.dialect.Name())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(createSchemaMigrationsTable); err != nil {
+		return nil, err
+	}
+	applied, err := readAppliedMigrations(db.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := &MigrationStatus{Version: m.Version, Name: m.Name}
+		if a := applied[m.Version]; a != nil {
+			appliedAt := a.appliedAt
+			status.AppliedAt = &appliedAt
+			status.Drifted = a.checksum != m.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// withMigrationLock runs fn on a single connection while holding the
+// dialect's migration lock, after checking the applied migrations against
+// this build
+func (db *DB) withMigrationLock(fn func(conn *sql.Conn, migrations []*Migration, applied map[int]*appliedMigration) error) error {
+	migrations, err := loadMigrations(db.dialect.Name())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unlock, err := db.dialect.lockMigrations(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer unlock()
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	applied, err := readAppliedMigrations(conn)
+	if err != nil {
+		return err
+	}
+	if err := checkDrift(migrations, applied); err != nil {
+		return err
+	}
+
+	return fn(conn, migrations, applied)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func readAppliedMigrations(q queryer) (map[int]*appliedMigration, error) {
+	rows, err := q.QueryContext(context.Background(), `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]*appliedMigration)
+	for rows.Next() {
+		a := &appliedMigration{}
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.version] = a
+	}
+	return applied, rows.Err()
+}
+
+// checkDrift refuses to migrate a database whose history doesn't match this
+// build: an applied file that has since been edited, or a version from a
+// newer build
+func checkDrift(migrations []*Migration, applied map[int]*appliedMigration) error {
+	known := make(map[int]*Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	var problems []string
+	for version, a := range applied {
+		m := known[version]
+		switch {
+		case m == nil:
+			problems = append(problems, fmt.Sprintf("%d_%s is applied but unknown to this build", version, a.name))
+		case m.Checksum != a.checksum:
+			problems = append(problems, fmt.Sprintf("%d_%s has changed since it was applied", version, m.Name))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s", ErrMigrationDrift, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (db *DB) applyMigration(conn *sql.Conn, m *Migration, adopt bool) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Another instance may have applied it while we waited for the lock
+	var count int
+	query, args := db.dialect.Rebind(`SELECT COUNT(*) FROM schema_migrations WHERE version = $1`, []interface{}{m.Version})
+	if err := tx.QueryRow(query, args...).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(m.Up); err != nil {
+		return err
+	}
+	if adopt {
+		for _, column := range legacyColumns[db.dialect.Name()] {
+			if err := db.addColumnIfMissing(tx, column); err != nil {
+				return fmt.Errorf("adding column %s.%s failed: %w", column.table, column.column, err)
+			}
+		}
+	}
+	query, args = db.dialect.Rebind(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+		[]interface{}{m.Version, m.Name, m.Checksum, time.Now().UTC()})
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if adopt {
+		log.Printf("Adopted existing database at migration %d_%s", m.Version, m.Name)
+	} else {
+		log.Printf("Applied migration %d_%s", m.Version, m.Name)
+	}
+	return nil
+}
+
+func (db *DB) revertMigration(conn *sql.Conn, m *Migration) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.Down); err != nil {
+		return err
+	}
+	query, args := db.dialect.Rebind(`DELETE FROM schema_migrations WHERE version = $1`, []interface{}{m.Version})
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Reverted migration %d_%s", m.Version, m.Name)
+	return nil
+}
+
+// legacyUsersTable is what the users table was called before versioned
+// migrations
+var legacyUsersTable = map[string]string{
+	"postgres": "shares_alert_users",
+	"sqlite":   "users",
+}
+
+// hasLegacySchema reports whether the users table exists, meaning the
+// database was set up before versioned migrations
+func (db *DB) hasLegacySchema(conn *sql.Conn) (bool, error) {
+	table, ok := legacyUsersTable[db.dialect.Name()]
+	if !ok {
+		return false, nil
+	}
+
+	query, args := db.dialect.Rebind(db.dialect.tableExistsQuery(), []interface{}{table})
+	var count int
+	if err := conn.QueryRowContext(context.Background(), query, args...).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// columnMigration describes a column added to a table after it was first created
+type columnMigration struct {
+	table      string
+	column     string
+	definition string
+}
+
+// legacyColumns were added on boot before versioned migrations. A database
+// adopted at the baseline may predate some of them. SQLite's unprefixed
+// tables are renamed by a later migration.
+var legacyColumns = map[string][]columnMigration{
+	"postgres": {
+		{"shares_alert_user_preferences", "timezone", "TEXT NOT NULL DEFAULT ''"},
+		{"shares_alert_user_preferences", "quiet_hours_start", "TEXT NOT NULL DEFAULT ''"},
+		{"shares_alert_user_preferences", "quiet_hours_end", "TEXT NOT NULL DEFAULT ''"},
+		{"shares_alert_user_preferences", "quiet_hours_mode", "TEXT NOT NULL DEFAULT 'hold'"},
+		{"shares_alert_alerts", "urgent", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"shares_alert_alerts", "snoozed_until", "TIMESTAMP"},
+		{"shares_alert_user_preferences", "locale", "TEXT NOT NULL DEFAULT 'en'"},
+		{"shares_alert_notification_outbox", "text_body", "TEXT NOT NULL DEFAULT ''"},
+		{"shares_alert_notification_outbox", "unsubscribe_url", "TEXT NOT NULL DEFAULT ''"},
+		{"shares_alert_users", "last_login_at", "TIMESTAMP"},
+		{"shares_alert_users", "role", "TEXT NOT NULL DEFAULT 'user'"},
+		{"shares_alert_users", "deletion_scheduled_at", "TIMESTAMP"},
+		{"shares_alert_portfolios", "cost_basis_method", "TEXT NOT NULL DEFAULT 'average'"},
+		{"shares_alert_portfolio_transactions", "lot_id", "TEXT NOT NULL DEFAULT ''"},
+		{"shares_alert_alerts", "scope", "TEXT NOT NULL DEFAULT 'stock'"},
+		{"shares_alert_alerts", "portfolio_id", "TEXT"},
+		{"shares_alert_alerts", "threshold_percent", "DOUBLE PRECISION"},
+		{"shares_alert_alerts", "direction", "TEXT NOT NULL DEFAULT ''"},
+		{"shares_alert_alerts", "peak_value", "DOUBLE PRECISION"},
+		{"shares_alert_alerts", "net_invested", "DOUBLE PRECISION"},
+	},
+	"sqlite": {
+		{"user_preferences", "timezone", "TEXT NOT NULL DEFAULT ''"},
+		{"user_preferences", "quiet_hours_start", "TEXT NOT NULL DEFAULT ''"},
+		{"user_preferences", "quiet_hours_end", "TEXT NOT NULL DEFAULT ''"},
+		{"user_preferences", "quiet_hours_mode", "TEXT NOT NULL DEFAULT 'hold'"},
+		{"alerts", "urgent", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"alerts", "snoozed_until", "DATETIME"},
+		{"user_preferences", "locale", "TEXT NOT NULL DEFAULT 'en'"},
+		{"shares_alert_notification_outbox", "text_body", "TEXT NOT NULL DEFAULT ''"},
+		{"shares_alert_notification_outbox", "unsubscribe_url", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "last_login_at", "DATETIME"},
+		{"users", "role", "TEXT NOT NULL DEFAULT 'user'"},
+		{"users", "deletion_scheduled_at", "DATETIME"},
+		{"shares_alert_portfolios", "cost_basis_method", "TEXT NOT NULL DEFAULT 'average'"},
+		{"shares_alert_portfolio_transactions", "lot_id", "TEXT NOT NULL DEFAULT ''"},
+		{"alerts", "scope", "TEXT NOT NULL DEFAULT 'stock'"},
+		{"alerts", "portfolio_id", "TEXT"},
+		{"alerts", "threshold_percent", "REAL"},
+		{"alerts", "direction", "TEXT NOT NULL DEFAULT ''"},
+		{"alerts", "peak_value", "REAL"},
+		{"alerts", "net_invested", "REAL"},
+	},
+}
+
+func (db *DB) addColumnIfMissing(tx *sql.Tx, c columnMigration) error {
+	query, args := db.dialect.Rebind(db.dialect.columnExistsQuery(), []interface{}{c.table, c.column})
+	var count int
+	if err := tx.QueryRow(query, args...).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition))
+	return err
+}
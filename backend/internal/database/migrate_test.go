@@ -0,0 +1,136 @@
+package database
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"shares-alert-backend/internal/config"
+)
+
+func openTestSQLite(t *testing.T) *DB {
+	t.Helper()
+
+	db, err := Open(&config.DatabaseConfig{Type: "sqlite", FilePath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLitePrefixMigrationKeepsData(t *testing.T) {
+	db := openTestSQLite(t)
+	if err := db.MigrateUp(1); err != nil {
+		t.Fatalf("MigrateUp(1): %v", err)
+	}
+
+	// Rows written to the unprefixed tables by the baseline schema
+	for _, stmt := range []string{
+		`INSERT INTO users (id, email, name, google_id) VALUES ('u1', 'ama@example.com', 'Ama', 'g1')`,
+		`INSERT INTO user_preferences (id, user_id) VALUES ('p1', 'u1')`,
+		`INSERT INTO alerts (id, user_id, stock_symbol, stock_name, alert_type) VALUES ('a1', 'u1', 'MTNGH', 'MTN Ghana', 'price_threshold')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seeding baseline tables: %v", err)
+		}
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	for table, want := range map[string]int{
+		"shares_alert_users":            1,
+		"shares_alert_user_preferences": 1,
+		"shares_alert_alerts":           1,
+	} {
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&count); err != nil {
+			t.Fatalf("counting %s: %v", table, err)
+		}
+		if count != want {
+			t.Errorf("%s has %d rows, want %d", table, count, want)
+		}
+	}
+	var legacy int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('users', 'user_preferences', 'alerts')`).Scan(&legacy); err != nil {
+		t.Fatalf("checking for old tables: %v", err)
+	}
+	if legacy != 0 {
+		t.Errorf("%d unprefixed tables remain, want 0", legacy)
+	}
+
+	// Email-only users have no Google ID, and foreign keys follow the rename
+	if _, err := db.Exec(`INSERT INTO shares_alert_users (id, email, name) VALUES ('u2', 'kofi@example.com', 'Kofi')`); err != nil {
+		t.Errorf("inserting a user without a Google ID: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM shares_alert_users WHERE id = 'u1'`); err != nil {
+		t.Fatalf("deleting user: %v", err)
+	}
+	var alerts int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM shares_alert_alerts`).Scan(&alerts); err != nil {
+		t.Fatalf("counting alerts: %v", err)
+	}
+	if alerts != 0 {
+		t.Errorf("%d alerts survived their user's deletion, want 0", alerts)
+	}
+}
+
+func TestSQLiteMigrationsRoundTrip(t *testing.T) {
+	db := openTestSQLite(t)
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if err := db.MigrateDown(0); err != nil {
+		t.Fatalf("MigrateDown(0): %v", err)
+	}
+
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name <> 'schema_migrations'`).Scan(&tables); err != nil {
+		t.Fatalf("counting tables: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("%d tables left after migrating down, want 0", tables)
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate after MigrateDown: %v", err)
+	}
+}
+
+// rerunnableStatement matches MySQL statements that can run twice
+var rerunnableStatement = regexp.MustCompile(`(?i)^(CREATE TABLE IF NOT EXISTS|DROP TABLE IF EXISTS|SELECT) `)
+
+// MySQL commits DDL as it runs, so a migration that fails partway is rerun
+// from the top. Each file must be a single statement or safe to repeat.
+func TestMySQLMigrationsCanBeRerun(t *testing.T) {
+	migrations, err := loadMigrations("mysql")
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+
+	for _, m := range migrations {
+		var statements []string
+		for _, stmt := range strings.Split(m.Up, ";") {
+			var lines []string
+			for _, line := range strings.Split(stmt, "\n") {
+				if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+					lines = append(lines, line)
+				}
+			}
+			if len(lines) > 0 {
+				statements = append(statements, strings.Join(lines, " "))
+			}
+		}
+		if len(statements) < 2 {
+			continue
+		}
+		for _, stmt := range statements {
+			if !rerunnableStatement.MatchString(stmt) {
+				t.Errorf("%d_%s: %.60q can't be rerun; make it idempotent or give it its own file", m.Version, m.Name, stmt)
+			}
+		}
+	}
+}
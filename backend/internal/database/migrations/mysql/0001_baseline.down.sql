@@ -0,0 +1,20 @@
+-- Drops every table, children first
+DROP TABLE IF EXISTS shares_alert_watchlist_items;
+DROP TABLE IF EXISTS shares_alert_watchlists;
+DROP TABLE IF EXISTS shares_alert_market_snapshots;
+DROP TABLE IF EXISTS shares_alert_portfolio_snapshots;
+DROP TABLE IF EXISTS shares_alert_portfolio_transactions;
+DROP TABLE IF EXISTS shares_alert_portfolios;
+DROP TABLE IF EXISTS shares_alert_magic_links;
+DROP TABLE IF EXISTS shares_alert_identities;
+DROP TABLE IF EXISTS shares_alert_api_keys;
+DROP TABLE IF EXISTS shares_alert_oauth_states;
+DROP TABLE IF EXISTS shares_alert_refresh_tokens;
+DROP TABLE IF EXISTS shares_alert_sessions;
+DROP TABLE IF EXISTS shares_alert_lifecycle_emails;
+DROP TABLE IF EXISTS shares_alert_notifications;
+DROP TABLE IF EXISTS shares_alert_notification_outbox;
+DROP TABLE IF EXISTS shares_alert_digest_entries;
+DROP TABLE IF EXISTS shares_alert_alerts;
+DROP TABLE IF EXISTS shares_alert_user_preferences;
+DROP TABLE IF EXISTS shares_alert_users;
@@ -0,0 +1,257 @@
+-- Baseline: the schema as it stood when MySQL support was added, written for
+-- MySQL 5.7+ and MariaDB 10.3+. Keys and indexed columns are VARCHAR because
+-- TEXT can't be indexed without a prefix length. Foreign keys are declared
+-- at table level because InnoDB ignores inline REFERENCES. The binary
+-- collation keeps comparisons case-sensitive, as they are on Postgres.
+--
+-- MySQL commits DDL as it goes, so a failure part way through this file
+-- leaves the tables created so far. Every statement is IF NOT EXISTS, with
+-- indexes declared inline, so a retry picks up where it stopped.
+
+CREATE TABLE IF NOT EXISTS shares_alert_users (
+	id VARCHAR(191) PRIMARY KEY,
+	email VARCHAR(191) NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	picture TEXT,
+	google_id VARCHAR(191) UNIQUE,
+	email_verified BOOLEAN DEFAULT FALSE,
+	created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	last_login_at DATETIME(6),
+	role VARCHAR(64) NOT NULL DEFAULT 'user',
+	deletion_scheduled_at DATETIME(6)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_user_preferences (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	email_notifications BOOLEAN DEFAULT TRUE,
+	push_notifications BOOLEAN DEFAULT TRUE,
+	notification_frequency VARCHAR(64) DEFAULT 'immediate',
+	created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	timezone VARCHAR(64) NOT NULL DEFAULT '',
+	quiet_hours_start VARCHAR(5) NOT NULL DEFAULT '',
+	quiet_hours_end VARCHAR(5) NOT NULL DEFAULT '',
+	quiet_hours_mode VARCHAR(64) NOT NULL DEFAULT 'hold',
+	locale VARCHAR(16) NOT NULL DEFAULT 'en',
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_alerts (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	stock_symbol VARCHAR(64) NOT NULL,
+	stock_name VARCHAR(255) NOT NULL,
+	alert_type VARCHAR(64) NOT NULL,
+	threshold_price DOUBLE,
+	current_price DOUBLE,
+	status VARCHAR(64) DEFAULT 'active',
+	created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	triggered_at DATETIME(6),
+	urgent BOOLEAN NOT NULL DEFAULT FALSE,
+	snoozed_until DATETIME(6),
+	scope VARCHAR(64) NOT NULL DEFAULT 'stock',
+	portfolio_id VARCHAR(191),
+	threshold_percent DOUBLE,
+	direction VARCHAR(64) NOT NULL DEFAULT '',
+	peak_value DOUBLE,
+	net_invested DOUBLE,
+	INDEX idx_shares_alert_alerts_user_id (user_id),
+	INDEX idx_shares_alert_alerts_status (status),
+	INDEX idx_shares_alert_alerts_stock_symbol (stock_symbol),
+	INDEX idx_shares_alert_alerts_alert_type (alert_type),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_digest_entries (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	alert_id VARCHAR(191) NOT NULL,
+	stock_symbol VARCHAR(64) NOT NULL,
+	stock_name VARCHAR(255) NOT NULL,
+	alert_type VARCHAR(64) NOT NULL,
+	threshold_price DOUBLE,
+	trigger_price DOUBLE NOT NULL,
+	triggered_at DATETIME(6) NOT NULL,
+	sent_at DATETIME(6),
+	INDEX idx_shares_alert_digest_entries_user_sent (user_id, sent_at),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_notification_outbox (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	alert_id VARCHAR(191),
+	kind VARCHAR(64) NOT NULL,
+	recipient VARCHAR(255) NOT NULL,
+	subject TEXT NOT NULL,
+	body MEDIUMTEXT NOT NULL,
+	status VARCHAR(64) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME(6) NOT NULL,
+	last_error TEXT,
+	created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+	sent_at DATETIME(6),
+	text_body MEDIUMTEXT NOT NULL,
+	unsubscribe_url TEXT NOT NULL,
+	INDEX idx_shares_alert_outbox_status_next (status, next_attempt_at),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_notifications (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	kind VARCHAR(64) NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL,
+	alert_id VARCHAR(191),
+	read_at DATETIME(6),
+	created_at DATETIME(6) NOT NULL,
+	INDEX idx_shares_alert_notifications_user_created (user_id, created_at),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_lifecycle_emails (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	kind VARCHAR(64) NOT NULL,
+	sent_at DATETIME(6) NOT NULL,
+	UNIQUE (user_id, kind),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_sessions (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	user_agent VARCHAR(512) NOT NULL DEFAULT '',
+	ip_address VARCHAR(64) NOT NULL DEFAULT '',
+	created_at DATETIME(6) NOT NULL,
+	last_seen_at DATETIME(6) NOT NULL,
+	expires_at DATETIME(6) NOT NULL,
+	revoked_at DATETIME(6),
+	INDEX idx_shares_alert_sessions_user (user_id),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_refresh_tokens (
+	id VARCHAR(191) PRIMARY KEY,
+	session_id VARCHAR(191) NOT NULL,
+	token_hash VARCHAR(191) NOT NULL UNIQUE,
+	created_at DATETIME(6) NOT NULL,
+	expires_at DATETIME(6) NOT NULL,
+	used_at DATETIME(6),
+	INDEX idx_shares_alert_refresh_tokens_session (session_id),
+	FOREIGN KEY (session_id) REFERENCES shares_alert_sessions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_oauth_states (
+	state VARCHAR(191) PRIMARY KEY,
+	code_verifier VARCHAR(255) NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	expires_at DATETIME(6) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_api_keys (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	prefix VARCHAR(64) NOT NULL,
+	key_hash VARCHAR(191) NOT NULL UNIQUE,
+	scopes VARCHAR(512) NOT NULL DEFAULT '',
+	expires_at DATETIME(6) NOT NULL,
+	last_used_at DATETIME(6),
+	created_at DATETIME(6) NOT NULL,
+	revoked_at DATETIME(6),
+	INDEX idx_shares_alert_api_keys_user (user_id),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_identities (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	provider VARCHAR(64) NOT NULL,
+	subject VARCHAR(191) NOT NULL,
+	email VARCHAR(191) NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	UNIQUE (provider, subject),
+	INDEX idx_shares_alert_identities_user (user_id),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_magic_links (
+	id VARCHAR(191) PRIMARY KEY,
+	email VARCHAR(191) NOT NULL,
+	token_hash VARCHAR(191) NOT NULL UNIQUE,
+	created_at DATETIME(6) NOT NULL,
+	expires_at DATETIME(6) NOT NULL,
+	used_at DATETIME(6),
+	INDEX idx_shares_alert_magic_links_email (email, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_portfolios (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	updated_at DATETIME(6) NOT NULL,
+	cost_basis_method VARCHAR(64) NOT NULL DEFAULT 'average',
+	INDEX idx_shares_alert_portfolios_user (user_id),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_portfolio_transactions (
+	id VARCHAR(191) PRIMARY KEY,
+	portfolio_id VARCHAR(191) NOT NULL,
+	type VARCHAR(64) NOT NULL,
+	stock_symbol VARCHAR(64) NOT NULL DEFAULT '',
+	quantity DOUBLE NOT NULL DEFAULT 0,
+	price DOUBLE NOT NULL DEFAULT 0,
+	fees DOUBLE NOT NULL DEFAULT 0,
+	trade_date DATETIME(6) NOT NULL,
+	notes TEXT NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	lot_id VARCHAR(191) NOT NULL DEFAULT '',
+	INDEX idx_shares_alert_portfolio_transactions_portfolio (portfolio_id, trade_date),
+	FOREIGN KEY (portfolio_id) REFERENCES shares_alert_portfolios(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_portfolio_snapshots (
+	portfolio_id VARCHAR(191) NOT NULL,
+	snapshot_date VARCHAR(10) NOT NULL,
+	market_value DOUBLE NOT NULL,
+	cost_basis DOUBLE NOT NULL,
+	net_flow DOUBLE NOT NULL DEFAULT 0,
+	created_at DATETIME(6) NOT NULL,
+	PRIMARY KEY (portfolio_id, snapshot_date),
+	FOREIGN KEY (portfolio_id) REFERENCES shares_alert_portfolios(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_market_snapshots (
+	snapshot_date VARCHAR(10) PRIMARY KEY,
+	index_value DOUBLE NOT NULL,
+	created_at DATETIME(6) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_watchlists (
+	id VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME(6) NOT NULL,
+	updated_at DATETIME(6) NOT NULL,
+	INDEX idx_shares_alert_watchlists_user (user_id, position),
+	FOREIGN KEY (user_id) REFERENCES shares_alert_users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE IF NOT EXISTS shares_alert_watchlist_items (
+	watchlist_id VARCHAR(191) NOT NULL,
+	stock_symbol VARCHAR(64) NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	notes TEXT NOT NULL,
+	added_at DATETIME(6) NOT NULL,
+	PRIMARY KEY (watchlist_id, stock_symbol),
+	FOREIGN KEY (watchlist_id) REFERENCES shares_alert_watchlists(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
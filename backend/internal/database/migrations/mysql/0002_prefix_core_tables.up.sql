@@ -0,0 +1,3 @@
+-- MySQL support arrived after the SQLite rename; kept so versions line up
+-- across dialects.
+SELECT 1;
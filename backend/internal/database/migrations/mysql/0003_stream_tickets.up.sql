@@ -0,0 +1,10 @@
+-- Single-use tickets that open the notification stream, since EventSource
+-- can't send an Authorization header. Only a hash of each ticket is kept.
+CREATE TABLE IF NOT EXISTS shares_alert_stream_tickets (
+	ticket_hash VARCHAR(191) PRIMARY KEY,
+	user_id VARCHAR(191) NOT NULL,
+	session_id VARCHAR(191) NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	expires_at DATETIME(6) NOT NULL,
+	FOREIGN KEY (session_id) REFERENCES shares_alert_sessions(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
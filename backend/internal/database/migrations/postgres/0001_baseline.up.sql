@@ -0,0 +1,248 @@
+-- Baseline: the schema as it stood when versioned migrations were introduced.
+-- Databases created before then are adopted at this version; the runner adds
+-- any columns they're missing.
+
+CREATE TABLE IF NOT EXISTS shares_alert_users (
+	id TEXT PRIMARY KEY,
+	email TEXT UNIQUE NOT NULL,
+	name TEXT NOT NULL,
+	picture TEXT,
+	google_id TEXT UNIQUE NOT NULL,
+	email_verified BOOLEAN DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	last_login_at TIMESTAMP,
+	role TEXT NOT NULL DEFAULT 'user',
+	deletion_scheduled_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_user_preferences (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	email_notifications BOOLEAN DEFAULT TRUE,
+	push_notifications BOOLEAN DEFAULT TRUE,
+	notification_frequency TEXT DEFAULT 'immediate',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	timezone TEXT NOT NULL DEFAULT '',
+	quiet_hours_start TEXT NOT NULL DEFAULT '',
+	quiet_hours_end TEXT NOT NULL DEFAULT '',
+	quiet_hours_mode TEXT NOT NULL DEFAULT 'hold',
+	locale TEXT NOT NULL DEFAULT 'en'
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_alerts (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	stock_symbol TEXT NOT NULL,
+	stock_name TEXT NOT NULL,
+	alert_type TEXT NOT NULL,
+	threshold_price REAL,
+	current_price REAL,
+	status TEXT DEFAULT 'active',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	triggered_at TIMESTAMP,
+	urgent BOOLEAN NOT NULL DEFAULT FALSE,
+	snoozed_until TIMESTAMP,
+	scope TEXT NOT NULL DEFAULT 'stock',
+	portfolio_id TEXT,
+	threshold_percent DOUBLE PRECISION,
+	direction TEXT NOT NULL DEFAULT '',
+	peak_value DOUBLE PRECISION,
+	net_invested DOUBLE PRECISION
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_alerts_user_id ON shares_alert_alerts(user_id);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_alerts_status ON shares_alert_alerts(status);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_alerts_stock_symbol ON shares_alert_alerts(stock_symbol);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_alerts_alert_type ON shares_alert_alerts(alert_type);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_users_email ON shares_alert_users(email);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_users_google_id ON shares_alert_users(google_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_digest_entries (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	alert_id TEXT NOT NULL,
+	stock_symbol TEXT NOT NULL,
+	stock_name TEXT NOT NULL,
+	alert_type TEXT NOT NULL,
+	threshold_price REAL,
+	trigger_price REAL NOT NULL,
+	triggered_at TIMESTAMP NOT NULL,
+	sent_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_digest_entries_user_sent ON shares_alert_digest_entries(user_id, sent_at);
+
+CREATE TABLE IF NOT EXISTS shares_alert_notification_outbox (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	alert_id TEXT,
+	kind TEXT NOT NULL,
+	recipient TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	body TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL,
+	last_error TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	sent_at TIMESTAMP,
+	text_body TEXT NOT NULL DEFAULT '',
+	unsubscribe_url TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_outbox_status_next ON shares_alert_notification_outbox(status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS shares_alert_notifications (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL,
+	alert_id TEXT,
+	read_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_notifications_user_created ON shares_alert_notifications(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS shares_alert_lifecycle_emails (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	sent_at TIMESTAMP NOT NULL,
+	UNIQUE (user_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_sessions (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip_address TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	last_seen_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_sessions_user ON shares_alert_sessions(user_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_refresh_tokens (
+	id TEXT PRIMARY KEY,
+	session_id TEXT NOT NULL REFERENCES shares_alert_sessions(id) ON DELETE CASCADE,
+	token_hash TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_refresh_tokens_session ON shares_alert_refresh_tokens(session_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_oauth_states (
+	state TEXT PRIMARY KEY,
+	code_verifier TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_api_keys (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT UNIQUE NOT NULL,
+	scopes TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMP NOT NULL,
+	last_used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_api_keys_user ON shares_alert_api_keys(user_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_identities (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	UNIQUE (provider, subject)
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_identities_user ON shares_alert_identities(user_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_magic_links (
+	id TEXT PRIMARY KEY,
+	email TEXT NOT NULL,
+	token_hash TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_magic_links_email ON shares_alert_magic_links(email, created_at);
+
+-- Sign-in methods live in shares_alert_identities, so users who sign in by
+-- email have no Google ID
+ALTER TABLE shares_alert_users ALTER COLUMN google_id DROP NOT NULL;
+
+-- Every Google user has a Google identity
+INSERT INTO shares_alert_identities (id, user_id, provider, subject, email, created_at)
+SELECT 'google:' || google_id, id, 'google', google_id, email, created_at FROM shares_alert_users
+WHERE google_id IS NOT NULL AND google_id <> ''
+ON CONFLICT (provider, subject) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS shares_alert_portfolios (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	cost_basis_method TEXT NOT NULL DEFAULT 'average'
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_portfolios_user ON shares_alert_portfolios(user_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_portfolio_transactions (
+	id TEXT PRIMARY KEY,
+	portfolio_id TEXT NOT NULL REFERENCES shares_alert_portfolios(id) ON DELETE CASCADE,
+	type TEXT NOT NULL,
+	stock_symbol TEXT NOT NULL DEFAULT '',
+	quantity DOUBLE PRECISION NOT NULL DEFAULT 0,
+	price DOUBLE PRECISION NOT NULL DEFAULT 0,
+	fees DOUBLE PRECISION NOT NULL DEFAULT 0,
+	trade_date TIMESTAMP NOT NULL,
+	notes TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	lot_id TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_portfolio_transactions_portfolio ON shares_alert_portfolio_transactions(portfolio_id, trade_date);
+
+CREATE TABLE IF NOT EXISTS shares_alert_portfolio_snapshots (
+	portfolio_id TEXT NOT NULL REFERENCES shares_alert_portfolios(id) ON DELETE CASCADE,
+	snapshot_date TEXT NOT NULL,
+	market_value DOUBLE PRECISION NOT NULL,
+	cost_basis DOUBLE PRECISION NOT NULL,
+	net_flow DOUBLE PRECISION NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (portfolio_id, snapshot_date)
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_market_snapshots (
+	snapshot_date TEXT PRIMARY KEY,
+	index_value DOUBLE PRECISION NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_watchlists (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES shares_alert_users(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_watchlists_user ON shares_alert_watchlists(user_id, position);
+
+CREATE TABLE IF NOT EXISTS shares_alert_watchlist_items (
+	watchlist_id TEXT NOT NULL REFERENCES shares_alert_watchlists(id) ON DELETE CASCADE,
+	stock_symbol TEXT NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	notes TEXT NOT NULL DEFAULT '',
+	added_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (watchlist_id, stock_symbol)
+);
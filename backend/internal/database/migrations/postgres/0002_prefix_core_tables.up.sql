@@ -0,0 +1,3 @@
+-- Postgres tables have always had the shares_alert_ prefix; this version
+-- only renames SQLite's. Kept so versions line up across dialects.
+SELECT 1;
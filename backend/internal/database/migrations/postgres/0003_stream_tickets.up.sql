@@ -0,0 +1,9 @@
+-- Single-use tickets that open the notification stream, since EventSource
+-- can't send an Authorization header. Only a hash of each ticket is kept.
+CREATE TABLE IF NOT EXISTS shares_alert_stream_tickets (
+	ticket_hash TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	session_id TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
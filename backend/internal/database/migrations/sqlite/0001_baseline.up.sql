@@ -0,0 +1,246 @@
+-- Baseline: the schema as it stood when versioned migrations were introduced.
+-- Databases created before then are adopted at this version; the runner adds
+-- any columns they're missing.
+
+CREATE TABLE IF NOT EXISTS users (
+	id TEXT PRIMARY KEY,
+	email TEXT UNIQUE NOT NULL,
+	name TEXT NOT NULL,
+	picture TEXT,
+	google_id TEXT UNIQUE NOT NULL,
+	email_verified BOOLEAN DEFAULT FALSE,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_login_at DATETIME,
+	role TEXT NOT NULL DEFAULT 'user',
+	deletion_scheduled_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	email_notifications BOOLEAN DEFAULT TRUE,
+	push_notifications BOOLEAN DEFAULT TRUE,
+	notification_frequency TEXT DEFAULT 'immediate',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	timezone TEXT NOT NULL DEFAULT '',
+	quiet_hours_start TEXT NOT NULL DEFAULT '',
+	quiet_hours_end TEXT NOT NULL DEFAULT '',
+	quiet_hours_mode TEXT NOT NULL DEFAULT 'hold',
+	locale TEXT NOT NULL DEFAULT 'en',
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	stock_symbol TEXT NOT NULL,
+	stock_name TEXT NOT NULL,
+	alert_type TEXT NOT NULL,
+	threshold_price REAL,
+	current_price REAL,
+	status TEXT DEFAULT 'active',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	triggered_at DATETIME,
+	urgent BOOLEAN NOT NULL DEFAULT FALSE,
+	snoozed_until DATETIME,
+	scope TEXT NOT NULL DEFAULT 'stock',
+	portfolio_id TEXT,
+	threshold_percent REAL,
+	direction TEXT NOT NULL DEFAULT '',
+	peak_value REAL,
+	net_invested REAL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_alerts_user_id ON alerts(user_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_status ON alerts(status);
+CREATE INDEX IF NOT EXISTS idx_alerts_stock_symbol ON alerts(stock_symbol);
+CREATE INDEX IF NOT EXISTS idx_alerts_alert_type ON alerts(alert_type);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_users_google_id ON users(google_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_digest_entries (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	alert_id TEXT NOT NULL,
+	stock_symbol TEXT NOT NULL,
+	stock_name TEXT NOT NULL,
+	alert_type TEXT NOT NULL,
+	threshold_price REAL,
+	trigger_price REAL NOT NULL,
+	triggered_at DATETIME NOT NULL,
+	sent_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_digest_entries_user_sent ON shares_alert_digest_entries(user_id, sent_at);
+
+CREATE TABLE IF NOT EXISTS shares_alert_notification_outbox (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	alert_id TEXT,
+	kind TEXT NOT NULL,
+	recipient TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	body TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME NOT NULL,
+	last_error TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	sent_at DATETIME,
+	text_body TEXT NOT NULL DEFAULT '',
+	unsubscribe_url TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_outbox_status_next ON shares_alert_notification_outbox(status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS shares_alert_notifications (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL,
+	alert_id TEXT,
+	read_at DATETIME,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_notifications_user_created ON shares_alert_notifications(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS shares_alert_lifecycle_emails (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	sent_at DATETIME NOT NULL,
+	UNIQUE (user_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_sessions (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip_address TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	last_seen_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_sessions_user ON shares_alert_sessions(user_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_refresh_tokens (
+	id TEXT PRIMARY KEY,
+	session_id TEXT NOT NULL,
+	token_hash TEXT UNIQUE NOT NULL,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_refresh_tokens_session ON shares_alert_refresh_tokens(session_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_oauth_states (
+	state TEXT PRIMARY KEY,
+	code_verifier TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_api_keys (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT UNIQUE NOT NULL,
+	scopes TEXT NOT NULL DEFAULT '',
+	expires_at DATETIME NOT NULL,
+	last_used_at DATETIME,
+	created_at DATETIME NOT NULL,
+	revoked_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_api_keys_user ON shares_alert_api_keys(user_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_identities (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (provider, subject)
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_identities_user ON shares_alert_identities(user_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_magic_links (
+	id TEXT PRIMARY KEY,
+	email TEXT NOT NULL,
+	token_hash TEXT UNIQUE NOT NULL,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_magic_links_email ON shares_alert_magic_links(email, created_at);
+
+-- Every Google user has a Google identity
+INSERT INTO shares_alert_identities (id, user_id, provider, subject, email, created_at)
+SELECT 'google:' || google_id, id, 'google', google_id, email, created_at FROM users
+WHERE google_id IS NOT NULL AND google_id <> ''
+ON CONFLICT (provider, subject) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS shares_alert_portfolios (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	cost_basis_method TEXT NOT NULL DEFAULT 'average'
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_portfolios_user ON shares_alert_portfolios(user_id);
+
+CREATE TABLE IF NOT EXISTS shares_alert_portfolio_transactions (
+	id TEXT PRIMARY KEY,
+	portfolio_id TEXT NOT NULL,
+	type TEXT NOT NULL,
+	stock_symbol TEXT NOT NULL DEFAULT '',
+	quantity REAL NOT NULL DEFAULT 0,
+	price REAL NOT NULL DEFAULT 0,
+	fees REAL NOT NULL DEFAULT 0,
+	trade_date DATETIME NOT NULL,
+	notes TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	lot_id TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_portfolio_transactions_portfolio ON shares_alert_portfolio_transactions(portfolio_id, trade_date);
+
+CREATE TABLE IF NOT EXISTS shares_alert_portfolio_snapshots (
+	portfolio_id TEXT NOT NULL,
+	snapshot_date TEXT NOT NULL,
+	market_value REAL NOT NULL,
+	cost_basis REAL NOT NULL,
+	net_flow REAL NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (portfolio_id, snapshot_date)
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_market_snapshots (
+	snapshot_date TEXT PRIMARY KEY,
+	index_value REAL NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS shares_alert_watchlists (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_shares_alert_watchlists_user ON shares_alert_watchlists(user_id, position);
+
+CREATE TABLE IF NOT EXISTS shares_alert_watchlist_items (
+	watchlist_id TEXT NOT NULL,
+	stock_symbol TEXT NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	notes TEXT NOT NULL DEFAULT '',
+	added_at DATETIME NOT NULL,
+	PRIMARY KEY (watchlist_id, stock_symbol)
+);
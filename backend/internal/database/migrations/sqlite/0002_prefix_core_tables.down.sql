@@ -0,0 +1,84 @@
+-- Puts users, user_preferences and alerts back under their unprefixed names.
+-- google_id stays nullable: users who signed in by email have none.
+
+CREATE TABLE users (
+	id TEXT PRIMARY KEY,
+	email TEXT UNIQUE NOT NULL,
+	name TEXT NOT NULL,
+	picture TEXT,
+	google_id TEXT UNIQUE,
+	email_verified BOOLEAN DEFAULT FALSE,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_login_at DATETIME,
+	role TEXT NOT NULL DEFAULT 'user',
+	deletion_scheduled_at DATETIME
+);
+INSERT INTO users (id, email, name, picture, google_id, email_verified, created_at, updated_at,
+	last_login_at, role, deletion_scheduled_at)
+SELECT id, email, name, picture, google_id, email_verified, created_at, updated_at,
+	last_login_at, role, deletion_scheduled_at
+FROM shares_alert_users;
+
+CREATE TABLE user_preferences (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	email_notifications BOOLEAN DEFAULT TRUE,
+	push_notifications BOOLEAN DEFAULT TRUE,
+	notification_frequency TEXT DEFAULT 'immediate',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	timezone TEXT NOT NULL DEFAULT '',
+	quiet_hours_start TEXT NOT NULL DEFAULT '',
+	quiet_hours_end TEXT NOT NULL DEFAULT '',
+	quiet_hours_mode TEXT NOT NULL DEFAULT 'hold',
+	locale TEXT NOT NULL DEFAULT 'en'
+);
+INSERT INTO user_preferences (id, user_id, email_notifications, push_notifications,
+	notification_frequency, created_at, updated_at, timezone, quiet_hours_start, quiet_hours_end,
+	quiet_hours_mode, locale)
+SELECT id, user_id, email_notifications, push_notifications,
+	notification_frequency, created_at, updated_at, timezone, quiet_hours_start, quiet_hours_end,
+	quiet_hours_mode, locale
+FROM shares_alert_user_preferences;
+
+CREATE TABLE alerts (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	stock_symbol TEXT NOT NULL,
+	stock_name TEXT NOT NULL,
+	alert_type TEXT NOT NULL,
+	threshold_price REAL,
+	current_price REAL,
+	status TEXT DEFAULT 'active',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	triggered_at DATETIME,
+	urgent BOOLEAN NOT NULL DEFAULT FALSE,
+	snoozed_until DATETIME,
+	scope TEXT NOT NULL DEFAULT 'stock',
+	portfolio_id TEXT,
+	threshold_percent REAL,
+	direction TEXT NOT NULL DEFAULT '',
+	peak_value REAL,
+	net_invested REAL
+);
+INSERT INTO alerts (id, user_id, stock_symbol, stock_name, alert_type, threshold_price,
+	current_price, status, created_at, updated_at, triggered_at, urgent, snoozed_until, scope,
+	portfolio_id, threshold_percent, direction, peak_value, net_invested)
+SELECT id, user_id, stock_symbol, stock_name, alert_type, threshold_price,
+	current_price, status, created_at, updated_at, triggered_at, urgent, snoozed_until, scope,
+	portfolio_id, threshold_percent, direction, peak_value, net_invested
+FROM shares_alert_alerts;
+
+-- Children first, so dropping users cascades into nothing
+DROP TABLE shares_alert_alerts;
+DROP TABLE shares_alert_user_preferences;
+DROP TABLE shares_alert_users;
+
+CREATE INDEX idx_alerts_user_id ON alerts(user_id);
+CREATE INDEX idx_alerts_status ON alerts(status);
+CREATE INDEX idx_alerts_stock_symbol ON alerts(stock_symbol);
+CREATE INDEX idx_alerts_alert_type ON alerts(alert_type);
+CREATE INDEX idx_users_email ON users(email);
+CREATE INDEX idx_users_google_id ON users(google_id);
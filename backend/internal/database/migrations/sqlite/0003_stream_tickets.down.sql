@@ -0,0 +1 @@
+DROP TABLE IF EXISTS shares_alert_stream_tickets;
@@ -0,0 +1,31 @@
+package database
+
+import "context"
+
+type txContextKey struct{}
+
+func txFromContext(ctx context.Context) *Tx {
+	tx, _ := ctx.Value(txContextKey{}).(*Tx)
+	return tx
+}
+
+// WithTx runs fn in a transaction, committing it if fn returns nil and
+// rolling it back otherwise. Queries made through the DB with the context fn
+// is given run in that transaction. If ctx already carries one, fn simply
+// joins it, and the outermost WithTx decides whether it commits.
+func (db *DB) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
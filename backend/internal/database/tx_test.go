@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithTx(t *testing.T) {
+	db := openTestSQLite(t)
+	ctx := context.Background()
+	if _, err := db.Exec(`CREATE TABLE items (name TEXT NOT NULL)`); err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+	count := func() int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM items`).Scan(&n); err != nil {
+			t.Fatalf("counting items: %v", err)
+		}
+		return n
+	}
+
+	// A nested WithTx joins the outer transaction, so the outer error undoes both inserts
+	errFailed := errors.New("failed")
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := db.ExecContext(ctx, `INSERT INTO items (name) VALUES ($1)`, "outer"); err != nil {
+			return err
+		}
+		if err := db.WithTx(ctx, func(ctx context.Context) error {
+			_, err := db.ExecContext(ctx, `INSERT INTO items (name) VALUES ($1)`, "inner")
+			return err
+		}); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("WithTx error = %v, want %v", err, errFailed)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("%d items after rollback, want 0", n)
+	}
+
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, `INSERT INTO items (name) VALUES ($1)`, "committed")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("%d items after commit, want 1", n)
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/services"
+)
+
+type AccountHandler struct {
+	accountService *services.AccountService
+}
+
+func NewAccountHandler(accountService *services.AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+	}
+}
+
+// Export downloads everything stored about the user as a JSON file
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.accountService.Export(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to export account data", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "shares-alert-export-" + time.Now().UTC().Format("20060102") + ".json"
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, export)
+}
+
+// DeleteAccount schedules the account for deletion after the grace period
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	scheduledAt, err := h.accountService.RequestDeletion(r.Context(), user)
+	if err != nil {
+		http.Error(w, "Failed to schedule account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	render.JSON(w, r, models.AccountDeletionResponse{DeletionScheduledAt: scheduledAt})
+}
+
+func (h *AccountHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.accountService.CancelDeletion(r.Context(), user.ID); err != nil {
+		if err == services.ErrDeletionNotScheduled {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to cancel account deletion", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
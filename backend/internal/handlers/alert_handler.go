@@ -2,7 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -28,25 +34,97 @@ func (h *AlertHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse query parameters for filtering
-	filters := make(map[string]interface{})
-	if status := r.URL.Query().Get("status"); status != "" {
-		filters["status"] = status
-	}
-	if stockSymbol := r.URL.Query().Get("stockSymbol"); stockSymbol != "" {
-		filters["stock_symbol"] = stockSymbol
+	query := r.URL.Query()
+	filter, err := parseAlertFilter(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if alertType := r.URL.Query().Get("alertType"); alertType != "" {
-		filters["alert_type"] = alertType
+
+	limit := 0
+	if raw := query.Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
 	}
 
-	alerts, err := h.alertService.GetUserAlerts(user.ID, filters)
+	page, err := h.alertService.ListAlerts(r.Context(), user.ID, filter, query.Get("sort"), query.Get("cursor"), limit)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidAlertQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Failed to fetch alerts: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	render.JSON(w, r, alerts)
+	render.JSON(w, r, page)
+}
+
+// parseAlertFilter reads the GET /alerts filters. Each list filter may be
+// repeated or comma-separated, and matches any of its values.
+func parseAlertFilter(query url.Values) (models.AlertFilter, error) {
+	filter := models.AlertFilter{
+		Statuses:     listParam(query, "status"),
+		StockSymbols: listParam(query, "stockSymbol"),
+		AlertTypes:   listParam(query, "alertType"),
+		Scopes:       listParam(query, "scope"),
+		PortfolioIDs: listParam(query, "portfolioId"),
+		Search:       query.Get("q"),
+	}
+
+	for _, p := range []struct {
+		name  string
+		dest  **time.Time
+		until bool
+	}{
+		{"createdFrom", &filter.CreatedFrom, false},
+		{"createdTo", &filter.CreatedTo, true},
+		{"triggeredFrom", &filter.TriggeredFrom, false},
+		{"triggeredTo", &filter.TriggeredTo, true},
+	} {
+		raw := query.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		at, err := parseTimeParam(raw, p.until)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date", p.name)
+		}
+		*p.dest = &at
+	}
+
+	return filter, nil
+}
+
+// listParam collects a query parameter's values, split on commas
+func listParam(query url.Values, name string) []string {
+	var values []string
+	for _, raw := range query[name] {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// parseTimeParam reads an RFC 3339 time or a UTC date. A date that ends a
+// range covers the whole day, so it's read as the start of the next.
+func parseTimeParam(raw string, until bool) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, raw); err == nil {
+		return at, nil
+	}
+	day, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if until {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
 }
 
 func (h *AlertHandler) CreateAlert(w http.ResponseWriter, r *http.Request) {
@@ -62,7 +140,7 @@ func (h *AlertHandler) CreateAlert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	alert, err := h.alertService.CreateAlert(user.ID, &req)
+	alert, err := h.alertService.CreateAlert(r.Context(), user.ID, &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -85,7 +163,7 @@ func (h *AlertHandler) GetAlert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	alert, err := h.alertService.GetAlert(alertID, user.ID)
+	alert, err := h.alertService.GetAlert(r.Context(), alertID, user.ID)
 	if err != nil {
 		http.Error(w, "Alert not found", http.StatusNotFound)
 		return
@@ -113,7 +191,7 @@ func (h *AlertHandler) UpdateAlert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	alert, err := h.alertService.UpdateAlert(alertID, user.ID, &req)
+	alert, err := h.alertService.UpdateAlert(r.Context(), alertID, user.ID, &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -135,7 +213,7 @@ func (h *AlertHandler) DeleteAlert(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.alertService.DeleteAlert(alertID, user.ID); err != nil {
+	if err := h.alertService.DeleteAlert(r.Context(), alertID, user.ID); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
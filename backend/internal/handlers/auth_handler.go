@@ -1,42 +1,70 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
+	"net"
 	"net/http"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 
+	"shares-alert-backend/internal/models"
 	"shares-alert-backend/internal/services"
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
+	authService   *services.AuthService
+	apiKeyService *services.APIKeyService
 }
 
 type AuthResponse struct {
-	User  interface{} `json:"user"`
-	Token string      `json:"token"`
+	User         interface{} `json:"user,omitempty"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refreshToken"`
+	ExpiresIn    int         `json:"expiresIn"`
 }
 
 type GoogleAuthRequest struct {
-	Code string `json:"code"`
+	Code  string `json:"code"`
+	State string `json:"state"`
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func NewAuthHandler(authService *services.AuthService, apiKeyService *services.APIKeyService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:   authService,
+		apiKeyService: apiKeyService,
 	}
 }
 
 func (h *AuthHandler) GetGoogleAuthURL(w http.ResponseWriter, r *http.Request) {
-	state := r.URL.Query().Get("state")
-	if state == "" {
-		state = "random-state-string" // In production, generate a proper state
+	authURL, state, err := h.authService.GetGoogleAuthURL(r.Context())
+	if err != nil {
+		log.Printf("Failed to start Google login: %v", err)
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
 	}
 
-	authURL := h.authService.GetGoogleAuthURL(state)
-	render.JSON(w, r, map[string]string{"authUrl": authURL})
+	http.SetCookie(w, h.authService.OAuthStateCookie(state))
+	render.JSON(w, r, map[string]string{"authUrl": authURL, "state": state})
+}
+
+// oauthStateCookie reads the cookie set when the login attempt was started,
+// and clears it: each state works once either way
+func (h *AuthHandler) oauthStateCookie(w http.ResponseWriter, r *http.Request) string {
+	http.SetCookie(w, h.authService.ExpiredOAuthStateCookie())
+	cookie, err := r.Cookie(services.OAuthStateCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
 }
 
 func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
@@ -51,20 +79,229 @@ func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, token, err := h.authService.HandleGoogleCallback(req.Code)
+	user, tokens, err := h.authService.HandleGoogleCallback(r.Context(), req.Code, req.State, h.oauthStateCookie(w, r), clientInfo(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMissingOAuthState),
+			errors.Is(err, services.ErrInvalidOAuthState),
+			errors.Is(err, services.ErrExpiredOAuthState),
+			errors.Is(err, services.ErrOAuthStateMismatch):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Authentication failed: "+err.Error(), http.StatusUnauthorized)
+		}
+		return
+	}
+
+	response := AuthResponse{
+		User:         user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+
+	render.JSON(w, r, response)
+}
+
+// RequestMagicLink emails a sign-in link. It answers the same way whether or
+// not the address has an account.
+func (h *AuthHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req models.MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RequestMagicLink(r.Context(), req.Email, req.Locale); err != nil {
+		if errors.Is(err, services.ErrInvalidEmail) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to send magic link: %v", err)
+		http.Error(w, "Failed to send sign-in link", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	render.JSON(w, r, map[string]string{"message": "If that address can sign in, a link is on its way"})
+}
+
+// VerifyMagicLink exchanges the token from a sign-in link for a session
+func (h *AuthHandler) VerifyMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req models.MagicLinkVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	user, tokens, err := h.authService.LoginWithMagicLink(r.Context(), req.Token, clientInfo(r))
 	if err != nil {
-		http.Error(w, "Authentication failed: "+err.Error(), http.StatusUnauthorized)
+		if errors.Is(err, services.ErrInvalidMagicLink) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		log.Printf("Failed to sign in with magic link: %v", err)
+		http.Error(w, "Authentication failed", http.StatusInternalServerError)
 		return
 	}
 
 	response := AuthResponse{
-		User:  user,
-		Token: token,
+		User:         user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 	}
 
 	render.JSON(w, r, response)
 }
 
+func (h *AuthHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok || user == nil {
+		http.Error(w, "User not found in context", http.StatusInternalServerError)
+		return
+	}
+
+	identities, err := h.authService.ListIdentities(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch identities", http.StatusInternalServerError)
+		return
+	}
+	if identities == nil {
+		identities = []*models.Identity{}
+	}
+
+	render.JSON(w, r, identities)
+}
+
+// LinkGoogle adds a Google account as a sign-in method. The client runs the
+// usual Google flow (GET /auth/google) and posts the code and state here
+// instead of to the login callback.
+func (h *AuthHandler) LinkGoogle(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok || user == nil {
+		http.Error(w, "User not found in context", http.StatusInternalServerError)
+		return
+	}
+
+	var req GoogleAuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" {
+		http.Error(w, "Authorization code is required", http.StatusBadRequest)
+		return
+	}
+
+	identity, err := h.authService.LinkGoogle(r.Context(), user, req.Code, req.State, h.oauthStateCookie(w, r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrIdentityInUse):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, services.ErrMissingOAuthState),
+			errors.Is(err, services.ErrInvalidOAuthState),
+			errors.Is(err, services.ErrExpiredOAuthState),
+			errors.Is(err, services.ErrOAuthStateMismatch):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to link Google account: "+err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	render.JSON(w, r, identity)
+}
+
+func (h *AuthHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok || user == nil {
+		http.Error(w, "User not found in context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.authService.UnlinkIdentity(r.Context(), user.ID, chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, services.ErrIdentityNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to unlink identity", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Refresh swaps a refresh token for a new access token and refresh token.
+// The old refresh token stops working.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		http.Error(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.authService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRefreshToken),
+			errors.Is(err, services.ErrRefreshTokenReused),
+			errors.Is(err, services.ErrSessionRevoked):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			log.Printf("Failed to refresh token: %v", err)
+			http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := AuthResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+
+	render.JSON(w, r, response)
+}
+
+// IssueStreamTicket returns a single-use ticket for opening the notification
+// stream, valid for a few seconds
+func (h *AuthHandler) IssueStreamTicket(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	sessionID, hasSession := getSessionFromContext(r.Context())
+	if !ok || user == nil || !hasSession {
+		http.Error(w, "Session not found in context", http.StatusInternalServerError)
+		return
+	}
+
+	ticket, expiresIn, err := h.authService.IssueStreamTicket(r.Context(), user.ID, sessionID)
+	if err != nil {
+		log.Printf("Failed to issue stream ticket: %v", err)
+		http.Error(w, "Failed to issue stream ticket", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{"ticket": ticket, "expiresIn": expiresIn})
+}
+
+// JWKS publishes the token verification keys at /.well-known/jwks.json
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	render.JSON(w, r, h.authService.JWKS())
+}
+
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	user, ok := getUserFromContext(r.Context())
 	if !ok || user == nil {
@@ -75,13 +312,44 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, user)
 }
 
+// Logout revokes the current session, invalidating its access and refresh tokens
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	// For JWT-based auth, logout is handled client-side by removing the token
-	// Here we just return a success response
+	sessionID, ok := getSessionFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Session not found in context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), sessionID); err != nil {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
 	render.JSON(w, r, map[string]string{"message": "Logged out successfully"})
 }
 
-// Middleware to authenticate requests
+// LogoutAll revokes every session the user has, logging out all devices
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok || user == nil {
+		http.Error(w, "User not found in context", http.StatusInternalServerError)
+		return
+	}
+
+	revoked, err := h.authService.RevokeAllSessions(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message":         "Logged out of all devices",
+		"sessionsRevoked": revoked,
+	})
+}
+
+// Middleware to authenticate requests. Accepts either a session access token
+// ("Bearer <jwt>") or a personal API key ("ApiKey <key>").
 func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -90,23 +358,19 @@ func (h *AuthHandler) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Extract token from "Bearer <token>"
+		// Extract credentials from "<scheme> <credentials>"
 		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
 			http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
 			return
 		}
 
-		token := parts[1]
-		user, err := h.authService.GetUserFromToken(token)
+		ctx, err := h.authenticate(r, parts[0], parts[1])
 		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		// Add user to request context
-		ctx := r.Context()
-		ctx = setUserInContext(ctx, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -117,15 +381,113 @@ func (h *AuthHandler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader != "" {
 			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) == 2 && parts[0] == "Bearer" {
-				token := parts[1]
-				if user, err := h.authService.GetUserFromToken(token); err == nil {
-					ctx := r.Context()
-					ctx = setUserInContext(ctx, user)
+			if len(parts) == 2 {
+				if ctx, err := h.authenticate(r, parts[0], parts[1]); err == nil {
 					r = r.WithContext(ctx)
 				}
 			}
 		}
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// authenticate resolves credentials and returns the request context with the
+// user, and the session or API key, added to it
+func (h *AuthHandler) authenticate(r *http.Request, scheme, credentials string) (context.Context, error) {
+	ctx := r.Context()
+	switch scheme {
+	case "Bearer":
+		user, claims, err := h.authService.Authenticate(r.Context(), credentials)
+		if err != nil {
+			return nil, err
+		}
+		ctx = setUserInContext(ctx, user)
+		ctx = setSessionInContext(ctx, claims.SessionID)
+	case "ApiKey":
+		user, key, err := h.apiKeyService.Authenticate(r.Context(), credentials)
+		if err != nil {
+			return nil, err
+		}
+		ctx = setUserInContext(ctx, user)
+		ctx = setAPIKeyInContext(ctx, key)
+	default:
+		return nil, errors.New("unsupported authorization scheme")
+	}
+	return ctx, nil
+}
+
+// RequireScope limits API keys to routes their scopes cover. Session tokens
+// have full access and pass straight through.
+func (h *AuthHandler) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := getAPIKeyFromContext(r.Context()); ok && !key.HasScope(scope) {
+				http.Error(w, "API key is missing the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole only lets users with the given role through. Run it after
+// AuthMiddleware. The role is read from the database on every request, so
+// demoting someone takes effect immediately.
+func (h *AuthHandler) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := getUserFromContext(r.Context())
+			if !ok || user == nil {
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+			if user.Role != role {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects API keys, for routes that no scope covers, such as
+// account settings and managing API keys themselves
+func (h *AuthHandler) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := getAPIKeyFromContext(r.Context()); ok {
+			http.Error(w, "This endpoint can't be used with an API key", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StreamTicketMiddleware authenticates a stream opened with ?ticket=, for
+// clients that can't set headers (e.g. EventSource). Tickets come from
+// IssueStreamTicket and work once, so the URL holds no reusable credential.
+func (h *AuthHandler) StreamTicketMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, sessionID, err := h.authService.RedeemStreamTicket(r.Context(), r.URL.Query().Get("ticket"))
+		if err != nil {
+			http.Error(w, "Invalid or expired stream ticket", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := setUserInContext(r.Context(), user)
+		ctx = setSessionInContext(ctx, sessionID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientInfo records where a session was started from. RemoteAddr already
+// reflects X-Forwarded-For when middleware.RealIP is in use.
+func clientInfo(r *http.Request) services.ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return services.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: ip,
+	}
+}
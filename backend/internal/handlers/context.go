@@ -8,7 +8,11 @@ import (
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey    contextKey = "user"
+	sessionContextKey contextKey = "session"
+	apiKeyContextKey  contextKey = "apiKey"
+)
 
 func setUserInContext(ctx context.Context, user *models.User) context.Context {
 	return context.WithValue(ctx, userContextKey, user)
@@ -17,4 +21,23 @@ func setUserInContext(ctx context.Context, user *models.User) context.Context {
 func getUserFromContext(ctx context.Context) (*models.User, bool) {
 	user, ok := ctx.Value(userContextKey).(*models.User)
 	return user, ok
+}
+
+func setSessionInContext(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionContextKey, sessionID)
+}
+
+func getSessionFromContext(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(sessionContextKey).(string)
+	return sessionID, ok
+}
+
+func setAPIKeyInContext(ctx context.Context, key *models.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey, key)
+}
+
+// getAPIKeyFromContext returns the API key the request authenticated with, if any
+func getAPIKeyFromContext(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(*models.APIKey)
+	return key, ok
 }
\ No newline at end of file
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"shares-alert-backend/internal/services"
+)
+
+// EmailActionHandler serves the signed links in notification emails. These
+// routes are unauthenticated; the HMAC-signed token is the credential.
+type EmailActionHandler struct {
+	emailActionService *services.EmailActionService
+}
+
+func NewEmailActionHandler(emailActionService *services.EmailActionService) *EmailActionHandler {
+	return &EmailActionHandler{
+		emailActionService: emailActionService,
+	}
+}
+
+// Confirm shows what the link will do and asks the user to confirm it
+func (h *EmailActionHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	page, err := h.emailActionService.ConfirmPage(r.Context(), r.URL.Query().Get("token"))
+	h.writePage(w, page, err)
+}
+
+// Perform carries out the link's action. It also serves RFC 8058 one-click
+// unsubscribe requests, which POST to the List-Unsubscribe URL.
+func (h *EmailActionHandler) Perform(w http.ResponseWriter, r *http.Request) {
+	page, err := h.emailActionService.Perform(r.Context(), r.URL.Query().Get("token"))
+	h.writePage(w, page, err)
+}
+
+func (h *EmailActionHandler) writePage(w http.ResponseWriter, page string, err error) {
+	status := http.StatusOK
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrExpiredActionLink):
+			status = http.StatusGone
+		case errors.Is(err, services.ErrInvalidActionLink):
+			status = http.StatusBadRequest
+		default:
+			log.Printf("Failed to perform email action: %v", err)
+			status = http.StatusInternalServerError
+		}
+		if page, err = h.emailActionService.ErrorPage(err); err != nil {
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	w.Write([]byte(page))
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"shares-alert-backend/internal/services"
+)
+
+type EmailHandler struct {
+	emailService *services.EmailService
+}
+
+func NewEmailHandler(emailService *services.EmailService) *EmailHandler {
+	return &EmailHandler{
+		emailService: emailService,
+	}
+}
+
+// GetLocales lists the locales emails can be rendered in
+func (h *EmailHandler) GetLocales(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, map[string][]string{"locales": h.emailService.Locales()})
+}
+
+// PreviewTemplate renders a template with sample data, e.g. /alert?locale=fr&format=text
+func (h *EmailHandler) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "template")
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = services.DefaultLocale
+	}
+
+	email, err := h.emailService.Preview(name, locale)
+	if err != nil {
+		http.Error(w, "Failed to render template: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("X-Email-Subject", email.Subject)
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(email.Text))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(email.HTML))
+}
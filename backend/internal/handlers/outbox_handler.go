@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"shares-alert-backend/internal/services"
+)
+
+type OutboxHandler struct {
+	outboxService *services.OutboxService
+}
+
+func NewOutboxHandler(outboxService *services.OutboxService) *OutboxHandler {
+	return &OutboxHandler{
+		outboxService: outboxService,
+	}
+}
+
+// ListMessages returns outbox messages, e.g. ?status=dead to inspect failed deliveries
+func (h *OutboxHandler) ListMessages(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	messages, err := h.outboxService.ListMessages(r.Context(), r.URL.Query().Get("status"), limit)
+	if err != nil {
+		http.Error(w, "Failed to fetch outbox: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, messages)
+}
+
+func (h *OutboxHandler) GetMessage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "Message ID is required", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := h.outboxService.GetMessage(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+
+	render.JSON(w, r, msg)
+}
+
+// ReplayMessage re-queues a dead-lettered message for delivery
+func (h *OutboxHandler) ReplayMessage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		http.Error(w, "Message ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.outboxService.Replay(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	render.JSON(w, r, map[string]string{
+		"status":  "ok",
+		"message": "Message queued for redelivery",
+	})
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/services"
+)
+
+// maxImportSize caps an uploaded broker statement
+const maxImportSize = 2 << 20
+
+type PortfolioHandler struct {
+	portfolioService *services.PortfolioService
+}
+
+func NewPortfolioHandler(portfolioService *services.PortfolioService) *PortfolioHandler {
+	return &PortfolioHandler{
+		portfolioService: portfolioService,
+	}
+}
+
+func (h *PortfolioHandler) ListPortfolios(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	portfolios, err := h.portfolioService.List(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch portfolios", http.StatusInternalServerError)
+		return
+	}
+	if portfolios == nil {
+		portfolios = []*models.Portfolio{}
+	}
+
+	render.JSON(w, r, portfolios)
+}
+
+func (h *PortfolioHandler) CreatePortfolio(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreatePortfolioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := h.portfolioService.Create(r.Context(), user.ID, &req)
+	if err != nil {
+		writePortfolioError(w, err, "Failed to create portfolio")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, portfolio)
+}
+
+// GetPortfolio returns the portfolio with its holdings valued at live prices
+func (h *PortfolioHandler) GetPortfolio(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	valuation, err := h.portfolioService.Value(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writePortfolioError(w, err, "Failed to value portfolio")
+		return
+	}
+
+	render.JSON(w, r, valuation)
+}
+
+// UpdatePortfolio renames the portfolio and/or changes its cost-basis method
+func (h *PortfolioHandler) UpdatePortfolio(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreatePortfolioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := h.portfolioService.Update(r.Context(), user.ID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writePortfolioError(w, err, "Failed to update portfolio")
+		return
+	}
+
+	render.JSON(w, r, portfolio)
+}
+
+// GetPnL reports the portfolio's profit and loss, optionally under another
+// cost-basis method (?method=fifo)
+func (h *PortfolioHandler) GetPnL(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	pnl, err := h.portfolioService.PnL(r.Context(), user.ID, chi.URLParam(r, "id"), r.URL.Query().Get("method"))
+	if err != nil {
+		writePortfolioError(w, err, "Failed to calculate profit and loss")
+		return
+	}
+
+	render.JSON(w, r, pnl)
+}
+
+// ListSnapshots returns the portfolio's daily values since ?from=YYYY-MM-DD
+func (h *PortfolioHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	snapshots, err := h.portfolioService.Snapshots(r.Context(), user.ID, chi.URLParam(r, "id"), r.URL.Query().Get("from"))
+	if err != nil {
+		writePortfolioError(w, err, "Failed to fetch snapshots")
+		return
+	}
+
+	render.JSON(w, r, snapshots)
+}
+
+func (h *PortfolioHandler) DeletePortfolio(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.portfolioService.Delete(r.Context(), user.ID, chi.URLParam(r, "id")); err != nil {
+		writePortfolioError(w, err, "Failed to delete portfolio")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PortfolioHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	transactions, err := h.portfolioService.ListTransactions(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writePortfolioError(w, err, "Failed to fetch transactions")
+		return
+	}
+	if transactions == nil {
+		transactions = []*models.Transaction{}
+	}
+
+	render.JSON(w, r, transactions)
+}
+
+func (h *PortfolioHandler) AddTransaction(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := h.portfolioService.AddTransaction(r.Context(), user.ID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writePortfolioError(w, err, "Failed to record transaction")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, transaction)
+}
+
+func (h *PortfolioHandler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	err := h.portfolioService.DeleteTransaction(r.Context(), user.ID, chi.URLParam(r, "id"), chi.URLParam(r, "transactionId"))
+	if err != nil {
+		writePortfolioError(w, err, "Failed to delete transaction")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListImportFormats returns the built-in broker statement mappings
+func (h *PortfolioHandler) ListImportFormats(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, services.ImportFormats())
+}
+
+// ImportTransactions reads a broker statement CSV into the ledger. The
+// multipart form carries the CSV as "file", plus "format" (a built-in
+// mapping) or "mapping" (custom mapping JSON), and "dryRun".
+func (h *PortfolioHandler) ImportTransactions(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		http.Error(w, "Expected a multipart form of at most 2 MB", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing statement file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	var mapping *models.ImportMapping
+	if raw := r.FormValue("mapping"); raw != "" {
+		mapping = &models.ImportMapping{}
+		if err := json.Unmarshal([]byte(raw), mapping); err != nil {
+			http.Error(w, "Invalid mapping", http.StatusBadRequest)
+			return
+		}
+	}
+	dryRun := false
+	if raw := r.FormValue("dryRun"); raw != "" {
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			http.Error(w, "dryRun must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.portfolioService.ImportTransactions(r.Context(), user.ID, chi.URLParam(r, "id"), file, r.FormValue("format"), mapping, dryRun)
+	if err != nil {
+		writePortfolioError(w, err, "Failed to import statement")
+		return
+	}
+
+	switch {
+	case result.Committed:
+		w.WriteHeader(http.StatusCreated)
+	case !dryRun && result.Errors > 0:
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	render.JSON(w, r, result)
+}
+
+// writePortfolioError maps portfolio service errors to status codes, hiding
+// anything unexpected behind message
+func writePortfolioError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrPortfolioNotFound), errors.Is(err, services.ErrTransactionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidPortfolio), errors.Is(err, services.ErrInvalidTransaction),
+		errors.Is(err, services.ErrInvalidImport):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrInsufficientShares):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
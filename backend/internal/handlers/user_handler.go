@@ -1,20 +1,24 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/render"
 
 	"shares-alert-backend/internal/models"
 	"shares-alert-backend/internal/repository"
+	"shares-alert-backend/internal/services"
 )
 
 type UserHandler struct {
-	userRepo *repository.UserRepository
+	userRepo repository.UserStore
 }
 
-func NewUserHandler(userRepo *repository.UserRepository) *UserHandler {
+func NewUserHandler(userRepo repository.UserStore) *UserHandler {
 	return &UserHandler{
 		userRepo: userRepo,
 	}
@@ -27,14 +31,16 @@ func (h *UserHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prefs, err := h.userRepo.GetPreferences(user.ID)
+	prefs, err := h.userRepo.GetPreferences(r.Context(), user.ID)
 	if err != nil {
 		// Return default preferences if none exist
 		defaultPrefs := &models.UserPreferences{
 			UserID:                user.ID,
 			EmailNotifications:    true,
 			PushNotifications:     true,
-			NotificationFrequency: "immediate",
+			NotificationFrequency: models.NotificationFrequencyImmediate,
+			QuietHoursMode:        models.QuietHoursModeHold,
+			Locale:                services.DefaultLocale,
 		}
 		render.JSON(w, r, defaultPrefs)
 		return
@@ -50,7 +56,17 @@ func (h *UserHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Decode onto the stored preferences, so fields the client leaves out
+	// keep their values rather than resetting
 	var req models.UserPreferences
+	stored, err := h.userRepo.GetPreferences(r.Context(), user.ID)
+	switch {
+	case err == nil:
+		req = *stored
+	case !errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Failed to fetch preferences", http.StatusInternalServerError)
+		return
+	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
@@ -59,18 +75,25 @@ func (h *UserHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request)
 	// Ensure the user ID matches
 	req.UserID = user.ID
 
+	if err := services.ValidatePreferences(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Try to update existing preferences
-	if err := h.userRepo.UpdatePreferences(&req); err != nil {
+	if err := h.userRepo.UpdatePreferences(r.Context(), &req); err != nil {
 		// If update fails, try to create new preferences
 		req.ID = user.ID + "-prefs" // Simple ID generation
-		if err := h.userRepo.CreatePreferences(&req); err != nil {
+		req.CreatedAt = time.Now()
+		req.UpdatedAt = req.CreatedAt
+		if err := h.userRepo.CreatePreferences(r.Context(), &req); err != nil {
 			http.Error(w, "Failed to save preferences: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 
 	// Fetch updated preferences
-	prefs, err := h.userRepo.GetPreferences(user.ID)
+	prefs, err := h.userRepo.GetPreferences(r.Context(), user.ID)
 	if err != nil {
 		http.Error(w, "Failed to fetch updated preferences", http.StatusInternalServerError)
 		return
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/services"
+)
+
+type WatchlistHandler struct {
+	watchlistService *services.WatchlistService
+}
+
+func NewWatchlistHandler(watchlistService *services.WatchlistService) *WatchlistHandler {
+	return &WatchlistHandler{
+		watchlistService: watchlistService,
+	}
+}
+
+func (h *WatchlistHandler) ListWatchlists(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	watchlists, err := h.watchlistService.List(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch watchlists", http.StatusInternalServerError)
+		return
+	}
+	if watchlists == nil {
+		watchlists = []*models.Watchlist{}
+	}
+
+	render.JSON(w, r, watchlists)
+}
+
+func (h *WatchlistHandler) CreateWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateWatchlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	watchlist, err := h.watchlistService.Create(r.Context(), user.ID, &req)
+	if err != nil {
+		writeWatchlistError(w, err, "Failed to create watchlist")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, watchlist)
+}
+
+// ReorderWatchlists sets the order of the user's watchlists
+func (h *WatchlistHandler) ReorderWatchlists(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	watchlists, err := h.watchlistService.Reorder(r.Context(), user.ID, req.Order)
+	if err != nil {
+		writeWatchlistError(w, err, "Failed to reorder watchlists")
+		return
+	}
+
+	render.JSON(w, r, watchlists)
+}
+
+func (h *WatchlistHandler) GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	watchlist, err := h.watchlistService.Get(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeWatchlistError(w, err, "Failed to fetch watchlist")
+		return
+	}
+
+	render.JSON(w, r, watchlist)
+}
+
+func (h *WatchlistHandler) UpdateWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateWatchlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	watchlist, err := h.watchlistService.Rename(r.Context(), user.ID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeWatchlistError(w, err, "Failed to update watchlist")
+		return
+	}
+
+	render.JSON(w, r, watchlist)
+}
+
+func (h *WatchlistHandler) DeleteWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.watchlistService.Delete(r.Context(), user.ID, chi.URLParam(r, "id")); err != nil {
+		writeWatchlistError(w, err, "Failed to delete watchlist")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetQuotes returns live prices for the watchlist's symbols
+func (h *WatchlistHandler) GetQuotes(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	quotes, err := h.watchlistService.Quotes(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeWatchlistError(w, err, "Failed to fetch quotes")
+		return
+	}
+
+	render.JSON(w, r, quotes)
+}
+
+func (h *WatchlistHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.AddWatchlistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.watchlistService.AddItem(r.Context(), user.ID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeWatchlistError(w, err, "Failed to add symbol")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, item)
+}
+
+// ReorderItems sets the order of the watchlist's symbols
+func (h *WatchlistHandler) ReorderItems(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	watchlist, err := h.watchlistService.ReorderItems(r.Context(), user.ID, chi.URLParam(r, "id"), req.Order)
+	if err != nil {
+		writeWatchlistError(w, err, "Failed to reorder watchlist")
+		return
+	}
+
+	render.JSON(w, r, watchlist)
+}
+
+// UpdateItem replaces the notes on a symbol
+func (h *WatchlistHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateWatchlistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.watchlistService.UpdateItem(r.Context(), user.ID, chi.URLParam(r, "id"), chi.URLParam(r, "symbol"), &req)
+	if err != nil {
+		writeWatchlistError(w, err, "Failed to update symbol")
+		return
+	}
+
+	render.JSON(w, r, item)
+}
+
+func (h *WatchlistHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.watchlistService.RemoveItem(r.Context(), user.ID, chi.URLParam(r, "id"), chi.URLParam(r, "symbol")); err != nil {
+		writeWatchlistError(w, err, "Failed to remove symbol")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateAlerts creates the standard alert set for every symbol on the watchlist
+func (h *WatchlistHandler) CreateAlerts(w http.ResponseWriter, r *http.Request) {
+	user, ok := getUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	// An empty body asks for the defaults
+	var req models.WatchlistAlertsRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.watchlistService.CreateAlerts(r.Context(), user.ID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeWatchlistError(w, err, "Failed to create alerts")
+		return
+	}
+
+	if len(result.Created) > 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	render.JSON(w, r, result)
+}
+
+// writeWatchlistError maps watchlist service errors to status codes, hiding
+// anything unexpected behind message
+func writeWatchlistError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrWatchlistNotFound), errors.Is(err, services.ErrWatchlistItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidWatchlist):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrDuplicateWatchlistItem):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
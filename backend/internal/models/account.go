@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// AccountExport is everything stored about a user, as returned by the data
+// export endpoint
+type AccountExport struct {
+	ExportedAt      time.Time         `json:"exportedAt"`
+	User            *User             `json:"user"`
+	Preferences     *UserPreferences  `json:"preferences,omitempty"`
+	Identities      []*Identity       `json:"identities"`
+	APIKeys         []*APIKey         `json:"apiKeys"`
+	Alerts          []*Alert          `json:"alerts"`
+	AlertEvents     []*DigestEntry    `json:"alertEvents"` // triggers recorded for digests
+	Notifications   []*Notification   `json:"notifications"`
+	Emails          []*OutboxMessage  `json:"emails"`
+	LifecycleEmails []*LifecycleEmail `json:"lifecycleEmails"`
+
+	Portfolios            []*Portfolio   `json:"portfolios"`
+	PortfolioTransactions []*Transaction `json:"portfolioTransactions"`
+
+	Watchlists []*Watchlist `json:"watchlists"` // with their items
+}
+
+// AccountDeletionResponse reports when a requested deletion takes effect
+type AccountDeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletionScheduledAt"`
+}
@@ -3,30 +3,85 @@ package models
 import "time"
 
 type Alert struct {
-	ID             string    `json:"id" db:"id"`
-	UserID         string    `json:"userId" db:"user_id"`
-	StockSymbol    string    `json:"stockSymbol" db:"stock_symbol"`
-	StockName      string    `json:"stockName" db:"stock_name"`
-	AlertType      string    `json:"alertType" db:"alert_type"`
-	ThresholdPrice *float64  `json:"thresholdPrice,omitempty" db:"threshold_price"`
-	CurrentPrice   *float64  `json:"currentPrice,omitempty" db:"current_price"`
-	Status         string    `json:"status" db:"status"`
-	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
-	TriggeredAt    *time.Time `json:"triggeredAt,omitempty" db:"triggered_at"`
+	ID               string     `json:"id" db:"id"`
+	UserID           string     `json:"userId" db:"user_id"`
+	Scope            string     `json:"scope" db:"scope"`                        // stock or portfolio
+	PortfolioID      *string    `json:"portfolioId,omitempty" db:"portfolio_id"` // set on portfolio alerts
+	StockSymbol      string     `json:"stockSymbol" db:"stock_symbol"`
+	StockName        string     `json:"stockName" db:"stock_name"` // the portfolio's name on portfolio alerts
+	AlertType        string     `json:"alertType" db:"alert_type"`
+	ThresholdPrice   *float64   `json:"thresholdPrice,omitempty" db:"threshold_price"`     // portfolio value for portfolio_value
+	ThresholdPercent *float64   `json:"thresholdPercent,omitempty" db:"threshold_percent"` // drawdown or weight limit
+	Direction        string     `json:"direction,omitempty" db:"direction"`                // above or below, for portfolio_value
+	CurrentPrice     *float64   `json:"currentPrice,omitempty" db:"current_price"`         // portfolio value on portfolio alerts
+	PeakValue        *float64   `json:"peakValue,omitempty" db:"peak_value"`               // high-water mark for portfolio_drawdown
+	NetInvested      *float64   `json:"-" db:"net_invested"`                               // ledger cash in when the peak was last adjusted
+	Status           string     `json:"status" db:"status"`
+	Urgent           bool       `json:"urgent" db:"urgent"` // bypasses the user's quiet hours
+	CreatedAt        time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updatedAt" db:"updated_at"`
+	TriggeredAt      *time.Time `json:"triggeredAt,omitempty" db:"triggered_at"`
+	SnoozedUntil     *time.Time `json:"snoozedUntil,omitempty" db:"snoozed_until"` // not checked until this time
 }
 
 type CreateAlertRequest struct {
-	StockSymbol    string   `json:"stockSymbol"`
-	StockName      string   `json:"stockName"`
-	AlertType      string   `json:"alertType"`
-	ThresholdPrice *float64 `json:"thresholdPrice,omitempty"`
+	StockSymbol      string   `json:"stockSymbol"`
+	StockName        string   `json:"stockName"`
+	AlertType        string   `json:"alertType"`
+	ThresholdPrice   *float64 `json:"thresholdPrice,omitempty"`
+	Urgent           bool     `json:"urgent,omitempty"`
+	PortfolioID      string   `json:"portfolioId,omitempty"`
+	ThresholdPercent *float64 `json:"thresholdPercent,omitempty"`
+	Direction        string   `json:"direction,omitempty"`
+}
+
+// AlertFilter narrows a listing of a user's alerts. Empty fields match
+// everything, and a list matches any of its values. Ranges include their
+// From time and exclude their To time.
+type AlertFilter struct {
+	Statuses      []string
+	StockSymbols  []string
+	AlertTypes    []string
+	Scopes        []string
+	PortfolioIDs  []string
+	Search        string // case-insensitive substring of the stock name
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	TriggeredFrom *time.Time
+	TriggeredTo   *time.Time
+}
+
+// AlertSort is one key of an alert listing's order
+type AlertSort struct {
+	Field      string // one of the AlertSort fields below
+	Descending bool
+}
+
+// Fields alerts can be sorted by. Alerts that have never triggered sort
+// after the rest by triggeredAt, in either direction.
+const (
+	AlertSortCreatedAt   = "createdAt"
+	AlertSortUpdatedAt   = "updatedAt"
+	AlertSortTriggeredAt = "triggeredAt"
+	AlertSortStockSymbol = "stockSymbol"
+	AlertSortStockName   = "stockName"
+	AlertSortStatus      = "status"
+	AlertSortAlertType   = "alertType"
+)
+
+// AlertPage is one page of a user's alerts
+type AlertPage struct {
+	Alerts     []*Alert `json:"alerts"`
+	NextCursor string   `json:"nextCursor,omitempty"`
 }
 
 type UpdateAlertRequest struct {
-	AlertType      *string  `json:"alertType,omitempty"`
-	ThresholdPrice *float64 `json:"thresholdPrice,omitempty"`
-	Status         *string  `json:"status,omitempty"`
+	AlertType        *string  `json:"alertType,omitempty"`
+	ThresholdPrice   *float64 `json:"thresholdPrice,omitempty"`
+	ThresholdPercent *float64 `json:"thresholdPercent,omitempty"`
+	Direction        *string  `json:"direction,omitempty"`
+	Status           *string  `json:"status,omitempty"`
+	Urgent           *bool    `json:"urgent,omitempty"`
 }
 
 // Alert types
@@ -34,6 +89,23 @@ const (
 	AlertTypePriceThreshold       = "price_threshold"
 	AlertTypeIPO                  = "ipo_alert"
 	AlertTypeDividendAnnouncement = "dividend_announcement"
+
+	// Portfolio-scope types, evaluated against a whole portfolio
+	AlertTypePortfolioValue      = "portfolio_value"      // value crosses thresholdPrice
+	AlertTypePortfolioDrawdown   = "portfolio_drawdown"   // value falls thresholdPercent below its peak
+	AlertTypePortfolioAllocation = "portfolio_allocation" // a holding exceeds thresholdPercent of the value
+)
+
+// Alert scopes
+const (
+	AlertScopeStock     = "stock"
+	AlertScopePortfolio = "portfolio"
+)
+
+// Directions for portfolio_value alerts
+const (
+	AlertDirectionAbove = "above"
+	AlertDirectionBelow = "below"
 )
 
 // Alert statuses
@@ -42,4 +114,4 @@ const (
 	AlertStatusTriggered = "triggered"
 	AlertStatusPaused    = "paused"
 	AlertStatusDeleted   = "deleted"
-)
\ No newline at end of file
+)
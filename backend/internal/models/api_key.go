@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// APIKey is a user-managed credential for scripts. The key itself is shown
+// once on creation; only its hash is stored.
+type APIKey struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"userId" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+}
+
+// HasScope reports whether the key grants scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expiresInDays,omitempty"`
+}
+
+// CreateAPIKeyResponse is returned once, when the key is created
+type CreateAPIKeyResponse struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// API key scopes
+const (
+	ScopeReadStocks  = "read:stocks"
+	ScopeReadAlerts  = "read:alerts"
+	ScopeWriteAlerts = "write:alerts"
+)
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// DigestEntry is a triggered alert held back for a user's daily or weekly digest
+type DigestEntry struct {
+	ID             string     `json:"id" db:"id"`
+	UserID         string     `json:"userId" db:"user_id"`
+	AlertID        string     `json:"alertId" db:"alert_id"`
+	StockSymbol    string     `json:"stockSymbol" db:"stock_symbol"`
+	StockName      string     `json:"stockName" db:"stock_name"`
+	AlertType      string     `json:"alertType" db:"alert_type"`
+	ThresholdPrice *float64   `json:"thresholdPrice,omitempty" db:"threshold_price"`
+	TriggerPrice   float64    `json:"triggerPrice" db:"trigger_price"`
+	TriggeredAt    time.Time  `json:"triggeredAt" db:"triggered_at"`
+	SentAt         *time.Time `json:"sentAt,omitempty" db:"sent_at"`
+}
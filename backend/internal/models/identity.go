@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Identity is one way of signing in to an account. A user can have a Google
+// identity, an email identity, or both.
+type Identity struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    string    `json:"userId" db:"user_id"`
+	Provider  string    `json:"provider" db:"provider"`
+	Subject   string    `json:"-" db:"subject"` // Google account ID, or the lower-cased email address
+	Email     string    `json:"email" db:"email"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Identity providers
+const (
+	IdentityProviderGoogle = "google"
+	IdentityProviderEmail  = "email"
+)
+
+// MagicLink is a pending passwordless sign-in. Only the token's hash is stored.
+type MagicLink struct {
+	ID        string     `json:"id" db:"id"`
+	Email     string     `json:"email" db:"email"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expires_at"`
+	UsedAt    *time.Time `json:"usedAt,omitempty" db:"used_at"`
+}
+
+type MagicLinkRequest struct {
+	Email  string `json:"email"`
+	Locale string `json:"locale,omitempty"`
+}
+
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// LifecycleEmail records that a one-off lifecycle email was sent to a user,
+// so it is never sent to them again
+type LifecycleEmail struct {
+	ID     string    `json:"id" db:"id"`
+	UserID string    `json:"userId" db:"user_id"`
+	Kind   string    `json:"kind" db:"kind"`
+	SentAt time.Time `json:"sentAt" db:"sent_at"`
+}
+
+// Lifecycle email kinds
+const (
+	LifecycleEmailWelcome      = "welcome"
+	LifecycleEmailNudge        = "no_alerts_nudge" // signed up but never created an alert
+	LifecycleEmailReengagement = "reengagement"    // hasn't logged in for a long time
+)
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Notification is an entry in a user's in-app notification inbox
+type Notification struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    string     `json:"userId" db:"user_id"`
+	Kind      string     `json:"kind" db:"kind"`
+	Title     string     `json:"title" db:"title"`
+	Message   string     `json:"message" db:"message"`
+	AlertID   *string    `json:"alertId,omitempty" db:"alert_id"`
+	ReadAt    *time.Time `json:"readAt,omitempty" db:"read_at"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// NotificationPage is one page of a user's inbox
+type NotificationPage struct {
+	Notifications []*Notification `json:"notifications"`
+	UnreadCount   int             `json:"unreadCount"`
+	NextCursor    string          `json:"nextCursor,omitempty"`
+}
+
+// Notification kinds
+const (
+	NotificationKindAlert = "alert"
+)
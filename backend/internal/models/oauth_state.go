@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// OAuthState is a pending OAuth login attempt. The state and PKCE code
+// verifier are generated server-side and can be consumed exactly once.
+type OAuthState struct {
+	State        string    `json:"-" db:"state"`
+	CodeVerifier string    `json:"-" db:"code_verifier"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	ExpiresAt    time.Time `json:"expiresAt" db:"expires_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// OutboxMessage is a notification waiting to be delivered by the outbox worker
+type OutboxMessage struct {
+	ID             string     `json:"id" db:"id"`
+	UserID         string     `json:"userId" db:"user_id"`
+	AlertID        *string    `json:"alertId,omitempty" db:"alert_id"`
+	Kind           string     `json:"kind" db:"kind"`
+	Recipient      string     `json:"recipient" db:"recipient"`
+	Subject        string     `json:"subject" db:"subject"`
+	Body           string     `json:"-" db:"body"`      // HTML alternative
+	TextBody       string     `json:"-" db:"text_body"` // plain-text alternative
+	UnsubscribeURL string     `json:"-" db:"unsubscribe_url"`
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastError      *string    `json:"lastError,omitempty" db:"last_error"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+	SentAt         *time.Time `json:"sentAt,omitempty" db:"sent_at"`
+}
+
+// Outbox message kinds
+const (
+	OutboxKindAlert     = "alert"
+	OutboxKindDigest    = "digest"
+	OutboxKindWelcome   = "welcome"
+	OutboxKindLifecycle = "lifecycle"
+	OutboxKindAccount   = "account"
+)
+
+// Outbox message statuses
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSending = "sending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"
+)
@@ -0,0 +1,202 @@
+package models
+
+import "time"
+
+// Portfolio is a named set of positions tracked through a transaction ledger
+type Portfolio struct {
+	ID              string    `json:"id" db:"id"`
+	UserID          string    `json:"userId" db:"user_id"`
+	Name            string    `json:"name" db:"name"`
+	CostBasisMethod string    `json:"costBasisMethod" db:"cost_basis_method"`
+	CreatedAt       time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt       time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Cost-basis methods, deciding which shares a sell disposes of
+const (
+	CostBasisAverage     = "average"      // every share costs the running average
+	CostBasisFIFO        = "fifo"         // oldest lots first
+	CostBasisSpecificLot = "specific_lot" // the lot named by the sell, else oldest first
+)
+
+// Transaction is one entry in a portfolio's ledger. Buys and sells move
+// Quantity shares at Price each; a dividend pays Price per share on Quantity
+// shares. Fees holds the commission on a trade, or the amount of a
+// standalone fee, or the tax withheld from a dividend.
+type Transaction struct {
+	ID          string    `json:"id" db:"id"`
+	PortfolioID string    `json:"portfolioId" db:"portfolio_id"`
+	Type        string    `json:"type" db:"type"`
+	StockSymbol string    `json:"stockSymbol,omitempty" db:"stock_symbol"`
+	Quantity    float64   `json:"quantity" db:"quantity"`
+	Price       float64   `json:"price" db:"price"`
+	Fees        float64   `json:"fees" db:"fees"`
+	TradeDate   time.Time `json:"tradeDate" db:"trade_date"`
+	LotID       string    `json:"lotId,omitempty" db:"lot_id"` // buy a sell draws from under specific_lot
+	Notes       string    `json:"notes,omitempty" db:"notes"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Transaction types
+const (
+	TransactionTypeBuy      = "buy"
+	TransactionTypeSell     = "sell"
+	TransactionTypeDividend = "dividend"
+	TransactionTypeFee      = "fee"
+)
+
+type CreatePortfolioRequest struct {
+	Name            string `json:"name"`
+	CostBasisMethod string `json:"costBasisMethod,omitempty"`
+}
+
+type CreateTransactionRequest struct {
+	Type        string    `json:"type"`
+	StockSymbol string    `json:"stockSymbol,omitempty"`
+	Quantity    float64   `json:"quantity"`
+	Price       float64   `json:"price"`
+	Fees        float64   `json:"fees,omitempty"`
+	TradeDate   time.Time `json:"tradeDate"`
+	LotID       string    `json:"lotId,omitempty"`
+	Notes       string    `json:"notes,omitempty"`
+}
+
+// Holding is a position derived from the ledger, valued at the live price
+type Holding struct {
+	StockSymbol           string  `json:"stockSymbol"`
+	StockName             string  `json:"stockName"`
+	Quantity              float64 `json:"quantity"`
+	AverageCost           float64 `json:"averageCost"` // per share, including buy commissions
+	CostBasis             float64 `json:"costBasis"`
+	CurrentPrice          float64 `json:"currentPrice"`
+	MarketValue           float64 `json:"marketValue"`
+	UnrealizedGain        float64 `json:"unrealizedGain"`
+	UnrealizedGainPercent float64 `json:"unrealizedGainPercent"`
+	DayChange             float64 `json:"dayChange"` // change in market value since the previous close
+	Weight                float64 `json:"weight"`    // percent of the portfolio's market value
+	Priced                bool    `json:"priced"`    // false if the symbol isn't on the live board
+}
+
+// PortfolioValuation is a portfolio with its holdings at live prices
+type PortfolioValuation struct {
+	*Portfolio
+	Holdings       []*Holding `json:"holdings"`
+	CostBasis      float64    `json:"costBasis"`
+	MarketValue    float64    `json:"marketValue"`
+	UnrealizedGain float64    `json:"unrealizedGain"`
+	DayChange      float64    `json:"dayChange"`
+	NetInvested    float64    `json:"netInvested"` // paid for buys less received from sells
+	ValuedAt       time.Time  `json:"valuedAt"`
+}
+
+// Lot is shares bought in one transaction that are still held
+type Lot struct {
+	ID           string    `json:"id"` // the buy transaction's ID
+	TradeDate    time.Time `json:"tradeDate"`
+	Quantity     float64   `json:"quantity"`
+	CostPerShare float64   `json:"costPerShare"` // including the buy commission
+}
+
+// HoldingPnL is one symbol's profit and loss, including positions that have
+// been sold out
+type HoldingPnL struct {
+	StockSymbol             string   `json:"stockSymbol"`
+	Quantity                float64  `json:"quantity"`
+	CostBasis               float64  `json:"costBasis"`
+	MarketValue             float64  `json:"marketValue"`
+	UnrealizedGain          float64  `json:"unrealizedGain"`
+	RealizedGain            float64  `json:"realizedGain"`   // net of sell commissions
+	DividendIncome          float64  `json:"dividendIncome"` // net of tax withheld
+	Fees                    float64  `json:"fees"`           // commissions and withholding
+	EstimatedAnnualDividend *float64 `json:"estimatedAnnualDividend,omitempty"`
+	Lots                    []*Lot   `json:"lots"`
+	Priced                  bool     `json:"priced"`
+}
+
+// PortfolioPnL is a profit and loss report under one cost-basis method
+type PortfolioPnL struct {
+	PortfolioID     string        `json:"portfolioId"`
+	CostBasisMethod string        `json:"costBasisMethod"`
+	CostBasis       float64       `json:"costBasis"`
+	MarketValue     float64       `json:"marketValue"`
+	UnrealizedGain  float64       `json:"unrealizedGain"`
+	RealizedGain    float64       `json:"realizedGain"`
+	DividendIncome  float64       `json:"dividendIncome"`
+	Fees            float64       `json:"fees"`      // every fee paid, including those already in the gains
+	OtherFees       float64       `json:"otherFees"` // standalone fees, not counted in any gain
+	TotalReturn     float64       `json:"totalReturn"`
+	Holdings        []*HoldingPnL `json:"holdings"`
+	ValuedAt        time.Time     `json:"valuedAt"`
+
+	// EstimatedAnnualDividends projects a year's income from each open
+	// holding's latest DPS
+	EstimatedAnnualDividends float64 `json:"estimatedAnnualDividends"`
+}
+
+// PortfolioSnapshot is a portfolio's value at the end of one trading day
+type PortfolioSnapshot struct {
+	PortfolioID  string  `json:"portfolioId" db:"portfolio_id"`
+	SnapshotDate string  `json:"date" db:"snapshot_date"` // YYYY-MM-DD in the snapshot timezone
+	MarketValue  float64 `json:"marketValue" db:"market_value"`
+	CostBasis    float64 `json:"costBasis" db:"cost_basis"`
+	NetFlow      float64 `json:"netFlow" db:"net_flow"` // cash put in (buys) less taken out (sells, dividends) that day
+
+	// ReturnIndex is the time-weighted return rebased to 100 at the start of
+	// the requested range, so deposits and withdrawals don't count as growth
+	ReturnIndex float64 `json:"returnIndex" db:"-"`
+	MarketIndex float64 `json:"marketIndex,omitempty" db:"-"` // the market benchmark, rebased the same way
+}
+
+// MarketSnapshot is one day of the equal-weighted market benchmark,
+// chain-linked from the board's daily percentage changes
+type MarketSnapshot struct {
+	SnapshotDate string  `json:"date" db:"snapshot_date"`
+	IndexValue   float64 `json:"indexValue" db:"index_value"`
+}
+
+// ImportMapping says how to read a broker statement CSV. Column fields name
+// header cells, matched case-insensitively; leave one empty if the statement
+// doesn't have it.
+type ImportMapping struct {
+	Name        string            `json:"name,omitempty"`
+	Delimiter   string            `json:"delimiter,omitempty"` // defaults to ","
+	SkipRows    int               `json:"skipRows,omitempty"`  // lines before the header row
+	Date        string            `json:"date"`
+	DateFormats []string          `json:"dateFormats"` // Go layouts, tried in order
+	Type        string            `json:"type"`
+	TypeValues  map[string]string `json:"typeValues,omitempty"` // cell value (lowercase) to transaction type
+	Symbol      string            `json:"symbol"`
+	Quantity    string            `json:"quantity"`
+	Price       string            `json:"price,omitempty"`
+	Amount      string            `json:"amount,omitempty"` // gross consideration, used when there's no price
+	Fees        []string          `json:"fees,omitempty"`   // summed, e.g. brokerage, levies and VAT
+	Reference   string            `json:"reference,omitempty"`
+	Notes       string            `json:"notes,omitempty"`
+}
+
+// Import row statuses
+const (
+	ImportRowOK        = "ok"
+	ImportRowDuplicate = "duplicate" // already in the ledger or earlier in the file; skipped
+	ImportRowError     = "error"
+)
+
+// ImportRow is the outcome for one line of an imported statement
+type ImportRow struct {
+	Row         int          `json:"row"` // line number in the file
+	Status      string       `json:"status"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Errors      []string     `json:"errors,omitempty"`
+}
+
+// ImportResult reports a statement import. Nothing is recorded if any row
+// has errors or DryRun is set.
+type ImportResult struct {
+	Format     string       `json:"format"`
+	DryRun     bool         `json:"dryRun"`
+	Committed  bool         `json:"committed"`
+	Imported   int          `json:"imported"`
+	Duplicates int          `json:"duplicates"`
+	Errors     int          `json:"errors"`
+	Rows       []*ImportRow `json:"rows"`
+}
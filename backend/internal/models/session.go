@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Session is one signed-in device. Its refresh tokens rotate on every use;
+// revoking the session logs that device out.
+type Session struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"userId" db:"user_id"`
+	UserAgent  string     `json:"userAgent" db:"user_agent"`
+	IPAddress  string     `json:"ipAddress" db:"ip_address"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	LastSeenAt time.Time  `json:"lastSeenAt" db:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expiresAt" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+}
+
+// RefreshToken is a single-use token that extends a session. Only its hash is stored.
+type RefreshToken struct {
+	ID        string     `json:"id" db:"id"`
+	SessionID string     `json:"sessionId" db:"session_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	ExpiresAt time.Time  `json:"expiresAt" db:"expires_at"`
+	UsedAt    *time.Time `json:"usedAt,omitempty" db:"used_at"`
+}
+
+// StreamTicket opens the notification stream once, for a client that can't
+// send an Authorization header. Only its hash is stored.
+type StreamTicket struct {
+	TicketHash string    `json:"-" db:"ticket_hash"`
+	UserID     string    `json:"userId" db:"user_id"`
+	SessionID  string    `json:"sessionId" db:"session_id"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	ExpiresAt  time.Time `json:"expiresAt" db:"expires_at"`
+}
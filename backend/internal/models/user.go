@@ -5,22 +5,50 @@ import (
 )
 
 type User struct {
-	ID            string    `json:"id" db:"id"`
-	Email         string    `json:"email" db:"email"`
-	Name          string    `json:"name" db:"name"`
-	Picture       string    `json:"picture" db:"picture"`
-	GoogleID      string    `json:"googleId" db:"google_id"`
-	EmailVerified bool      `json:"emailVerified" db:"email_verified"`
-	CreatedAt     time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt     time.Time `json:"updatedAt" db:"updated_at"`
+	ID            string     `json:"id" db:"id"`
+	Email         string     `json:"email" db:"email"`
+	Name          string     `json:"name" db:"name"`
+	Picture       string     `json:"picture" db:"picture"`
+	GoogleID      string     `json:"googleId" db:"google_id"`
+	EmailVerified bool       `json:"emailVerified" db:"email_verified"`
+	CreatedAt     time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updatedAt" db:"updated_at"`
+	LastLoginAt   *time.Time `json:"lastLoginAt,omitempty" db:"last_login_at"`
+	Role          string     `json:"role" db:"role"`
+
+	// DeletionScheduledAt is when a pending account deletion takes effect
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt,omitempty" db:"deletion_scheduled_at"`
 }
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type UserPreferences struct {
 	ID                    string `json:"id" db:"id"`
 	UserID                string `json:"userId" db:"user_id"`
 	EmailNotifications    bool   `json:"emailNotifications" db:"email_notifications"`
 	PushNotifications     bool   `json:"pushNotifications" db:"push_notifications"`
 	NotificationFrequency string `json:"notificationFrequency" db:"notification_frequency"` // immediate, daily, weekly
+	Timezone              string `json:"timezone" db:"timezone"`                 // IANA zone, e.g. Africa/Accra; empty uses the server default
+	QuietHoursStart       string `json:"quietHoursStart" db:"quiet_hours_start"` // HH:MM local time; empty disables quiet hours
+	QuietHoursEnd         string `json:"quietHoursEnd" db:"quiet_hours_end"`     // HH:MM local time
+	QuietHoursMode        string `json:"quietHoursMode" db:"quiet_hours_mode"`   // hold, summary
+	Locale                string `json:"locale" db:"locale"`                     // email language: en, tw, fr
 	CreatedAt             time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt             time.Time `json:"updatedAt" db:"updated_at"`
-}
\ No newline at end of file
+}
+// Notification frequencies
+const (
+	NotificationFrequencyImmediate = "immediate"
+	NotificationFrequencyDaily     = "daily"
+	NotificationFrequencyWeekly    = "weekly"
+)
+
+// Quiet hours modes
+const (
+	QuietHoursModeHold    = "hold"    // deliver each held alert when quiet hours end
+	QuietHoursModeSummary = "summary" // fold held alerts into one summary email
+)
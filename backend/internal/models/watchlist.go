@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Watchlist is a named, ordered list of symbols a user follows
+type Watchlist struct {
+	ID        string           `json:"id" db:"id"`
+	UserID    string           `json:"userId" db:"user_id"`
+	Name      string           `json:"name" db:"name"`
+	Position  int              `json:"position" db:"position"` // order among the user's watchlists
+	Items     []*WatchlistItem `json:"items"`
+	CreatedAt time.Time        `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time        `json:"updatedAt" db:"updated_at"`
+}
+
+// WatchlistItem is one symbol on a watchlist
+type WatchlistItem struct {
+	WatchlistID string    `json:"watchlistId" db:"watchlist_id"`
+	StockSymbol string    `json:"stockSymbol" db:"stock_symbol"`
+	Position    int       `json:"position" db:"position"`
+	Notes       string    `json:"notes,omitempty" db:"notes"`
+	AddedAt     time.Time `json:"addedAt" db:"added_at"`
+}
+
+type CreateWatchlistRequest struct {
+	Name    string   `json:"name"`
+	Symbols []string `json:"symbols,omitempty"` // initial items, in order
+}
+
+type UpdateWatchlistRequest struct {
+	Name string `json:"name"`
+}
+
+type AddWatchlistItemRequest struct {
+	StockSymbol string `json:"stockSymbol"`
+	Notes       string `json:"notes,omitempty"`
+	Position    *int   `json:"position,omitempty"` // zero-based; appended if omitted
+}
+
+type UpdateWatchlistItemRequest struct {
+	Notes string `json:"notes"`
+}
+
+// ReorderRequest lists every watchlist ID, or every symbol on a watchlist,
+// in the new order
+type ReorderRequest struct {
+	Order []string `json:"order"`
+}
+
+// WatchlistQuotes is a watchlist's symbols priced from the live board
+type WatchlistQuotes struct {
+	WatchlistID string          `json:"watchlistId"`
+	Quotes      []EnhancedStock `json:"quotes"`      // in watchlist order
+	Unavailable []string        `json:"unavailable"` // symbols not on the live board
+}
+
+// WatchlistAlertsRequest picks the standard alerts created for every symbol
+// on a watchlist
+type WatchlistAlertsRequest struct {
+	RisePercent *float64 `json:"risePercent,omitempty"` // price_threshold this far above today's price; 0 for none
+	Dividend    *bool    `json:"dividend,omitempty"`    // a dividend_announcement alert
+	Urgent      bool     `json:"urgent,omitempty"`
+}
+
+// WatchlistAlertsResult reports the alerts created for a watchlist and the
+// ones skipped because the user already has them
+type WatchlistAlertsResult struct {
+	Created []*Alert        `json:"created"`
+	Skipped []*SkippedAlert `json:"skipped"`
+}
+
+type SkippedAlert struct {
+	StockSymbol string `json:"stockSymbol"`
+	AlertType   string `json:"alertType"`
+	Reason      string `json:"reason"`
+}
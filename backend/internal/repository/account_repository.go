@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+// AccountRepository removes everything stored about a user when their
+// account is deleted
+type AccountRepository struct {
+	db *database.DB
+}
+
+func NewAccountRepository(db *database.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// purgeStatements delete a user's rows, children first. Most tables have no
+// foreign keys, so nothing can be left to ON DELETE CASCADE.
+var purgeStatements = []string{
+	`DELETE FROM shares_alert_notification_outbox WHERE user_id = $1`,
+	`DELETE FROM shares_alert_notifications WHERE user_id = $1`,
+	`DELETE FROM shares_alert_digest_entries WHERE user_id = $1`,
+	`DELETE FROM shares_alert_lifecycle_emails WHERE user_id = $1`,
+	`DELETE FROM shares_alert_stream_tickets WHERE user_id = $1`,
+	`DELETE FROM shares_alert_refresh_tokens WHERE session_id IN (SELECT id FROM shares_alert_sessions WHERE user_id = $1)`,
+	`DELETE FROM shares_alert_sessions WHERE user_id = $1`,
+	`DELETE FROM shares_alert_api_keys WHERE user_id = $1`,
+	`DELETE FROM shares_alert_identities WHERE user_id = $1`,
+	`DELETE FROM shares_alert_alerts WHERE user_id = $1`,
+	`DELETE FROM shares_alert_portfolio_snapshots WHERE portfolio_id IN (SELECT id FROM shares_alert_portfolios WHERE user_id = $1)`,
+	`DELETE FROM shares_alert_portfolio_transactions WHERE portfolio_id IN (SELECT id FROM shares_alert_portfolios WHERE user_id = $1)`,
+	`DELETE FROM shares_alert_portfolios WHERE user_id = $1`,
+	`DELETE FROM shares_alert_watchlist_items WHERE watchlist_id IN (SELECT id FROM shares_alert_watchlists WHERE user_id = $1)`,
+	`DELETE FROM shares_alert_watchlists WHERE user_id = $1`,
+	`DELETE FROM shares_alert_user_preferences WHERE user_id = $1`,
+}
+
+// Purge deletes the user and all of their data in one transaction
+func (r *AccountRepository) Purge(ctx context.Context, user *models.User) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		for _, query := range purgeStatements {
+			if _, err := r.db.ExecContext(ctx, query, user.ID); err != nil {
+				return err
+			}
+		}
+
+		// Sign-in links are keyed by address, not user
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_magic_links WHERE email = $1`, user.Email); err != nil {
+			return err
+		}
+
+		result, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_users WHERE id = $1`, user.ID)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return sql.ErrNoRows
+		}
+
+		return nil
+	})
+}
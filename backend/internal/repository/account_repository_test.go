@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestAccountRepositoryPurge(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	user := createTestUser(t, db, "leaving@example.com")
+	other := createTestUser(t, db, "staying@example.com")
+
+	createTestAlert(t, db, user.ID, "MTNGH", testNow)
+	createTestAlert(t, db, other.ID, "MTNGH", testNow)
+	createTestOutboxMessage(t, db, user.ID, testNow)
+	prefs := &models.UserPreferences{ID: uuid.New().String(), UserID: user.ID, CreatedAt: testNow, UpdatedAt: testNow}
+	if err := NewUserRepository(db).CreatePreferences(ctx, prefs); err != nil {
+		t.Fatalf("CreatePreferences: %v", err)
+	}
+
+	repo := NewAccountRepository(db)
+	if err := repo.Purge(ctx, user); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if err := repo.Purge(ctx, user); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second Purge error = %v, want sql.ErrNoRows", err)
+	}
+
+	for _, table := range []string{"shares_alert_alerts", "shares_alert_notification_outbox", "shares_alert_user_preferences"} {
+		if n := countRows(t, db, `SELECT COUNT(*) FROM `+table+` WHERE user_id = $1`, user.ID); n != 0 {
+			t.Errorf("%d rows left in %s, want 0", n, table)
+		}
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM shares_alert_alerts WHERE user_id = $1`, other.ID); n != 1 {
+		t.Errorf("other user has %d alerts after the purge, want 1", n)
+	}
+}
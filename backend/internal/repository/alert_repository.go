@@ -1,45 +1,34 @@
 package repository
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"shares-alert-backend/internal/database"
 	"shares-alert-backend/internal/models"
 )
 
 type AlertRepository struct {
-	db *sql.DB
+	db *database.DB
 }
 
-func NewAlertRepository(db *sql.DB) *AlertRepository {
+func NewAlertRepository(db *database.DB) *AlertRepository {
 	return &AlertRepository{db: db}
 }
 
-func (r *AlertRepository) Create(alert *models.Alert) error {
-	query := `
-		INSERT INTO shares_alert_alerts (id, user_id, stock_symbol, stock_name, alert_type, 
-			threshold_price, current_price, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	`
-	_, err := r.db.Exec(query, alert.ID, alert.UserID, alert.StockSymbol,
-		alert.StockName, alert.AlertType, alert.ThresholdPrice, alert.CurrentPrice,
-		alert.Status, alert.CreatedAt, alert.UpdatedAt)
-	return err
-}
+const alertColumns = `id, user_id, scope, portfolio_id, stock_symbol, stock_name, alert_type, threshold_price,
+	threshold_percent, direction, current_price, peak_value, net_invested, status, urgent,
+	created_at, updated_at, triggered_at, snoozed_until`
 
-func (r *AlertRepository) GetByID(id string) (*models.Alert, error) {
-	query := `
-		SELECT id, user_id, stock_symbol, stock_name, alert_type, threshold_price,
-			current_price, status, created_at, updated_at, triggered_at
-		FROM shares_alert_alerts WHERE id = $1
-	`
+func scanAlert(scanner interface{ Scan(...interface{}) error }) (*models.Alert, error) {
 	alert := &models.Alert{}
-	err := r.db.QueryRow(query, id).Scan(
-		&alert.ID, &alert.UserID, &alert.StockSymbol, &alert.StockName,
-		&alert.AlertType, &alert.ThresholdPrice, &alert.CurrentPrice,
-		&alert.Status, &alert.CreatedAt, &alert.UpdatedAt, &alert.TriggeredAt,
+	err := scanner.Scan(
+		&alert.ID, &alert.UserID, &alert.Scope, &alert.PortfolioID, &alert.StockSymbol, &alert.StockName,
+		&alert.AlertType, &alert.ThresholdPrice, &alert.ThresholdPercent, &alert.Direction,
+		&alert.CurrentPrice, &alert.PeakValue, &alert.NetInvested, &alert.Status, &alert.Urgent,
+		&alert.CreatedAt, &alert.UpdatedAt, &alert.TriggeredAt, &alert.SnoozedUntil,
 	)
 	if err != nil {
 		return nil, err
@@ -47,35 +36,96 @@ func (r *AlertRepository) GetByID(id string) (*models.Alert, error) {
 	return alert, nil
 }
 
-func (r *AlertRepository) GetByUserID(userID string, filters map[string]interface{}) ([]*models.Alert, error) {
+func insertAlert(ctx context.Context, ex execer, alert *models.Alert) error {
 	query := `
-		SELECT id, user_id, stock_symbol, stock_name, alert_type, threshold_price,
-			current_price, status, created_at, updated_at, triggered_at
-		FROM shares_alert_alerts WHERE user_id = $1
+		INSERT INTO shares_alert_alerts (id, user_id, scope, portfolio_id, stock_symbol, stock_name, alert_type, 
+			threshold_price, threshold_percent, direction, current_price, peak_value, net_invested,
+			status, urgent, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
+	_, err := ex.ExecContext(ctx, query, alert.ID, alert.UserID, alert.Scope, alert.PortfolioID, alert.StockSymbol,
+		alert.StockName, alert.AlertType, alert.ThresholdPrice, alert.ThresholdPercent, alert.Direction,
+		alert.CurrentPrice, alert.PeakValue, alert.NetInvested, alert.Status, alert.Urgent,
+		alert.CreatedAt, alert.UpdatedAt)
+	return err
+}
+
+func (r *AlertRepository) Create(ctx context.Context, alert *models.Alert) error {
+	return insertAlert(ctx, r.db, alert)
+}
+
+// CreateAlerts records a batch of alerts atomically
+func (r *AlertRepository) CreateAlerts(ctx context.Context, alerts []*models.Alert) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		for _, alert := range alerts {
+			if err := insertAlert(ctx, r.db, alert); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *AlertRepository) GetByID(ctx context.Context, id string) (*models.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM shares_alert_alerts WHERE id = $1`
+	return scanAlert(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetByUserID returns every alert of the user's that matches filter, newest first
+func (r *AlertRepository) GetByUserID(ctx context.Context, userID string, filter models.AlertFilter) ([]*models.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM shares_alert_alerts WHERE user_id = $1`
 	args := []interface{}{userID}
-	paramCount := 1
 
-	// Add filters
-	if status, ok := filters["status"].(string); ok && status != "" {
-		paramCount++
-		query += fmt.Sprintf(" AND status = $%d", paramCount)
-		args = append(args, status)
+	query, args = appendAlertFilter(query, args, filter)
+	query += " ORDER BY created_at DESC"
+
+	return r.queryAlerts(ctx, query, args)
+}
+
+// ListByUserID returns up to limit of the user's alerts that match filter,
+// ordered by sort and then by id. after is the sort position to start past:
+// one value per sort key followed by the id, as read from the last alert of
+// the previous page, or nil for the first page.
+func (r *AlertRepository) ListByUserID(ctx context.Context, userID string, filter models.AlertFilter, sort []models.AlertSort, after []interface{}, limit int) ([]*models.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM shares_alert_alerts WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	query, args = appendAlertFilter(query, args, filter)
+
+	keys := make([]alertSortKey, 0, len(sort)+1)
+	for _, s := range sort {
+		key, ok := alertSortKeys[s.Field]
+		if !ok {
+			return nil, fmt.Errorf("unknown alert sort field %q", s.Field)
+		}
+		key.descending = s.Descending
+		keys = append(keys, key)
 	}
-	if stockSymbol, ok := filters["stock_symbol"].(string); ok && stockSymbol != "" {
-		paramCount++
-		query += fmt.Sprintf(" AND stock_symbol = $%d", paramCount)
-		args = append(args, stockSymbol)
+	// id breaks ties, in the direction of the last key
+	keys = append(keys, alertSortKey{column: "id", descending: len(sort) > 0 && sort[len(sort)-1].Descending})
+
+	if after != nil {
+		if len(after) != len(keys) {
+			return nil, fmt.Errorf("alert cursor has %d values, want %d", len(after), len(keys))
+		}
+		var predicate string
+		predicate, args = keysetPredicate(keys, after, args)
+		query += " AND (" + predicate + ")"
 	}
-	if alertType, ok := filters["alert_type"].(string); ok && alertType != "" {
-		paramCount++
-		query += fmt.Sprintf(" AND alert_type = $%d", paramCount)
-		args = append(args, alertType)
+
+	order := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		order = append(order, key.orderBy()...)
 	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", strings.Join(order, ", "), len(args))
 
-	query += " ORDER BY created_at DESC"
+	return r.queryAlerts(ctx, query, args)
+}
 
-	rows, err := r.db.Query(query, args...)
+func (r *AlertRepository) queryAlerts(ctx context.Context, query string, args []interface{}) ([]*models.Alert, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -83,29 +133,119 @@ func (r *AlertRepository) GetByUserID(userID string, filters map[string]interfac
 
 	var alerts []*models.Alert
 	for rows.Next() {
-		alert := &models.Alert{}
-		err := rows.Scan(
-			&alert.ID, &alert.UserID, &alert.StockSymbol, &alert.StockName,
-			&alert.AlertType, &alert.ThresholdPrice, &alert.CurrentPrice,
-			&alert.Status, &alert.CreatedAt, &alert.UpdatedAt, &alert.TriggeredAt,
-		)
+		alert, err := scanAlert(rows)
 		if err != nil {
 			return nil, err
 		}
 		alerts = append(alerts, alert)
 	}
 
-	return alerts, nil
+	return alerts, rows.Err()
 }
 
-func (r *AlertRepository) GetActiveAlerts() ([]*models.Alert, error) {
-	query := `
-		SELECT id, user_id, stock_symbol, stock_name, alert_type, threshold_price,
-			current_price, status, created_at, updated_at, triggered_at
-		FROM shares_alert_alerts WHERE status = $1
-		ORDER BY created_at DESC
-	`
-	rows, err := r.db.Query(query, models.AlertStatusActive)
+// appendAlertFilter adds filter's conditions to a query on the alerts table
+func appendAlertFilter(query string, args []interface{}, filter models.AlertFilter) (string, []interface{}) {
+	in := func(column string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			args = append(args, v)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		query += fmt.Sprintf(" AND %s IN (%s)", column, strings.Join(placeholders, ", "))
+	}
+	in("status", filter.Statuses)
+	in("stock_symbol", filter.StockSymbols)
+	in("alert_type", filter.AlertTypes)
+	in("scope", filter.Scopes)
+	in("portfolio_id", filter.PortfolioIDs)
+
+	compare := func(column, op string, at *time.Time) {
+		if at == nil {
+			return
+		}
+		args = append(args, *at)
+		query += fmt.Sprintf(" AND %s %s $%d", column, op, len(args))
+	}
+	compare("created_at", ">=", filter.CreatedFrom)
+	compare("created_at", "<", filter.CreatedTo)
+	compare("triggered_at", ">=", filter.TriggeredFrom)
+	compare("triggered_at", "<", filter.TriggeredTo)
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		// ! escapes the LIKE wildcards; backslash would mean something
+		// different to each database
+		escaped := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(search))
+		args = append(args, "%"+escaped+"%")
+		query += fmt.Sprintf(" AND LOWER(stock_name) LIKE $%d ESCAPE '!'", len(args))
+	}
+
+	return query, args
+}
+
+type alertSortKey struct {
+	column     string
+	nullable   bool
+	descending bool
+}
+
+var alertSortKeys = map[string]alertSortKey{
+	models.AlertSortCreatedAt:   {column: "created_at"},
+	models.AlertSortUpdatedAt:   {column: "updated_at"},
+	models.AlertSortTriggeredAt: {column: "triggered_at", nullable: true},
+	models.AlertSortStockSymbol: {column: "stock_symbol"},
+	models.AlertSortStockName:   {column: "stock_name"},
+	models.AlertSortStatus:      {column: "status"},
+	models.AlertSortAlertType:   {column: "alert_type"},
+}
+
+// orderBy puts NULLs last in either direction. The databases disagree on
+// where they go by default, and MySQL has no NULLS LAST.
+func (k alertSortKey) orderBy() []string {
+	dir := " ASC"
+	if k.descending {
+		dir = " DESC"
+	}
+	if k.nullable {
+		return []string{k.column + " IS NULL", k.column + dir}
+	}
+	return []string{k.column + dir}
+}
+
+// keysetPredicate matches the rows that sort after the values, one per key:
+// those past the first value, or level with it and past the second, and so on
+func keysetPredicate(keys []alertSortKey, values []interface{}, args []interface{}) (string, []interface{}) {
+	var alternatives, level []string
+	for i, key := range keys {
+		value := values[i]
+		if value == nil {
+			// Nothing sorts after NULL, which comes last
+			level = append(level, key.column+" IS NULL")
+			continue
+		}
+
+		args = append(args, value)
+		placeholder := fmt.Sprintf("$%d", len(args))
+		op := " > "
+		if key.descending {
+			op = " < "
+		}
+		past := key.column + op + placeholder
+		if key.nullable {
+			past = "(" + past + " OR " + key.column + " IS NULL)"
+		}
+
+		alternatives = append(alternatives, "("+strings.Join(append(level[:len(level):len(level)], past), " AND ")+")")
+		level = append(level, key.column+" = "+placeholder)
+	}
+	return strings.Join(alternatives, " OR "), args
+}
+
+func (r *AlertRepository) GetActiveAlerts(ctx context.Context) ([]*models.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM shares_alert_alerts WHERE status = $1 ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, models.AlertStatusActive)
 	if err != nil {
 		return nil, err
 	}
@@ -113,12 +253,7 @@ func (r *AlertRepository) GetActiveAlerts() ([]*models.Alert, error) {
 
 	var alerts []*models.Alert
 	for rows.Next() {
-		alert := &models.Alert{}
-		err := rows.Scan(
-			&alert.ID, &alert.UserID, &alert.StockSymbol, &alert.StockName,
-			&alert.AlertType, &alert.ThresholdPrice, &alert.CurrentPrice,
-			&alert.Status, &alert.CreatedAt, &alert.UpdatedAt, &alert.TriggeredAt,
-		)
+		alert, err := scanAlert(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -128,7 +263,7 @@ func (r *AlertRepository) GetActiveAlerts() ([]*models.Alert, error) {
 	return alerts, nil
 }
 
-func (r *AlertRepository) Update(alert *models.Alert) error {
+func (r *AlertRepository) Update(ctx context.Context, alert *models.Alert) error {
 	// Build dynamic update query
 	setParts := []string{}
 	args := []interface{}{}
@@ -149,16 +284,42 @@ func (r *AlertRepository) Update(alert *models.Alert) error {
 		setParts = append(setParts, fmt.Sprintf("threshold_price = $%d", paramCount))
 		args = append(args, alert.ThresholdPrice)
 	}
+	if alert.ThresholdPercent != nil {
+		paramCount++
+		setParts = append(setParts, fmt.Sprintf("threshold_percent = $%d", paramCount))
+		args = append(args, alert.ThresholdPercent)
+	}
+	if alert.Direction != "" {
+		paramCount++
+		setParts = append(setParts, fmt.Sprintf("direction = $%d", paramCount))
+		args = append(args, alert.Direction)
+	}
 	if alert.CurrentPrice != nil {
 		paramCount++
 		setParts = append(setParts, fmt.Sprintf("current_price = $%d", paramCount))
 		args = append(args, alert.CurrentPrice)
 	}
+	if alert.PeakValue != nil {
+		paramCount++
+		setParts = append(setParts, fmt.Sprintf("peak_value = $%d", paramCount))
+		args = append(args, alert.PeakValue)
+	}
+	if alert.NetInvested != nil {
+		paramCount++
+		setParts = append(setParts, fmt.Sprintf("net_invested = $%d", paramCount))
+		args = append(args, alert.NetInvested)
+	}
 	if alert.Status != "" {
 		paramCount++
 		setParts = append(setParts, fmt.Sprintf("status = $%d", paramCount))
 		args = append(args, alert.Status)
 	}
+	paramCount++
+	setParts = append(setParts, fmt.Sprintf("urgent = $%d", paramCount))
+	args = append(args, alert.Urgent)
+	paramCount++
+	setParts = append(setParts, fmt.Sprintf("snoozed_until = $%d", paramCount))
+	args = append(args, alert.SnoozedUntil)
 	if alert.TriggeredAt != nil {
 		paramCount++
 		setParts = append(setParts, fmt.Sprintf("triggered_at = $%d", paramCount))
@@ -168,33 +329,64 @@ func (r *AlertRepository) Update(alert *models.Alert) error {
 	args = append(args, alert.ID)
 
 	query := fmt.Sprintf("UPDATE shares_alert_alerts SET %s WHERE id = $%d", strings.Join(setParts, ", "), len(args))
-	_, err := r.db.Exec(query, args...)
+	_, err := r.db.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (r *AlertRepository) Delete(id string) error {
+func (r *AlertRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM shares_alert_alerts WHERE id = $1`
-	_, err := r.db.Exec(query, id)
+	_, err := r.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (r *AlertRepository) UpdateCurrentPrice(stockSymbol string, currentPrice float64) error {
+func (r *AlertRepository) UpdateCurrentPrice(ctx context.Context, stockSymbol string, currentPrice float64) error {
 	query := `
 		UPDATE shares_alert_alerts 
 		SET current_price = $1, updated_at = $2
 		WHERE stock_symbol = $3 AND status = $4
 	`
-	_, err := r.db.Exec(query, currentPrice, time.Now(), stockSymbol, models.AlertStatusActive)
+	_, err := r.db.ExecContext(ctx, query, currentPrice, time.Now(), stockSymbol, models.AlertStatusActive)
 	return err
 }
 
-func (r *AlertRepository) TriggerAlert(alertID string) error {
+func (r *AlertRepository) TriggerAlert(ctx context.Context, alertID string) error {
 	now := time.Now()
 	query := `
 		UPDATE shares_alert_alerts 
 		SET status = $1, triggered_at = $2, updated_at = $3
 		WHERE id = $4
 	`
-	_, err := r.db.Exec(query, models.AlertStatusTriggered, now, now, alertID)
+	_, err := r.db.ExecContext(ctx, query, models.AlertStatusTriggered, now, now, alertID)
 	return err
-}
\ No newline at end of file
+}
+
+// TriggerAlertWithNotifications marks the alert triggered and records its in-app
+// notification and queued emails in one transaction, so a trigger is never
+// recorded without its notifications
+func (r *AlertRepository) TriggerAlertWithNotifications(ctx context.Context, alertID string, notification *models.Notification, messages []*models.OutboxMessage) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		query := `
+			UPDATE shares_alert_alerts 
+			SET status = $1, triggered_at = $2, updated_at = $3
+			WHERE id = $4
+		`
+		if _, err := r.db.ExecContext(ctx, query, models.AlertStatusTriggered, now, now, alertID); err != nil {
+			return err
+		}
+
+		if notification != nil {
+			if err := insertNotification(ctx, r.db, notification); err != nil {
+				return fmt.Errorf("failed to record notification: %w", err)
+			}
+		}
+
+		for _, msg := range messages {
+			if err := insertOutboxMessage(ctx, r.db, msg); err != nil {
+				return fmt.Errorf("failed to queue notification: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
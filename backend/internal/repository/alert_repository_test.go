@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+func TestAlertRepositoryGetByUserIDFilters(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewAlertRepository(db)
+	user := createTestUser(t, db, "alerts@example.com")
+	other := createTestUser(t, db, "other@example.com")
+
+	older := createTestAlert(t, db, user.ID, "MTNGH", testNow.Add(-time.Hour))
+	newer := createTestAlert(t, db, user.ID, "GCB", testNow)
+	createTestAlert(t, db, other.ID, "MTNGH", testNow)
+
+	alerts, err := repo.GetByUserID(ctx, user.ID, models.AlertFilter{})
+	if err != nil {
+		t.Fatalf("GetByUserID: %v", err)
+	}
+	if len(alerts) != 2 || alerts[0].ID != newer.ID || alerts[1].ID != older.ID {
+		t.Fatalf("GetByUserID returned %d alerts, want newest first", len(alerts))
+	}
+	if alerts[0].ThresholdPrice == nil || *alerts[0].ThresholdPrice != 10.5 {
+		t.Errorf("ThresholdPrice = %v, want 10.5", alerts[0].ThresholdPrice)
+	}
+
+	alerts, err = repo.GetByUserID(ctx, user.ID, models.AlertFilter{
+		StockSymbols: []string{"MTNGH"},
+		Statuses:     []string{models.AlertStatusActive},
+		AlertTypes:   []string{models.AlertTypePriceThreshold},
+	})
+	if err != nil {
+		t.Fatalf("GetByUserID with filters: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].ID != older.ID {
+		t.Errorf("filtered GetByUserID returned %d alerts, want just %s", len(alerts), older.ID)
+	}
+}
+
+func TestAlertRepositoryListByUserIDFilters(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewAlertRepository(db)
+	user := createTestUser(t, db, "filters@example.com")
+
+	mtn := createTestAlert(t, db, user.ID, "MTNGH", testNow.Add(-48*time.Hour))
+	gcb := createTestAlert(t, db, user.ID, "GCB", testNow.Add(-24*time.Hour))
+	underscore := createTestAlert(t, db, user.ID, "ACCESS_GH", testNow)
+	setTriggeredAt(t, db, gcb.ID, testNow.Add(-time.Hour))
+
+	from, to := testNow.Add(-36*time.Hour), testNow
+	tests := []struct {
+		name   string
+		filter models.AlertFilter
+		want   []string
+	}{
+		{"any of several symbols", models.AlertFilter{StockSymbols: []string{"MTNGH", "GCB"}}, []string{gcb.ID, mtn.ID}},
+		{"search ignores case", models.AlertFilter{Search: "mtn"}, []string{mtn.ID}},
+		{"search treats wildcards literally", models.AlertFilter{Search: "s_g"}, []string{underscore.ID}},
+		{"search matches _ only to itself", models.AlertFilter{Search: "t_g"}, nil},
+		{"search matches % only to itself", models.AlertFilter{Search: "%"}, nil},
+		{"created range excludes its end", models.AlertFilter{CreatedFrom: &from, CreatedTo: &to}, []string{gcb.ID}},
+		{"triggered range skips untriggered alerts", models.AlertFilter{TriggeredFrom: &from}, []string{gcb.ID}},
+	}
+
+	for _, tt := range tests {
+		alerts, err := repo.ListByUserID(ctx, user.ID, tt.filter, []models.AlertSort{{Field: models.AlertSortCreatedAt, Descending: true}}, nil, 10)
+		if err != nil {
+			t.Fatalf("%s: ListByUserID: %v", tt.name, err)
+		}
+		if got := alertIDs(alerts); !equalIDs(got, tt.want) {
+			t.Errorf("%s: ListByUserID returned %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAlertRepositoryListByUserIDPages(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewAlertRepository(db)
+	user := createTestUser(t, db, "pages@example.com")
+
+	// Two share a trigger time, and two have never triggered
+	a := createTestAlert(t, db, user.ID, "AAA", testNow)
+	b := createTestAlert(t, db, user.ID, "BBB", testNow)
+	c := createTestAlert(t, db, user.ID, "CCC", testNow)
+	d := createTestAlert(t, db, user.ID, "DDD", testNow)
+	e := createTestAlert(t, db, user.ID, "EEE", testNow)
+	setTriggeredAt(t, db, a.ID, testNow.Add(-2*time.Hour))
+	setTriggeredAt(t, db, b.ID, testNow.Add(-time.Hour))
+	setTriggeredAt(t, db, c.ID, testNow.Add(-2*time.Hour))
+
+	tests := []struct {
+		sort []models.AlertSort
+		want []string
+	}{
+		{
+			[]models.AlertSort{{Field: models.AlertSortTriggeredAt, Descending: true}, {Field: models.AlertSortStockName}},
+			[]string{b.ID, a.ID, c.ID, d.ID, e.ID},
+		},
+		{
+			[]models.AlertSort{{Field: models.AlertSortTriggeredAt}, {Field: models.AlertSortStockName, Descending: true}},
+			[]string{c.ID, a.ID, b.ID, e.ID, d.ID},
+		},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		var after []interface{}
+		for page := 0; ; page++ {
+			if page > len(tt.want) {
+				t.Fatalf("sort %v: paging did not finish", tt.sort)
+			}
+			alerts, err := repo.ListByUserID(ctx, user.ID, models.AlertFilter{}, tt.sort, after, 2)
+			if err != nil {
+				t.Fatalf("sort %v: ListByUserID: %v", tt.sort, err)
+			}
+			got = append(got, alertIDs(alerts)...)
+			if len(alerts) < 2 {
+				break
+			}
+
+			last := alerts[len(alerts)-1]
+			after = nil
+			for _, key := range tt.sort {
+				switch key.Field {
+				case models.AlertSortTriggeredAt:
+					if last.TriggeredAt == nil {
+						after = append(after, nil)
+					} else {
+						after = append(after, *last.TriggeredAt)
+					}
+				case models.AlertSortStockName:
+					after = append(after, last.StockName)
+				}
+			}
+			after = append(after, last.ID)
+		}
+
+		if !equalIDs(got, tt.want) {
+			t.Errorf("sort %v: pages returned %v, want %v", tt.sort, got, tt.want)
+		}
+	}
+}
+
+func TestAlertRepositoryUpdate(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewAlertRepository(db)
+	user := createTestUser(t, db, "update@example.com")
+	alert := createTestAlert(t, db, user.ID, "MTNGH", testNow)
+
+	threshold := 12.0
+	snoozedUntil := testNow.Add(24 * time.Hour)
+	update := &models.Alert{
+		ID:             alert.ID,
+		ThresholdPrice: &threshold,
+		Status:         models.AlertStatusPaused,
+		Urgent:         true,
+		SnoozedUntil:   &snoozedUntil,
+	}
+	if err := repo.Update(ctx, update); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, alert.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if *got.ThresholdPrice != 12 || got.Status != models.AlertStatusPaused || !got.Urgent {
+		t.Errorf("GetByID returned %+v", got)
+	}
+	if got.SnoozedUntil == nil || !got.SnoozedUntil.Equal(snoozedUntil) {
+		t.Errorf("SnoozedUntil = %v, want %v", got.SnoozedUntil, snoozedUntil)
+	}
+	if got.AlertType != models.AlertTypePriceThreshold {
+		t.Errorf("AlertType = %q, want it unchanged", got.AlertType)
+	}
+}
+
+func TestAlertRepositoryTriggerWithNotifications(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewAlertRepository(db)
+	user := createTestUser(t, db, "trigger@example.com")
+	alert := createTestAlert(t, db, user.ID, "MTNGH", testNow)
+
+	notification := &models.Notification{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Kind:      "alert_triggered",
+		Title:     "MTNGH",
+		Message:   "MTNGH crossed GH₵10.50",
+		AlertID:   &alert.ID,
+		CreatedAt: testNow,
+	}
+	msg := &models.OutboxMessage{
+		ID:            uuid.New().String(),
+		UserID:        user.ID,
+		AlertID:       &alert.ID,
+		Kind:          models.OutboxKindAlert,
+		Recipient:     user.Email,
+		Subject:       "Alert",
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: testNow,
+		CreatedAt:     testNow,
+		UpdatedAt:     testNow,
+	}
+	if err := repo.TriggerAlertWithNotifications(ctx, alert.ID, notification, []*models.OutboxMessage{msg}); err != nil {
+		t.Fatalf("TriggerAlertWithNotifications: %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, alert.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Status != models.AlertStatusTriggered || got.TriggeredAt == nil {
+		t.Errorf("alert not triggered: %+v", got)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM shares_alert_notifications WHERE alert_id = $1`, alert.ID); n != 1 {
+		t.Errorf("%d notifications recorded, want 1", n)
+	}
+
+	// A duplicate outbox ID fails the transaction, leaving nothing behind
+	second := createTestAlert(t, db, user.ID, "GCB", testNow)
+	if err := repo.TriggerAlertWithNotifications(ctx, second.ID, nil, []*models.OutboxMessage{msg}); err == nil {
+		t.Fatal("TriggerAlertWithNotifications with a duplicate message succeeded")
+	}
+	got, err = repo.GetByID(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Status != models.AlertStatusActive {
+		t.Errorf("Status = %q after a failed trigger, want active", got.Status)
+	}
+}
+
+func TestAlertRepositoryCreateAlertsIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewAlertRepository(db)
+	user := createTestUser(t, db, "batch@example.com")
+
+	first := &models.Alert{
+		ID: uuid.New().String(), UserID: user.ID, Scope: models.AlertScopeStock, StockSymbol: "MTNGH",
+		StockName: "MTN Ghana", AlertType: models.AlertTypeDividendAnnouncement, Status: models.AlertStatusActive,
+		CreatedAt: testNow, UpdatedAt: testNow,
+	}
+	duplicate := *first
+	if err := repo.CreateAlerts(ctx, []*models.Alert{first, &duplicate}); err == nil {
+		t.Fatal("CreateAlerts with a duplicate ID succeeded")
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM shares_alert_alerts WHERE user_id = $1`, user.ID); n != 0 {
+		t.Errorf("%d alerts left after a failed batch, want 0", n)
+	}
+}
+
+func setTriggeredAt(t *testing.T, db *database.DB, alertID string, at time.Time) {
+	t.Helper()
+
+	if _, err := db.ExecContext(context.Background(), `UPDATE shares_alert_alerts SET status = $1, triggered_at = $2 WHERE id = $3`, models.AlertStatusTriggered, at, alertID); err != nil {
+		t.Fatalf("failed to trigger alert: %v", err)
+	}
+}
+
+func alertIDs(alerts []*models.Alert) []string {
+	var ids []string
+	for _, alert := range alerts {
+		ids = append(ids, alert.ID)
+	}
+	return ids
+}
+
+func equalIDs(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type APIKeyRepository struct {
+	db *database.DB
+}
+
+func NewAPIKeyRepository(db *database.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked_at`
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	query := `
+		INSERT INTO shares_alert_api_keys (id, user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query, key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash,
+		strings.Join(key.Scopes, ","), key.ExpiresAt, key.CreatedAt)
+	return err
+}
+
+func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM shares_alert_api_keys WHERE key_hash = $1`
+	return scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+}
+
+// GetByUserID lists the user's keys that haven't been revoked, newest first
+func (r *APIKeyRepository) GetByUserID(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM shares_alert_api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Revoke revokes one of the user's keys. It returns sql.ErrNoRows if the user
+// has no such active key.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id, userID string, at time.Time) error {
+	query := `UPDATE shares_alert_api_keys SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, at, id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) UpdateLastUsed(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE shares_alert_api_keys SET last_used_at = $1 WHERE id = $2`, at, id)
+	return err
+}
+
+func scanAPIKey(scanner interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var scopes string
+	err := scanner.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	return key, nil
+}
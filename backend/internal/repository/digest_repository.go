@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+// errDigestAlreadySent rolls back a send that another run got to first
+var errDigestAlreadySent = errors.New("digest entries already sent")
+
+type DigestRepository struct {
+	db *database.DB
+}
+
+func NewDigestRepository(db *database.DB) *DigestRepository {
+	return &DigestRepository{db: db}
+}
+
+func (r *DigestRepository) Create(ctx context.Context, entry *models.DigestEntry) error {
+	query := `
+		INSERT INTO shares_alert_digest_entries (id, user_id, alert_id, stock_symbol, stock_name,
+			alert_type, threshold_price, trigger_price, triggered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query, entry.ID, entry.UserID, entry.AlertID, entry.StockSymbol,
+		entry.StockName, entry.AlertType, entry.ThresholdPrice, entry.TriggerPrice, entry.TriggeredAt)
+	return err
+}
+
+// GetUsersWithPending returns the IDs of users that have unsent digest entries
+func (r *DigestRepository) GetUsersWithPending(ctx context.Context) ([]string, error) {
+	query := `SELECT DISTINCT user_id FROM shares_alert_digest_entries WHERE sent_at IS NULL`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
+func (r *DigestRepository) GetPendingByUserID(ctx context.Context, userID string) ([]*models.DigestEntry, error) {
+	query := `
+		SELECT id, user_id, alert_id, stock_symbol, stock_name, alert_type,
+			threshold_price, trigger_price, triggered_at, sent_at
+		FROM shares_alert_digest_entries
+		WHERE user_id = $1 AND sent_at IS NULL
+		ORDER BY triggered_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.DigestEntry
+	for rows.Next() {
+		entry := &models.DigestEntry{}
+		err := rows.Scan(
+			&entry.ID, &entry.UserID, &entry.AlertID, &entry.StockSymbol, &entry.StockName,
+			&entry.AlertType, &entry.ThresholdPrice, &entry.TriggerPrice, &entry.TriggeredAt, &entry.SentAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetByUserID returns every digest entry for the user, sent or not, oldest first
+func (r *DigestRepository) GetByUserID(ctx context.Context, userID string) ([]*models.DigestEntry, error) {
+	query := `
+		SELECT id, user_id, alert_id, stock_symbol, stock_name, alert_type,
+			threshold_price, trigger_price, triggered_at, sent_at
+		FROM shares_alert_digest_entries
+		WHERE user_id = $1
+		ORDER BY triggered_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.DigestEntry
+	for rows.Next() {
+		entry := &models.DigestEntry{}
+		err := rows.Scan(
+			&entry.ID, &entry.UserID, &entry.AlertID, &entry.StockSymbol, &entry.StockName,
+			&entry.AlertType, &entry.ThresholdPrice, &entry.TriggerPrice, &entry.TriggeredAt, &entry.SentAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetLastSentAt returns when the user's most recent digest went out, or nil if none has
+func (r *DigestRepository) GetLastSentAt(ctx context.Context, userID string) (*time.Time, error) {
+	query := `
+		SELECT sent_at FROM shares_alert_digest_entries
+		WHERE user_id = $1 AND sent_at IS NOT NULL
+		ORDER BY sent_at DESC LIMIT 1
+	`
+	var sentAt time.Time
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&sentAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sentAt, nil
+}
+
+// MarkSentWithMessage marks the entries sent and queues the digest's outbox
+// message in one transaction. It returns false, queuing nothing, if any of
+// the entries has already been sent.
+func (r *DigestRepository) MarkSentWithMessage(ctx context.Context, ids []string, sentAt time.Time, msg *models.OutboxMessage) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := []interface{}{sentAt}
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		args = append(args, id)
+	}
+	query := fmt.Sprintf("UPDATE shares_alert_digest_entries SET sent_at = $1 WHERE sent_at IS NULL AND id IN (%s)",
+		strings.Join(placeholders, ", "))
+
+	sent := false
+	err := r.db.WithTx(ctx, func(ctx context.Context) error {
+		result, err := r.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected != int64(len(ids)) {
+			return errDigestAlreadySent
+		}
+
+		if err := insertOutboxMessage(ctx, r.db, msg); err != nil {
+			return err
+		}
+
+		sent = true
+		return nil
+	})
+	if errors.Is(err, errDigestAlreadySent) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return sent, nil
+}
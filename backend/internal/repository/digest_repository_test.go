@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestDigestRepositoryMarkSentWithMessage(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewDigestRepository(db)
+	user := createTestUser(t, db, "digest@example.com")
+	alert := createTestAlert(t, db, user.ID, "MTNGH", testNow)
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		entry := &models.DigestEntry{
+			ID: uuid.New().String(), UserID: user.ID, AlertID: alert.ID, StockSymbol: "MTNGH", StockName: "MTN Ghana",
+			AlertType: alert.AlertType, TriggerPrice: 2.5, TriggeredAt: testNow.Add(time.Duration(i) * time.Minute),
+		}
+		if err := repo.Create(ctx, entry); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	send := func() bool {
+		t.Helper()
+		msg := &models.OutboxMessage{
+			ID: uuid.New().String(), UserID: user.ID, Kind: models.OutboxKindDigest, Recipient: user.Email,
+			Subject: "Your daily digest", Status: models.OutboxStatusPending,
+			NextAttemptAt: testNow, CreatedAt: testNow, UpdatedAt: testNow,
+		}
+		sent, err := repo.MarkSentWithMessage(ctx, ids, testNow.Add(time.Hour), msg)
+		if err != nil {
+			t.Fatalf("MarkSentWithMessage: %v", err)
+		}
+		return sent
+	}
+	if !send() {
+		t.Fatal("first MarkSentWithMessage sent nothing")
+	}
+	if send() {
+		t.Fatal("second MarkSentWithMessage sent the digest again")
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM shares_alert_notification_outbox WHERE user_id = $1`, user.ID); n != 1 {
+		t.Errorf("%d messages queued, want 1", n)
+	}
+
+	pending, err := repo.GetPendingByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetPendingByUserID: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("%d entries still pending, want 0", len(pending))
+	}
+}
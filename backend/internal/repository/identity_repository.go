@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type IdentityRepository struct {
+	db *database.DB
+}
+
+func NewIdentityRepository(db *database.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func (r *IdentityRepository) Create(ctx context.Context, identity *models.Identity) error {
+	query := `
+		INSERT INTO shares_alert_identities (id, user_id, provider, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query, identity.ID, identity.UserID, identity.Provider, identity.Subject,
+		identity.Email, identity.CreatedAt)
+	return err
+}
+
+func (r *IdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM shares_alert_identities WHERE provider = $1 AND subject = $2
+	`
+	identity := &models.Identity{}
+	err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *IdentityRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at
+		FROM shares_alert_identities WHERE user_id = $1 ORDER BY created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*models.Identity
+	for rows.Next() {
+		identity := &models.Identity{}
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+			&identity.Email, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+// UpdateEmail records the address the provider currently reports for the identity
+func (r *IdentityRepository) UpdateEmail(ctx context.Context, id, email string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE shares_alert_identities SET email = $1 WHERE id = $2`, email, id)
+	return err
+}
+
+// Delete unlinks one of the user's identities, refusing to remove the last
+// one so the account can still be signed in to. It returns sql.ErrNoRows if
+// the user has no such identity, or no other identity.
+func (r *IdentityRepository) Delete(ctx context.Context, id, userID string) error {
+	query := `
+		DELETE FROM shares_alert_identities
+		WHERE id = $1 AND user_id = $2
+			AND (SELECT COUNT(*) FROM shares_alert_identities WHERE user_id = $2) > 1
+	`
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestIdentityRepositoryUpdateEmailAndDelete(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewIdentityRepository(db)
+	user := createTestUser(t, db, "ama@example.com")
+
+	google := &models.Identity{
+		ID: uuid.New().String(), UserID: user.ID, Provider: models.IdentityProviderGoogle,
+		Subject: "google-123", Email: "ama@gmail.com", CreatedAt: testNow,
+	}
+	if err := repo.Create(ctx, google); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := repo.UpdateEmail(ctx, google.ID, "ama.mensah@gmail.com"); err != nil {
+		t.Fatalf("UpdateEmail: %v", err)
+	}
+	got, err := repo.GetByProviderSubject(ctx, models.IdentityProviderGoogle, "google-123")
+	if err != nil {
+		t.Fatalf("GetByProviderSubject: %v", err)
+	}
+	if got.Email != "ama.mensah@gmail.com" {
+		t.Errorf("Email = %q, want the updated address", got.Email)
+	}
+
+	// The only identity can't be removed
+	if err := repo.Delete(ctx, google.ID, user.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Delete(last identity) error = %v, want sql.ErrNoRows", err)
+	}
+}
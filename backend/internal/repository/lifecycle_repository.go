@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type LifecycleRepository struct {
+	db *database.DB
+}
+
+func NewLifecycleRepository(db *database.DB) *LifecycleRepository {
+	return &LifecycleRepository{db: db}
+}
+
+// GetUsersWithoutAlerts returns users who signed up before signedUpBefore,
+// have never created an alert and haven't been sent the nudge
+func (r *LifecycleRepository) GetUsersWithoutAlerts(ctx context.Context, signedUpBefore time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT u.id FROM shares_alert_users u
+		WHERE u.created_at <= $1
+			AND NOT EXISTS (SELECT 1 FROM shares_alert_alerts a WHERE a.user_id = u.id)
+			AND NOT EXISTS (SELECT 1 FROM shares_alert_lifecycle_emails l WHERE l.user_id = u.id AND l.kind = $2)
+		ORDER BY u.created_at ASC
+		LIMIT $3
+	`
+	return r.queryUserIDs(ctx, query, signedUpBefore, models.LifecycleEmailNudge, limit)
+}
+
+// GetDormantUsers returns users who haven't logged in since inactiveSince and
+// haven't been sent the re-engagement email
+func (r *LifecycleRepository) GetDormantUsers(ctx context.Context, inactiveSince time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT u.id FROM shares_alert_users u
+		WHERE COALESCE(u.last_login_at, u.created_at) <= $1
+			AND NOT EXISTS (SELECT 1 FROM shares_alert_lifecycle_emails l WHERE l.user_id = u.id AND l.kind = $2)
+		ORDER BY u.created_at ASC
+		LIMIT $3
+	`
+	return r.queryUserIDs(ctx, query, inactiveSince, models.LifecycleEmailReengagement, limit)
+}
+
+// RecordWithMessage records a lifecycle email and queues its outbox message in
+// one transaction. It returns false, queuing nothing, if the user has already
+// been sent that kind of email.
+func (r *LifecycleRepository) RecordWithMessage(ctx context.Context, entry *models.LifecycleEmail, msg *models.OutboxMessage) (bool, error) {
+	recorded := false
+	err := r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := r.db.Dialect().Upsert("shares_alert_lifecycle_emails",
+			[]string{"id", "user_id", "kind", "sent_at"}, []string{"user_id", "kind"}, nil)
+		result, err := r.db.ExecContext(ctx, query, entry.ID, entry.UserID, entry.Kind, entry.SentAt)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+
+		if err := insertOutboxMessage(ctx, r.db, msg); err != nil {
+			return err
+		}
+
+		recorded = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return recorded, nil
+}
+
+func (r *LifecycleRepository) GetByUserID(ctx context.Context, userID string) ([]*models.LifecycleEmail, error) {
+	query := `
+		SELECT id, user_id, kind, sent_at FROM shares_alert_lifecycle_emails
+		WHERE user_id = $1 ORDER BY sent_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.LifecycleEmail
+	for rows.Next() {
+		entry := &models.LifecycleEmail{}
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Kind, &entry.SentAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (r *LifecycleRepository) queryUserIDs(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
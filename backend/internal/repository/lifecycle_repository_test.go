@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestLifecycleRepositoryNudgeOnce(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewLifecycleRepository(db)
+	idle := createTestUser(t, db, "idle@example.com")
+	active := createTestUser(t, db, "active@example.com")
+	createTestAlert(t, db, active.ID, "MTNGH", testNow)
+
+	userIDs, err := repo.GetUsersWithoutAlerts(ctx, testNow.Add(time.Hour), 10)
+	if err != nil {
+		t.Fatalf("GetUsersWithoutAlerts: %v", err)
+	}
+	if len(userIDs) != 1 || userIDs[0] != idle.ID {
+		t.Fatalf("GetUsersWithoutAlerts returned %v, want just %s", userIDs, idle.ID)
+	}
+
+	record := func() bool {
+		t.Helper()
+		entry := &models.LifecycleEmail{
+			ID: uuid.New().String(), UserID: idle.ID, Kind: models.LifecycleEmailNudge, SentAt: testNow,
+		}
+		msg := &models.OutboxMessage{
+			ID: uuid.New().String(), UserID: idle.ID, Kind: models.OutboxKindLifecycle, Recipient: idle.Email,
+			Subject: "Set up your first alert", Status: models.OutboxStatusPending,
+			NextAttemptAt: testNow, CreatedAt: testNow, UpdatedAt: testNow,
+		}
+		recorded, err := repo.RecordWithMessage(ctx, entry, msg)
+		if err != nil {
+			t.Fatalf("RecordWithMessage: %v", err)
+		}
+		return recorded
+	}
+	if !record() {
+		t.Fatal("first RecordWithMessage reported a duplicate")
+	}
+	if record() {
+		t.Fatal("second RecordWithMessage recorded the nudge again")
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM shares_alert_notification_outbox WHERE user_id = $1`, idle.ID); n != 1 {
+		t.Errorf("%d messages queued, want 1", n)
+	}
+
+	userIDs, err = repo.GetUsersWithoutAlerts(ctx, testNow.Add(time.Hour), 10)
+	if err != nil {
+		t.Fatalf("GetUsersWithoutAlerts: %v", err)
+	}
+	if len(userIDs) != 0 {
+		t.Errorf("GetUsersWithoutAlerts returned %v after the nudge, want none", userIDs)
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type MagicLinkRepository struct {
+	db *database.DB
+}
+
+func NewMagicLinkRepository(db *database.DB) *MagicLinkRepository {
+	return &MagicLinkRepository{db: db}
+}
+
+// Create stores a new link, clearing out expired ones as it goes
+func (r *MagicLinkRepository) Create(ctx context.Context, link *models.MagicLink) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_magic_links WHERE expires_at < $1`, link.CreatedAt); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO shares_alert_magic_links (id, email, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.ExecContext(ctx, query, link.ID, link.Email, link.TokenHash, link.CreatedAt, link.ExpiresAt)
+	return err
+}
+
+// CountSince counts links sent to an email address since the given time
+func (r *MagicLinkRepository) CountSince(ctx context.Context, email string, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM shares_alert_magic_links WHERE email = $1 AND created_at >= $2`,
+		email, since).Scan(&count)
+	return count, err
+}
+
+// Consume marks an unused, unexpired link as used and returns it. It returns
+// sql.ErrNoRows if there is no such link.
+func (r *MagicLinkRepository) Consume(ctx context.Context, tokenHash string, at time.Time) (*models.MagicLink, error) {
+	link := &models.MagicLink{}
+	err := r.db.WithTx(ctx, func(ctx context.Context) error {
+		result, err := r.db.ExecContext(ctx, `
+			UPDATE shares_alert_magic_links SET used_at = $1
+			WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1
+		`, at, tokenHash)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return sql.ErrNoRows
+		}
+
+		return r.db.QueryRowContext(ctx, `
+			SELECT id, email, token_hash, created_at, expires_at, used_at
+			FROM shares_alert_magic_links WHERE token_hash = $1
+		`, tokenHash).Scan(&link.ID, &link.Email, &link.TokenHash, &link.CreatedAt, &link.ExpiresAt, &link.UsedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
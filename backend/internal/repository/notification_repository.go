@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type NotificationRepository struct {
+	db *database.DB
+}
+
+func NewNotificationRepository(db *database.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func insertNotification(ctx context.Context, ex execer, n *models.Notification) error {
+	query := `
+		INSERT INTO shares_alert_notifications (id, user_id, kind, title, message, alert_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := ex.ExecContext(ctx, query, n.ID, n.UserID, n.Kind, n.Title, n.Message, n.AlertID, n.CreatedAt)
+	return err
+}
+
+func (r *NotificationRepository) Create(ctx context.Context, n *models.Notification) error {
+	return insertNotification(ctx, r.db, n)
+}
+
+// ListByUserID returns up to limit notifications older than the (beforeCreatedAt, beforeID)
+// position, newest first. Pass a nil beforeCreatedAt to start from the newest.
+func (r *NotificationRepository) ListByUserID(ctx context.Context, userID string, beforeCreatedAt *time.Time, beforeID string, unreadOnly bool, limit int) ([]*models.Notification, error) {
+	query := `
+		SELECT id, user_id, kind, title, message, alert_id, read_at, created_at
+		FROM shares_alert_notifications WHERE user_id = $1
+	`
+	args := []interface{}{userID}
+
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	if beforeCreatedAt != nil {
+		query += " AND (created_at < $2 OR (created_at = $2 AND id < $3))"
+		args = append(args, *beforeCreatedAt, beforeID)
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		n := &models.Notification{}
+		err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Title, &n.Message, &n.AlertID, &n.ReadAt, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// GetByUserID returns every notification in the user's inbox, oldest first
+func (r *NotificationRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Notification, error) {
+	query := `
+		SELECT id, user_id, kind, title, message, alert_id, read_at, created_at
+		FROM shares_alert_notifications WHERE user_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		n := &models.Notification{}
+		err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Title, &n.Message, &n.AlertID, &n.ReadAt, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID string) (int, error) {
+	query := `SELECT COUNT(*) FROM shares_alert_notifications WHERE user_id = $1 AND read_at IS NULL`
+	var count int
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications read, returning sql.ErrNoRows if it doesn't exist
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, id string) error {
+	query := `
+		UPDATE shares_alert_notifications SET read_at = COALESCE(read_at, $1)
+		WHERE id = $2 AND user_id = $3
+	`
+	result, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID string) error {
+	query := `UPDATE shares_alert_notifications SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, time.Now().UTC(), userID)
+	return err
+}
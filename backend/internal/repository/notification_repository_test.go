@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestNotificationRepositoryListByUserIDPages(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewNotificationRepository(db)
+	user := createTestUser(t, db, "inbox@example.com")
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		n := &models.Notification{
+			ID:        uuid.New().String(),
+			UserID:    user.ID,
+			Kind:      "alert_triggered",
+			Title:     "Alert",
+			Message:   "Something happened",
+			CreatedAt: testNow.Add(time.Duration(i) * time.Minute),
+		}
+		if err := repo.Create(ctx, n); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		ids = append(ids, n.ID)
+	}
+
+	page, err := repo.ListByUserID(ctx, user.ID, nil, "", false, 2)
+	if err != nil {
+		t.Fatalf("ListByUserID: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != ids[4] || page[1].ID != ids[3] {
+		t.Fatalf("first page is wrong: got %d notifications", len(page))
+	}
+
+	last := page[len(page)-1]
+	page, err = repo.ListByUserID(ctx, user.ID, &last.CreatedAt, last.ID, false, 10)
+	if err != nil {
+		t.Fatalf("ListByUserID after cursor: %v", err)
+	}
+	if len(page) != 3 || page[0].ID != ids[2] || page[2].ID != ids[0] {
+		t.Fatalf("second page is wrong: got %d notifications", len(page))
+	}
+
+	if err := repo.MarkRead(ctx, user.ID, ids[0]); err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+	if err := repo.MarkRead(ctx, user.ID, "missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("MarkRead(missing) error = %v, want sql.ErrNoRows", err)
+	}
+	unread, err := repo.ListByUserID(ctx, user.ID, nil, "", true, 10)
+	if err != nil {
+		t.Fatalf("ListByUserID unread: %v", err)
+	}
+	if len(unread) != 4 {
+		t.Errorf("%d unread notifications, want 4", len(unread))
+	}
+
+	if err := repo.MarkAllRead(ctx, user.ID); err != nil {
+		t.Fatalf("MarkAllRead: %v", err)
+	}
+	count, err := repo.CountUnread(ctx, user.ID)
+	if err != nil || count != 0 {
+		t.Errorf("CountUnread = %d, %v; want 0, nil", count, err)
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type OAuthStateRepository struct {
+	db *database.DB
+}
+
+func NewOAuthStateRepository(db *database.DB) *OAuthStateRepository {
+	return &OAuthStateRepository{db: db}
+}
+
+// Create stores a new login attempt, clearing out abandoned ones as it goes
+func (r *OAuthStateRepository) Create(ctx context.Context, state *models.OAuthState) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_oauth_states WHERE expires_at < $1`, state.CreatedAt); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO shares_alert_oauth_states (state, code_verifier, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := r.db.ExecContext(ctx, query, state.State, state.CodeVerifier, state.CreatedAt, state.ExpiresAt)
+	return err
+}
+
+// Consume looks up a login attempt and deletes it, so each state can only be
+// used once. It returns sql.ErrNoRows if the state is unknown or already used.
+func (r *OAuthStateRepository) Consume(ctx context.Context, state string) (*models.OAuthState, error) {
+	entry := &models.OAuthState{}
+	err := r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			SELECT state, code_verifier, created_at, expires_at
+			FROM shares_alert_oauth_states WHERE state = $1
+		`
+		err := r.db.QueryRowContext(ctx, query, state).Scan(&entry.State, &entry.CodeVerifier, &entry.CreatedAt, &entry.ExpiresAt)
+		if err != nil {
+			return err
+		}
+
+		result, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_oauth_states WHERE state = $1`, state)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			// Consumed by a concurrent callback
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
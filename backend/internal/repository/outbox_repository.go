@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type OutboxRepository struct {
+	db *database.DB
+}
+
+// execer is satisfied by both *database.DB and *database.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func NewOutboxRepository(db *database.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+const outboxColumns = `id, user_id, alert_id, kind, recipient, subject, body, text_body, unsubscribe_url, status,
+	attempts, next_attempt_at, last_error, created_at, updated_at, sent_at`
+
+func insertOutboxMessage(ctx context.Context, ex execer, msg *models.OutboxMessage) error {
+	query := `
+		INSERT INTO shares_alert_notification_outbox (id, user_id, alert_id, kind, recipient,
+			subject, body, text_body, unsubscribe_url, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+	_, err := ex.ExecContext(ctx, query, msg.ID, msg.UserID, msg.AlertID, msg.Kind, msg.Recipient,
+		msg.Subject, msg.Body, msg.TextBody, msg.UnsubscribeURL, msg.Status, msg.Attempts, msg.NextAttemptAt, msg.CreatedAt, msg.UpdatedAt)
+	return err
+}
+
+func scanOutboxMessage(scanner interface{ Scan(...interface{}) error }) (*models.OutboxMessage, error) {
+	msg := &models.OutboxMessage{}
+	err := scanner.Scan(
+		&msg.ID, &msg.UserID, &msg.AlertID, &msg.Kind, &msg.Recipient, &msg.Subject, &msg.Body, &msg.TextBody, &msg.UnsubscribeURL,
+		&msg.Status, &msg.Attempts, &msg.NextAttemptAt, &msg.LastError,
+		&msg.CreatedAt, &msg.UpdatedAt, &msg.SentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (r *OutboxRepository) Create(ctx context.Context, msg *models.OutboxMessage) error {
+	return insertOutboxMessage(ctx, r.db, msg)
+}
+
+func (r *OutboxRepository) GetByID(ctx context.Context, id string) (*models.OutboxMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM shares_alert_notification_outbox WHERE id = $1`
+	return scanOutboxMessage(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetDue returns pending messages whose next attempt is at or before now
+func (r *OutboxRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]*models.OutboxMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM shares_alert_notification_outbox
+		WHERE status = $1 AND next_attempt_at <= $2
+		ORDER BY next_attempt_at ASC LIMIT $3`
+	return r.list(ctx, query, models.OutboxStatusPending, now, limit)
+}
+
+// List returns the most recently updated messages, optionally filtered by status
+func (r *OutboxRepository) List(ctx context.Context, status string, limit int) ([]*models.OutboxMessage, error) {
+	if status == "" {
+		query := `SELECT ` + outboxColumns + ` FROM shares_alert_notification_outbox
+			ORDER BY updated_at DESC LIMIT $1`
+		return r.list(ctx, query, limit)
+	}
+
+	query := `SELECT ` + outboxColumns + ` FROM shares_alert_notification_outbox
+		WHERE status = $1 ORDER BY updated_at DESC LIMIT $2`
+	return r.list(ctx, query, status, limit)
+}
+
+// GetByUserID returns every message queued for the user, oldest first
+func (r *OutboxRepository) GetByUserID(ctx context.Context, userID string) ([]*models.OutboxMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM shares_alert_notification_outbox
+		WHERE user_id = $1 ORDER BY created_at ASC`
+	return r.list(ctx, query, userID)
+}
+
+func (r *OutboxRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.OutboxMessage, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*models.OutboxMessage
+	for rows.Next() {
+		msg, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// Claim moves a pending message to sending so that only one worker delivers it.
+// It reports false if another worker got there first.
+func (r *OutboxRepository) Claim(ctx context.Context, id string) (bool, error) {
+	query := `
+		UPDATE shares_alert_notification_outbox
+		SET status = $1, updated_at = $2
+		WHERE id = $3 AND status = $4
+	`
+	result, err := r.db.ExecContext(ctx, query, models.OutboxStatusSending, time.Now(), id, models.OutboxStatusPending)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// ReleaseStale returns messages stuck in sending (e.g. after a crash) to pending
+func (r *OutboxRepository) ReleaseStale(ctx context.Context, olderThan time.Time) error {
+	query := `
+		UPDATE shares_alert_notification_outbox
+		SET status = $1, updated_at = $2
+		WHERE status = $3 AND updated_at < $4
+	`
+	_, err := r.db.ExecContext(ctx, query, models.OutboxStatusPending, time.Now(), models.OutboxStatusSending, olderThan)
+	return err
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, id string, attempts int, sentAt time.Time) error {
+	query := `
+		UPDATE shares_alert_notification_outbox
+		SET status = $1, attempts = $2, sent_at = $3, updated_at = $4, last_error = NULL
+		WHERE id = $5
+	`
+	_, err := r.db.ExecContext(ctx, query, models.OutboxStatusSent, attempts, sentAt, sentAt, id)
+	return err
+}
+
+// MarkFailed records a failed attempt and either schedules a retry or dead-letters the message
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error {
+	status := models.OutboxStatusPending
+	if dead {
+		status = models.OutboxStatusDead
+	}
+
+	query := `
+		UPDATE shares_alert_notification_outbox
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4, updated_at = $5
+		WHERE id = $6
+	`
+	_, err := r.db.ExecContext(ctx, query, status, attempts, nextAttemptAt, lastError, time.Now(), id)
+	return err
+}
+
+// Replay resets a dead-lettered message so the worker picks it up again on its next pass
+func (r *OutboxRepository) Replay(ctx context.Context, id string) error {
+	now := time.Now()
+	query := `
+		UPDATE shares_alert_notification_outbox
+		SET status = $1, attempts = 0, next_attempt_at = $2, last_error = NULL, updated_at = $3
+		WHERE id = $4 AND status = $5
+	`
+	result, err := r.db.ExecContext(ctx, query, models.OutboxStatusPending, now, now, id, models.OutboxStatusDead)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("outbox message not found or not dead-lettered")
+	}
+	return nil
+}
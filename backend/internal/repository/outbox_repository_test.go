@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+func createTestOutboxMessage(t *testing.T, db *database.DB, userID string, nextAttemptAt time.Time) *models.OutboxMessage {
+	t.Helper()
+	ctx := context.Background()
+
+	msg := &models.OutboxMessage{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Kind:          models.OutboxKindAlert,
+		Recipient:     "someone@example.com",
+		Subject:       "Alert",
+		Body:          "<p>Alert</p>",
+		TextBody:      "Alert",
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: nextAttemptAt,
+		CreatedAt:     testNow,
+		UpdatedAt:     testNow,
+	}
+	if err := NewOutboxRepository(db).Create(ctx, msg); err != nil {
+		t.Fatalf("failed to create outbox message: %v", err)
+	}
+	return msg
+}
+
+func TestOutboxRepositoryDeliveryLifecycle(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewOutboxRepository(db)
+	user := createTestUser(t, db, "outbox@example.com")
+
+	due := createTestOutboxMessage(t, db, user.ID, testNow.Add(-time.Minute))
+	createTestOutboxMessage(t, db, user.ID, testNow.Add(time.Minute))
+
+	messages, err := repo.GetDue(ctx, testNow, 10)
+	if err != nil {
+		t.Fatalf("GetDue: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != due.ID {
+		t.Fatalf("GetDue returned %d messages, want just the due one", len(messages))
+	}
+
+	claimed, err := repo.Claim(ctx, due.ID)
+	if err != nil || !claimed {
+		t.Fatalf("Claim = %v, %v; want true, nil", claimed, err)
+	}
+	claimed, err = repo.Claim(ctx, due.ID)
+	if err != nil || claimed {
+		t.Fatalf("second Claim = %v, %v; want false, nil", claimed, err)
+	}
+
+	if err := repo.MarkFailed(ctx, due.ID, 1, testNow, "smtp down", true); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	dead, err := repo.List(ctx, models.OutboxStatusDead, 10)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(dead) != 1 || dead[0].LastError == nil || *dead[0].LastError != "smtp down" {
+		t.Fatalf("List(dead) returned %d messages", len(dead))
+	}
+
+	if err := repo.Replay(ctx, due.ID); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if err := repo.Replay(ctx, due.ID); err == nil {
+		t.Error("Replay of a pending message succeeded")
+	}
+
+	if err := repo.MarkSent(ctx, due.ID, 2, testNow); err != nil {
+		t.Fatalf("MarkSent: %v", err)
+	}
+	sent, err := repo.GetByID(ctx, due.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if sent.Status != models.OutboxStatusSent || sent.SentAt == nil || sent.LastError != nil || sent.Attempts != 2 {
+		t.Errorf("GetByID returned %+v", sent)
+	}
+}
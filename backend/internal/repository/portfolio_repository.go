@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type PortfolioRepository struct {
+	db *database.DB
+}
+
+func NewPortfolioRepository(db *database.DB) *PortfolioRepository {
+	return &PortfolioRepository{db: db}
+}
+
+const portfolioColumns = `id, user_id, name, cost_basis_method, created_at, updated_at`
+
+func scanPortfolio(scanner interface{ Scan(...interface{}) error }) (*models.Portfolio, error) {
+	portfolio := &models.Portfolio{}
+	err := scanner.Scan(&portfolio.ID, &portfolio.UserID, &portfolio.Name, &portfolio.CostBasisMethod,
+		&portfolio.CreatedAt, &portfolio.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return portfolio, nil
+}
+
+func (r *PortfolioRepository) Create(ctx context.Context, portfolio *models.Portfolio) error {
+	query := `
+		INSERT INTO shares_alert_portfolios (id, user_id, name, cost_basis_method, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query, portfolio.ID, portfolio.UserID, portfolio.Name, portfolio.CostBasisMethod,
+		portfolio.CreatedAt, portfolio.UpdatedAt)
+	return err
+}
+
+func (r *PortfolioRepository) GetByID(ctx context.Context, id string) (*models.Portfolio, error) {
+	query := `SELECT ` + portfolioColumns + ` FROM shares_alert_portfolios WHERE id = $1`
+	return scanPortfolio(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *PortfolioRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Portfolio, error) {
+	query := `SELECT ` + portfolioColumns + ` FROM shares_alert_portfolios WHERE user_id = $1 ORDER BY created_at ASC`
+	return r.listPortfolios(ctx, query, userID)
+}
+
+// GetAll returns every portfolio, for the daily snapshot job
+func (r *PortfolioRepository) GetAll(ctx context.Context) ([]*models.Portfolio, error) {
+	query := `SELECT ` + portfolioColumns + ` FROM shares_alert_portfolios ORDER BY created_at ASC`
+	return r.listPortfolios(ctx, query)
+}
+
+func (r *PortfolioRepository) listPortfolios(ctx context.Context, query string, args ...interface{}) ([]*models.Portfolio, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var portfolios []*models.Portfolio
+	for rows.Next() {
+		portfolio, err := scanPortfolio(rows)
+		if err != nil {
+			return nil, err
+		}
+		portfolios = append(portfolios, portfolio)
+	}
+
+	return portfolios, rows.Err()
+}
+
+func (r *PortfolioRepository) Update(ctx context.Context, portfolio *models.Portfolio) error {
+	query := `UPDATE shares_alert_portfolios SET name = $1, cost_basis_method = $2, updated_at = $3 WHERE id = $4`
+	portfolio.UpdatedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, query, portfolio.Name, portfolio.CostBasisMethod, portfolio.UpdatedAt, portfolio.ID)
+	return err
+}
+
+// Delete removes the portfolio, its ledger, its snapshots and its alerts
+func (r *PortfolioRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_alerts WHERE portfolio_id = $1`, id); err != nil {
+			return err
+		}
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_portfolio_snapshots WHERE portfolio_id = $1`, id); err != nil {
+			return err
+		}
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_portfolio_transactions WHERE portfolio_id = $1`, id); err != nil {
+			return err
+		}
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_portfolios WHERE id = $1`, id); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func insertTransaction(ctx context.Context, ex execer, t *models.Transaction) error {
+	query := `
+		INSERT INTO shares_alert_portfolio_transactions (id, portfolio_id, type, stock_symbol,
+			quantity, price, fees, trade_date, lot_id, notes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err := ex.ExecContext(ctx, query, t.ID, t.PortfolioID, t.Type, t.StockSymbol,
+		t.Quantity, t.Price, t.Fees, t.TradeDate, t.LotID, t.Notes, t.CreatedAt)
+	return err
+}
+
+func (r *PortfolioRepository) CreateTransaction(ctx context.Context, t *models.Transaction) error {
+	return insertTransaction(ctx, r.db, t)
+}
+
+// CreateTransactions records a batch of transactions atomically
+func (r *PortfolioRepository) CreateTransactions(ctx context.Context, transactions []*models.Transaction) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		for _, t := range transactions {
+			if err := insertTransaction(ctx, r.db, t); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetTransactions returns the portfolio's ledger in the order it happened
+func (r *PortfolioRepository) GetTransactions(ctx context.Context, portfolioID string) ([]*models.Transaction, error) {
+	query := `
+		SELECT id, portfolio_id, type, stock_symbol, quantity, price, fees, trade_date, lot_id, notes, created_at
+		FROM shares_alert_portfolio_transactions WHERE portfolio_id = $1
+		ORDER BY trade_date ASC, created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		t := &models.Transaction{}
+		err := rows.Scan(&t.ID, &t.PortfolioID, &t.Type, &t.StockSymbol, &t.Quantity,
+			&t.Price, &t.Fees, &t.TradeDate, &t.LotID, &t.Notes, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+// DeleteTransaction removes one entry from the ledger, returning sql.ErrNoRows
+// if the portfolio has no such transaction
+func (r *PortfolioRepository) DeleteTransaction(ctx context.Context, portfolioID, id string) error {
+	query := `DELETE FROM shares_alert_portfolio_transactions WHERE id = $1 AND portfolio_id = $2`
+	result, err := r.db.ExecContext(ctx, query, id, portfolioID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpsertSnapshot records the portfolio's value for a day, replacing any
+// earlier snapshot of the same day
+func (r *PortfolioRepository) UpsertSnapshot(ctx context.Context, snapshot *models.PortfolioSnapshot) error {
+	query := r.db.Dialect().Upsert("shares_alert_portfolio_snapshots",
+		[]string{"portfolio_id", "snapshot_date", "market_value", "cost_basis", "net_flow", "created_at"},
+		[]string{"portfolio_id", "snapshot_date"},
+		[]string{"market_value", "cost_basis", "net_flow", "created_at"})
+	_, err := r.db.ExecContext(ctx, query, snapshot.PortfolioID, snapshot.SnapshotDate, snapshot.MarketValue,
+		snapshot.CostBasis, snapshot.NetFlow, time.Now())
+	return err
+}
+
+// GetSnapshots returns the portfolio's snapshots from the given date (YYYY-MM-DD) on, oldest first
+func (r *PortfolioRepository) GetSnapshots(ctx context.Context, portfolioID, from string) ([]*models.PortfolioSnapshot, error) {
+	query := `
+		SELECT portfolio_id, snapshot_date, market_value, cost_basis, net_flow
+		FROM shares_alert_portfolio_snapshots WHERE portfolio_id = $1 AND snapshot_date >= $2
+		ORDER BY snapshot_date ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, portfolioID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*models.PortfolioSnapshot
+	for rows.Next() {
+		snapshot := &models.PortfolioSnapshot{}
+		if err := rows.Scan(&snapshot.PortfolioID, &snapshot.SnapshotDate, &snapshot.MarketValue,
+			&snapshot.CostBasis, &snapshot.NetFlow); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+func (r *PortfolioRepository) UpsertMarketSnapshot(ctx context.Context, snapshot *models.MarketSnapshot) error {
+	query := r.db.Dialect().Upsert("shares_alert_market_snapshots",
+		[]string{"snapshot_date", "index_value", "created_at"},
+		[]string{"snapshot_date"},
+		[]string{"index_value", "created_at"})
+	_, err := r.db.ExecContext(ctx, query, snapshot.SnapshotDate, snapshot.IndexValue, time.Now())
+	return err
+}
+
+// GetMarketSnapshot returns the market snapshot for a date, or sql.ErrNoRows
+func (r *PortfolioRepository) GetMarketSnapshot(ctx context.Context, date string) (*models.MarketSnapshot, error) {
+	query := `SELECT snapshot_date, index_value FROM shares_alert_market_snapshots WHERE snapshot_date = $1`
+	snapshot := &models.MarketSnapshot{}
+	if err := r.db.QueryRowContext(ctx, query, date).Scan(&snapshot.SnapshotDate, &snapshot.IndexValue); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetMarketSnapshotBefore returns the latest market snapshot strictly before
+// the date, or sql.ErrNoRows if there is none
+func (r *PortfolioRepository) GetMarketSnapshotBefore(ctx context.Context, date string) (*models.MarketSnapshot, error) {
+	query := `
+		SELECT snapshot_date, index_value FROM shares_alert_market_snapshots
+		WHERE snapshot_date < $1 ORDER BY snapshot_date DESC LIMIT 1
+	`
+	snapshot := &models.MarketSnapshot{}
+	if err := r.db.QueryRowContext(ctx, query, date).Scan(&snapshot.SnapshotDate, &snapshot.IndexValue); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (r *PortfolioRepository) GetMarketSnapshots(ctx context.Context, from string) ([]*models.MarketSnapshot, error) {
+	query := `
+		SELECT snapshot_date, index_value FROM shares_alert_market_snapshots
+		WHERE snapshot_date >= $1 ORDER BY snapshot_date ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []*models.MarketSnapshot
+	for rows.Next() {
+		snapshot := &models.MarketSnapshot{}
+		if err := rows.Scan(&snapshot.SnapshotDate, &snapshot.IndexValue); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
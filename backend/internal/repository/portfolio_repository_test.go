@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestPortfolioRepositoryLedger(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewPortfolioRepository(db)
+	user := createTestUser(t, db, "ledger@example.com")
+
+	portfolio := &models.Portfolio{
+		ID:              uuid.New().String(),
+		UserID:          user.ID,
+		Name:            "Main",
+		CostBasisMethod: models.CostBasisFIFO,
+		CreatedAt:       testNow,
+		UpdatedAt:       testNow,
+	}
+	if err := repo.Create(ctx, portfolio); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	sell := &models.Transaction{
+		ID: uuid.New().String(), PortfolioID: portfolio.ID, Type: models.TransactionTypeSell,
+		StockSymbol: "MTNGH", Quantity: 50, Price: 1.6, TradeDate: testNow, CreatedAt: testNow,
+	}
+	buy := &models.Transaction{
+		ID: uuid.New().String(), PortfolioID: portfolio.ID, Type: models.TransactionTypeBuy,
+		StockSymbol: "MTNGH", Quantity: 100, Price: 1.5, Fees: 2.25, TradeDate: testNow.Add(-48 * time.Hour), CreatedAt: testNow,
+	}
+	if err := repo.CreateTransactions(ctx, []*models.Transaction{sell, buy}); err != nil {
+		t.Fatalf("CreateTransactions: %v", err)
+	}
+
+	transactions, err := repo.GetTransactions(ctx, portfolio.ID)
+	if err != nil {
+		t.Fatalf("GetTransactions: %v", err)
+	}
+	if len(transactions) != 2 || transactions[0].ID != buy.ID || transactions[1].ID != sell.ID {
+		t.Fatalf("GetTransactions returned %d transactions, want them in trade order", len(transactions))
+	}
+	if transactions[0].Fees != 2.25 || !transactions[0].TradeDate.Equal(buy.TradeDate) {
+		t.Errorf("buy read back as %+v", transactions[0])
+	}
+
+	if err := repo.DeleteTransaction(ctx, portfolio.ID, sell.ID); err != nil {
+		t.Fatalf("DeleteTransaction: %v", err)
+	}
+	if err := repo.DeleteTransaction(ctx, portfolio.ID, sell.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second DeleteTransaction error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := repo.Delete(ctx, portfolio.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM shares_alert_portfolio_transactions WHERE portfolio_id = $1`, portfolio.ID); n != 0 {
+		t.Errorf("%d transactions left after Delete, want 0", n)
+	}
+}
+
+func TestPortfolioRepositoryUpsertSnapshots(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewPortfolioRepository(db)
+	user := createTestUser(t, db, "snapshots@example.com")
+	portfolio := createTestPortfolio(t, db, user.ID)
+
+	snapshot := &models.PortfolioSnapshot{PortfolioID: portfolio.ID, SnapshotDate: "2026-03-13", MarketValue: 100, CostBasis: 90}
+	if err := repo.UpsertSnapshot(ctx, snapshot); err != nil {
+		t.Fatalf("UpsertSnapshot: %v", err)
+	}
+	snapshot.MarketValue = 120
+	snapshot.NetFlow = 15
+	if err := repo.UpsertSnapshot(ctx, snapshot); err != nil {
+		t.Fatalf("second UpsertSnapshot: %v", err)
+	}
+
+	snapshots, err := repo.GetSnapshots(ctx, portfolio.ID, "2026-03-01")
+	if err != nil {
+		t.Fatalf("GetSnapshots: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].MarketValue != 120 || snapshots[0].NetFlow != 15 {
+		t.Fatalf("GetSnapshots returned %+v, want one replaced snapshot", snapshots)
+	}
+
+	for _, s := range []*models.MarketSnapshot{
+		{SnapshotDate: "2026-03-12", IndexValue: 100},
+		{SnapshotDate: "2026-03-13", IndexValue: 101},
+		{SnapshotDate: "2026-03-13", IndexValue: 102},
+	} {
+		if err := repo.UpsertMarketSnapshot(ctx, s); err != nil {
+			t.Fatalf("UpsertMarketSnapshot: %v", err)
+		}
+	}
+
+	latest, err := repo.GetMarketSnapshot(ctx, "2026-03-13")
+	if err != nil || latest.IndexValue != 102 {
+		t.Fatalf("GetMarketSnapshot = %+v, %v; want 102", latest, err)
+	}
+	before, err := repo.GetMarketSnapshotBefore(ctx, "2026-03-13")
+	if err != nil || before.SnapshotDate != "2026-03-12" {
+		t.Fatalf("GetMarketSnapshotBefore = %+v, %v; want 2026-03-12", before, err)
+	}
+	if _, err := repo.GetMarketSnapshotBefore(ctx, "2026-03-12"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetMarketSnapshotBefore(first day) error = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+// UnitOfWork runs fn in a transaction, committing it if fn returns nil and
+// rolling it back otherwise. Repository calls made with the context fn is
+// given take part in the transaction, so several of them succeed or fail
+// together. *database.DB implements it.
+type UnitOfWork interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var _ UnitOfWork = (*database.DB)(nil)
+
+// The Store interfaces are what services depend on, so they can be given
+// fakes in place of the database-backed repositories
+
+type AccountStore interface {
+	Purge(ctx context.Context, user *models.User) error
+}
+
+type AlertStore interface {
+	Create(ctx context.Context, alert *models.Alert) error
+	CreateAlerts(ctx context.Context, alerts []*models.Alert) error
+	GetByID(ctx context.Context, id string) (*models.Alert, error)
+	GetByUserID(ctx context.Context, userID string, filter models.AlertFilter) ([]*models.Alert, error)
+	ListByUserID(ctx context.Context, userID string, filter models.AlertFilter, sort []models.AlertSort, after []interface{}, limit int) ([]*models.Alert, error)
+	GetActiveAlerts(ctx context.Context) ([]*models.Alert, error)
+	Update(ctx context.Context, alert *models.Alert) error
+	Delete(ctx context.Context, id string) error
+	UpdateCurrentPrice(ctx context.Context, stockSymbol string, currentPrice float64) error
+	TriggerAlert(ctx context.Context, alertID string) error
+	TriggerAlertWithNotifications(ctx context.Context, alertID string, notification *models.Notification, messages []*models.OutboxMessage) error
+}
+
+type APIKeyStore interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	GetByUserID(ctx context.Context, userID string) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, id, userID string, at time.Time) error
+	UpdateLastUsed(ctx context.Context, id string, at time.Time) error
+}
+
+type DigestStore interface {
+	Create(ctx context.Context, entry *models.DigestEntry) error
+	GetUsersWithPending(ctx context.Context) ([]string, error)
+	GetPendingByUserID(ctx context.Context, userID string) ([]*models.DigestEntry, error)
+	GetByUserID(ctx context.Context, userID string) ([]*models.DigestEntry, error)
+	GetLastSentAt(ctx context.Context, userID string) (*time.Time, error)
+	MarkSentWithMessage(ctx context.Context, ids []string, sentAt time.Time, msg *models.OutboxMessage) (bool, error)
+}
+
+type IdentityStore interface {
+	Create(ctx context.Context, identity *models.Identity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*models.Identity, error)
+	GetByUserID(ctx context.Context, userID string) ([]*models.Identity, error)
+	UpdateEmail(ctx context.Context, id, email string) error
+	Delete(ctx context.Context, id, userID string) error
+}
+
+type LifecycleStore interface {
+	GetUsersWithoutAlerts(ctx context.Context, signedUpBefore time.Time, limit int) ([]string, error)
+	GetDormantUsers(ctx context.Context, inactiveSince time.Time, limit int) ([]string, error)
+	RecordWithMessage(ctx context.Context, entry *models.LifecycleEmail, msg *models.OutboxMessage) (bool, error)
+	GetByUserID(ctx context.Context, userID string) ([]*models.LifecycleEmail, error)
+}
+
+type MagicLinkStore interface {
+	Create(ctx context.Context, link *models.MagicLink) error
+	CountSince(ctx context.Context, email string, since time.Time) (int, error)
+	Consume(ctx context.Context, tokenHash string, at time.Time) (*models.MagicLink, error)
+}
+
+type NotificationStore interface {
+	Create(ctx context.Context, n *models.Notification) error
+	ListByUserID(ctx context.Context, userID string, beforeCreatedAt *time.Time, beforeID string, unreadOnly bool, limit int) ([]*models.Notification, error)
+	GetByUserID(ctx context.Context, userID string) ([]*models.Notification, error)
+	CountUnread(ctx context.Context, userID string) (int, error)
+	MarkRead(ctx context.Context, userID, id string) error
+	MarkAllRead(ctx context.Context, userID string) error
+}
+
+type OAuthStateStore interface {
+	Create(ctx context.Context, state *models.OAuthState) error
+	Consume(ctx context.Context, state string) (*models.OAuthState, error)
+}
+
+type OutboxStore interface {
+	Create(ctx context.Context, msg *models.OutboxMessage) error
+	GetByID(ctx context.Context, id string) (*models.OutboxMessage, error)
+	GetDue(ctx context.Context, now time.Time, limit int) ([]*models.OutboxMessage, error)
+	List(ctx context.Context, status string, limit int) ([]*models.OutboxMessage, error)
+	GetByUserID(ctx context.Context, userID string) ([]*models.OutboxMessage, error)
+	Claim(ctx context.Context, id string) (bool, error)
+	ReleaseStale(ctx context.Context, olderThan time.Time) error
+	MarkSent(ctx context.Context, id string, attempts int, sentAt time.Time) error
+	MarkFailed(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error
+	Replay(ctx context.Context, id string) error
+}
+
+type PortfolioStore interface {
+	Create(ctx context.Context, portfolio *models.Portfolio) error
+	GetByID(ctx context.Context, id string) (*models.Portfolio, error)
+	GetByUserID(ctx context.Context, userID string) ([]*models.Portfolio, error)
+	GetAll(ctx context.Context) ([]*models.Portfolio, error)
+	Update(ctx context.Context, portfolio *models.Portfolio) error
+	Delete(ctx context.Context, id string) error
+	CreateTransaction(ctx context.Context, t *models.Transaction) error
+	CreateTransactions(ctx context.Context, transactions []*models.Transaction) error
+	GetTransactions(ctx context.Context, portfolioID string) ([]*models.Transaction, error)
+	DeleteTransaction(ctx context.Context, portfolioID, id string) error
+	UpsertSnapshot(ctx context.Context, snapshot *models.PortfolioSnapshot) error
+	GetSnapshots(ctx context.Context, portfolioID, from string) ([]*models.PortfolioSnapshot, error)
+	UpsertMarketSnapshot(ctx context.Context, snapshot *models.MarketSnapshot) error
+	GetMarketSnapshot(ctx context.Context, date string) (*models.MarketSnapshot, error)
+	GetMarketSnapshotBefore(ctx context.Context, date string) (*models.MarketSnapshot, error)
+	GetMarketSnapshots(ctx context.Context, from string) ([]*models.MarketSnapshot, error)
+}
+
+type SessionStore interface {
+	CreateWithToken(ctx context.Context, session *models.Session, token *models.RefreshToken) error
+	GetByID(ctx context.Context, id string) (*models.Session, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	Rotate(ctx context.Context, oldTokenID string, next *models.RefreshToken, sessionExpiresAt time.Time) (bool, error)
+	Revoke(ctx context.Context, id string, at time.Time) error
+	RevokeAllForUser(ctx context.Context, userID string, at time.Time) (int64, error)
+}
+
+type StreamTicketStore interface {
+	Create(ctx context.Context, ticket *models.StreamTicket) error
+	Consume(ctx context.Context, ticketHash string) (*models.StreamTicket, error)
+}
+
+type UserStore interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByGoogleID(ctx context.Context, googleID string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	UpdateLastLogin(ctx context.Context, id string, at time.Time) error
+	UpdateRole(ctx context.Context, id, role string) error
+	PromoteByEmail(ctx context.Context, email, role string) (bool, error)
+	ScheduleDeletion(ctx context.Context, id string, at time.Time) error
+	CancelDeletion(ctx context.Context, id string) error
+	GetDueForDeletion(ctx context.Context, now time.Time, limit int) ([]*models.User, error)
+	Delete(ctx context.Context, id string) error
+	CreatePreferences(ctx context.Context, prefs *models.UserPreferences) error
+	GetPreferences(ctx context.Context, userID string) (*models.UserPreferences, error)
+	UpdatePreferences(ctx context.Context, prefs *models.UserPreferences) error
+}
+
+type WatchlistStore interface {
+	Create(ctx context.Context, watchlist *models.Watchlist) error
+	GetByID(ctx context.Context, id string) (*models.Watchlist, error)
+	GetByUserID(ctx context.Context, userID string) ([]*models.Watchlist, error)
+	Update(ctx context.Context, watchlist *models.Watchlist) error
+	Delete(ctx context.Context, id string) error
+	SetPositions(ctx context.Context, userID string, ids []string) error
+	GetItems(ctx context.Context, watchlistID string) ([]*models.WatchlistItem, error)
+	GetItemsByUserID(ctx context.Context, userID string) ([]*models.WatchlistItem, error)
+	AddItem(ctx context.Context, item *models.WatchlistItem) error
+	UpdateItemNotes(ctx context.Context, watchlistID, symbol, notes string) error
+	RemoveItem(ctx context.Context, watchlistID, symbol string) error
+	SetItemPositions(ctx context.Context, watchlistID string, symbols []string) error
+}
+
+var (
+	_ AccountStore      = (*AccountRepository)(nil)
+	_ AlertStore        = (*AlertRepository)(nil)
+	_ APIKeyStore       = (*APIKeyRepository)(nil)
+	_ DigestStore       = (*DigestRepository)(nil)
+	_ IdentityStore     = (*IdentityRepository)(nil)
+	_ LifecycleStore    = (*LifecycleRepository)(nil)
+	_ MagicLinkStore    = (*MagicLinkRepository)(nil)
+	_ NotificationStore = (*NotificationRepository)(nil)
+	_ OAuthStateStore   = (*OAuthStateRepository)(nil)
+	_ OutboxStore       = (*OutboxRepository)(nil)
+	_ PortfolioStore    = (*PortfolioRepository)(nil)
+	_ SessionStore      = (*SessionRepository)(nil)
+	_ StreamTicketStore = (*StreamTicketRepository)(nil)
+	_ UserStore         = (*UserRepository)(nil)
+	_ WatchlistStore    = (*WatchlistRepository)(nil)
+)
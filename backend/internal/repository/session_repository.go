@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type SessionRepository struct {
+	db *database.DB
+}
+
+func NewSessionRepository(db *database.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func insertRefreshToken(ctx context.Context, ex execer, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO shares_alert_refresh_tokens (id, session_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := ex.ExecContext(ctx, query, token.ID, token.SessionID, token.TokenHash, token.CreatedAt, token.ExpiresAt)
+	return err
+}
+
+// CreateWithToken stores a new session together with its first refresh token
+func (r *SessionRepository) CreateWithToken(ctx context.Context, session *models.Session, token *models.RefreshToken) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO shares_alert_sessions (id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`
+		if _, err := r.db.ExecContext(ctx, query, session.ID, session.UserID, session.UserAgent, session.IPAddress,
+			session.CreatedAt, session.LastSeenAt, session.ExpiresAt); err != nil {
+			return err
+		}
+		if err := insertRefreshToken(ctx, r.db, token); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (r *SessionRepository) GetByID(ctx context.Context, id string) (*models.Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at
+		FROM shares_alert_sessions WHERE id = $1
+	`
+	session := &models.Session{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *SessionRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, session_id, token_hash, created_at, expires_at, used_at
+		FROM shares_alert_refresh_tokens WHERE token_hash = $1
+	`
+	token := &models.RefreshToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.SessionID, &token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Rotate spends a refresh token and issues its replacement, extending the
+// session. It returns false, changing nothing, if the old token was already
+// spent, which means it has been presented twice.
+func (r *SessionRepository) Rotate(ctx context.Context, oldTokenID string, next *models.RefreshToken, sessionExpiresAt time.Time) (bool, error) {
+	rotated := false
+	err := r.db.WithTx(ctx, func(ctx context.Context) error {
+		result, err := r.db.ExecContext(ctx, `UPDATE shares_alert_refresh_tokens SET used_at = $1 WHERE id = $2 AND used_at IS NULL`,
+			next.CreatedAt, oldTokenID)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+
+		if err := insertRefreshToken(ctx, r.db, next); err != nil {
+			return err
+		}
+		if _, err := r.db.ExecContext(ctx, `UPDATE shares_alert_sessions SET last_seen_at = $1, expires_at = $2 WHERE id = $3`,
+			next.CreatedAt, sessionExpiresAt, next.SessionID); err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return rotated, nil
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE shares_alert_sessions SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, at, id)
+	return err
+}
+
+// RevokeAllForUser revokes every active session the user has, returning how many
+func (r *SessionRepository) RevokeAllForUser(ctx context.Context, userID string, at time.Time) (int64, error) {
+	query := `UPDATE shares_alert_sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, at, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestSessionRepositoryRotate(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewSessionRepository(db)
+	user := createTestUser(t, db, "session@example.com")
+
+	session := &models.Session{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		UserAgent:  "test",
+		CreatedAt:  testNow,
+		LastSeenAt: testNow,
+		ExpiresAt:  testNow.Add(24 * time.Hour),
+	}
+	first := &models.RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: session.ID,
+		TokenHash: "hash-1",
+		CreatedAt: testNow,
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := repo.CreateWithToken(ctx, session, first); err != nil {
+		t.Fatalf("CreateWithToken: %v", err)
+	}
+
+	next := &models.RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: session.ID,
+		TokenHash: "hash-2",
+		CreatedAt: testNow.Add(time.Hour),
+		ExpiresAt: testNow.Add(25 * time.Hour),
+	}
+	rotated, err := repo.Rotate(ctx, first.ID, next, next.ExpiresAt)
+	if err != nil || !rotated {
+		t.Fatalf("Rotate = %v, %v; want true, nil", rotated, err)
+	}
+
+	// Presenting the spent token again must not mint another
+	replay := &models.RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: session.ID,
+		TokenHash: "hash-3",
+		CreatedAt: testNow.Add(2 * time.Hour),
+		ExpiresAt: testNow.Add(26 * time.Hour),
+	}
+	rotated, err = repo.Rotate(ctx, first.ID, replay, replay.ExpiresAt)
+	if err != nil || rotated {
+		t.Fatalf("replayed Rotate = %v, %v; want false, nil", rotated, err)
+	}
+
+	got, err := repo.GetByID(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !got.ExpiresAt.Equal(next.ExpiresAt) || !got.LastSeenAt.Equal(next.CreatedAt) {
+		t.Errorf("session not extended: %+v", got)
+	}
+	spent, err := repo.GetRefreshToken(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("GetRefreshToken: %v", err)
+	}
+	if spent.UsedAt == nil {
+		t.Error("first token not marked used")
+	}
+	if _, err := repo.GetRefreshToken(ctx, "hash-3"); err == nil {
+		t.Error("replayed rotation stored its token")
+	}
+}
+
+func TestSessionRepositoryRevokeAllForUser(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewSessionRepository(db)
+	user := createTestUser(t, db, "revoke@example.com")
+
+	for i := 0; i < 2; i++ {
+		session := &models.Session{
+			ID: uuid.New().String(), UserID: user.ID,
+			CreatedAt: testNow, LastSeenAt: testNow, ExpiresAt: testNow.Add(time.Hour),
+		}
+		token := &models.RefreshToken{
+			ID: uuid.New().String(), SessionID: session.ID, TokenHash: uuid.New().String(),
+			CreatedAt: testNow, ExpiresAt: session.ExpiresAt,
+		}
+		if err := repo.CreateWithToken(ctx, session, token); err != nil {
+			t.Fatalf("CreateWithToken: %v", err)
+		}
+	}
+
+	revoked, err := repo.RevokeAllForUser(ctx, user.ID, testNow)
+	if err != nil || revoked != 2 {
+		t.Fatalf("RevokeAllForUser = %d, %v; want 2, nil", revoked, err)
+	}
+	revoked, err = repo.RevokeAllForUser(ctx, user.ID, testNow)
+	if err != nil || revoked != 0 {
+		t.Fatalf("second RevokeAllForUser = %d, %v; want 0, nil", revoked, err)
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type StreamTicketRepository struct {
+	db *database.DB
+}
+
+func NewStreamTicketRepository(db *database.DB) *StreamTicketRepository {
+	return &StreamTicketRepository{db: db}
+}
+
+// Create stores a new ticket, clearing out expired ones as it goes
+func (r *StreamTicketRepository) Create(ctx context.Context, ticket *models.StreamTicket) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_stream_tickets WHERE expires_at < $1`, ticket.CreatedAt); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO shares_alert_stream_tickets (ticket_hash, user_id, session_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.ExecContext(ctx, query, ticket.TicketHash, ticket.UserID, ticket.SessionID, ticket.CreatedAt, ticket.ExpiresAt)
+	return err
+}
+
+// Consume looks up a ticket and deletes it, so each ticket can only be used
+// once. It returns sql.ErrNoRows if the ticket is unknown or already used.
+func (r *StreamTicketRepository) Consume(ctx context.Context, ticketHash string) (*models.StreamTicket, error) {
+	ticket := &models.StreamTicket{}
+	err := r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			SELECT ticket_hash, user_id, session_id, created_at, expires_at
+			FROM shares_alert_stream_tickets WHERE ticket_hash = $1
+		`
+		err := r.db.QueryRowContext(ctx, query, ticketHash).Scan(&ticket.TicketHash, &ticket.UserID, &ticket.SessionID,
+			&ticket.CreatedAt, &ticket.ExpiresAt)
+		if err != nil {
+			return err
+		}
+
+		result, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_stream_tickets WHERE ticket_hash = $1`, ticketHash)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			// Consumed by a concurrent request
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
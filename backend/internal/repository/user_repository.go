@@ -1,39 +1,48 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
+	"shares-alert-backend/internal/database"
 	"shares-alert-backend/internal/models"
 )
 
 type UserRepository struct {
-	db *sql.DB
+	db *database.DB
 }
 
-func NewUserRepository(db *sql.DB) *UserRepository {
+func NewUserRepository(db *database.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) Create(user *models.User) error {
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO shares_alert_users (id, email, name, picture, google_id, email_verified, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO shares_alert_users (id, email, name, picture, google_id, email_verified, created_at, updated_at, role)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
-	_, err := r.db.Exec(query, user.ID, user.Email, user.Name, user.Picture, 
-		user.GoogleID, user.EmailVerified, user.CreatedAt, user.UpdatedAt)
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	// Users who only sign in by email have no Google ID
+	googleID := sql.NullString{String: user.GoogleID, Valid: user.GoogleID != ""}
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Email, user.Name, user.Picture, 
+		googleID, user.EmailVerified, user.CreatedAt, user.UpdatedAt, user.Role)
 	return err
 }
 
-func (r *UserRepository) GetByID(id string) (*models.User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
 	query := `
-		SELECT id, email, name, picture, google_id, email_verified, created_at, updated_at
+		SELECT id, email, name, picture, COALESCE(google_id, ''), email_verified, created_at, updated_at, last_login_at, role,
+			deletion_scheduled_at
 		FROM shares_alert_users WHERE id = $1
 	`
 	user := &models.User{}
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.Email, &user.Name, &user.Picture,
-		&user.GoogleID, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt,
+		&user.GoogleID, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.Role,
+		&user.DeletionScheduledAt,
 	)
 	if err != nil {
 		return nil, err
@@ -41,15 +50,17 @@ func (r *UserRepository) GetByID(id string) (*models.User, error) {
 	return user, nil
 }
 
-func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, name, picture, google_id, email_verified, created_at, updated_at
-		FROM shares_alert_users WHERE email = $1
+		SELECT id, email, name, picture, COALESCE(google_id, ''), email_verified, created_at, updated_at, last_login_at, role,
+			deletion_scheduled_at
+		FROM shares_alert_users WHERE LOWER(email) = LOWER($1) ORDER BY created_at ASC
 	`
 	user := &models.User{}
-	err := r.db.QueryRow(query, email).Scan(
+	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID, &user.Email, &user.Name, &user.Picture,
-		&user.GoogleID, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt,
+		&user.GoogleID, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.Role,
+		&user.DeletionScheduledAt,
 	)
 	if err != nil {
 		return nil, err
@@ -57,15 +68,17 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (r *UserRepository) GetByGoogleID(googleID string) (*models.User, error) {
+func (r *UserRepository) GetByGoogleID(ctx context.Context, googleID string) (*models.User, error) {
 	query := `
-		SELECT id, email, name, picture, google_id, email_verified, created_at, updated_at
+		SELECT id, email, name, picture, COALESCE(google_id, ''), email_verified, created_at, updated_at, last_login_at, role,
+			deletion_scheduled_at
 		FROM shares_alert_users WHERE google_id = $1
 	`
 	user := &models.User{}
-	err := r.db.QueryRow(query, googleID).Scan(
+	err := r.db.QueryRowContext(ctx, query, googleID).Scan(
 		&user.ID, &user.Email, &user.Name, &user.Picture,
-		&user.GoogleID, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt,
+		&user.GoogleID, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.Role,
+		&user.DeletionScheduledAt,
 	)
 	if err != nil {
 		return nil, err
@@ -73,47 +86,128 @@ func (r *UserRepository) GetByGoogleID(googleID string) (*models.User, error) {
 	return user, nil
 }
 
-func (r *UserRepository) Update(user *models.User) error {
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE shares_alert_users 
 		SET email = $1, name = $2, picture = $3, email_verified = $4, updated_at = $5
 		WHERE id = $6
 	`
 	user.UpdatedAt = time.Now()
-	_, err := r.db.Exec(query, user.Email, user.Name, user.Picture, 
+	_, err := r.db.ExecContext(ctx, query, user.Email, user.Name, user.Picture, 
 		user.EmailVerified, user.UpdatedAt, user.ID)
 	return err
 }
 
-func (r *UserRepository) Delete(id string) error {
+// UpdateLastLogin records when the user last signed in
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE shares_alert_users SET last_login_at = $1 WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, at, id)
+	return err
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, id, role string) error {
+	query := `UPDATE shares_alert_users SET role = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, role, time.Now(), id)
+	return err
+}
+
+// PromoteByEmail gives the user with this email the role, returning whether
+// anyone was changed
+func (r *UserRepository) PromoteByEmail(ctx context.Context, email, role string) (bool, error) {
+	query := `UPDATE shares_alert_users SET role = $1, updated_at = $2 WHERE LOWER(email) = LOWER($3) AND role <> $1`
+	result, err := r.db.ExecContext(ctx, query, role, time.Now(), email)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ScheduleDeletion marks the user's account for deletion at the given time
+func (r *UserRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE shares_alert_users SET deletion_scheduled_at = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, at, time.Now(), id)
+	return err
+}
+
+// CancelDeletion clears a pending deletion, returning sql.ErrNoRows if none was scheduled
+func (r *UserRepository) CancelDeletion(ctx context.Context, id string) error {
+	query := `UPDATE shares_alert_users SET deletion_scheduled_at = NULL, updated_at = $1
+		WHERE id = $2 AND deletion_scheduled_at IS NOT NULL`
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetDueForDeletion returns up to limit users whose grace period ended by now
+func (r *UserRepository) GetDueForDeletion(ctx context.Context, now time.Time, limit int) ([]*models.User, error) {
+	query := `
+		SELECT id, email, name, picture, COALESCE(google_id, ''), email_verified, created_at, updated_at, last_login_at, role,
+			deletion_scheduled_at
+		FROM shares_alert_users WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= $1
+		ORDER BY deletion_scheduled_at ASC LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		err := rows.Scan(
+			&user.ID, &user.Email, &user.Name, &user.Picture,
+			&user.GoogleID, &user.EmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.Role,
+			&user.DeletionScheduledAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM shares_alert_users WHERE id = $1`
-	_, err := r.db.Exec(query, id)
+	_, err := r.db.ExecContext(ctx, query, id)
 	return err
 }
 
 // User Preferences methods
-func (r *UserRepository) CreatePreferences(prefs *models.UserPreferences) error {
+func (r *UserRepository) CreatePreferences(ctx context.Context, prefs *models.UserPreferences) error {
 	query := `
 		INSERT INTO shares_alert_user_preferences (id, user_id, email_notifications, push_notifications, 
-			notification_frequency, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+			notification_frequency, timezone, quiet_hours_start, quiet_hours_end, quiet_hours_mode,
+			locale, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
-	_, err := r.db.Exec(query, prefs.ID, prefs.UserID, prefs.EmailNotifications,
-		prefs.PushNotifications, prefs.NotificationFrequency, prefs.CreatedAt, prefs.UpdatedAt)
+	_, err := r.db.ExecContext(ctx, query, prefs.ID, prefs.UserID, prefs.EmailNotifications,
+		prefs.PushNotifications, prefs.NotificationFrequency, prefs.Timezone, prefs.QuietHoursStart,
+		prefs.QuietHoursEnd, prefs.QuietHoursMode, prefs.Locale, prefs.CreatedAt, prefs.UpdatedAt)
 	return err
 }
 
-func (r *UserRepository) GetPreferences(userID string) (*models.UserPreferences, error) {
+func (r *UserRepository) GetPreferences(ctx context.Context, userID string) (*models.UserPreferences, error) {
 	query := `
 		SELECT id, user_id, email_notifications, push_notifications, 
-			notification_frequency, created_at, updated_at
+			notification_frequency, timezone, quiet_hours_start, quiet_hours_end, quiet_hours_mode,
+			locale, created_at, updated_at
 		FROM shares_alert_user_preferences WHERE user_id = $1
 	`
 	prefs := &models.UserPreferences{}
-	err := r.db.QueryRow(query, userID).Scan(
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
 		&prefs.ID, &prefs.UserID, &prefs.EmailNotifications,
 		&prefs.PushNotifications, &prefs.NotificationFrequency,
-		&prefs.CreatedAt, &prefs.UpdatedAt,
+		&prefs.Timezone, &prefs.QuietHoursStart, &prefs.QuietHoursEnd, &prefs.QuietHoursMode,
+		&prefs.Locale, &prefs.CreatedAt, &prefs.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -121,15 +215,23 @@ func (r *UserRepository) GetPreferences(userID string) (*models.UserPreferences,
 	return prefs, nil
 }
 
-func (r *UserRepository) UpdatePreferences(prefs *models.UserPreferences) error {
+func (r *UserRepository) UpdatePreferences(ctx context.Context, prefs *models.UserPreferences) error {
 	query := `
 		UPDATE shares_alert_user_preferences 
 		SET email_notifications = $1, push_notifications = $2, 
-			notification_frequency = $3, updated_at = $4
-		WHERE user_id = $5
+			notification_frequency = $3, timezone = $4, quiet_hours_start = $5,
+			quiet_hours_end = $6, quiet_hours_mode = $7, locale = $8, updated_at = $9
+		WHERE user_id = $10
 	`
 	prefs.UpdatedAt = time.Now()
-	_, err := r.db.Exec(query, prefs.EmailNotifications, prefs.PushNotifications,
-		prefs.NotificationFrequency, prefs.UpdatedAt, prefs.UserID)
-	return err
+	result, err := r.db.ExecContext(ctx, query, prefs.EmailNotifications, prefs.PushNotifications,
+		prefs.NotificationFrequency, prefs.Timezone, prefs.QuietHoursStart,
+		prefs.QuietHoursEnd, prefs.QuietHoursMode, prefs.Locale, prefs.UpdatedAt, prefs.UserID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestUserRepositoryCreateAndGet(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+
+	user := &models.User{
+		ID:            uuid.New().String(),
+		Email:         "ama@example.com",
+		Name:          "Ama",
+		GoogleID:      "google-123",
+		EmailVerified: true,
+		CreatedAt:     testNow,
+		UpdatedAt:     testNow,
+	}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	byID, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if byID.Email != user.Email || byID.GoogleID != "google-123" || !byID.EmailVerified || byID.Role != models.RoleUser {
+		t.Errorf("GetByID returned %+v", byID)
+	}
+	if !byID.CreatedAt.Equal(testNow) {
+		t.Errorf("CreatedAt = %v, want %v", byID.CreatedAt, testNow)
+	}
+
+	if _, err := repo.GetByEmail(ctx, "ama@example.com"); err != nil {
+		t.Errorf("GetByEmail: %v", err)
+	}
+	if _, err := repo.GetByGoogleID(ctx, "google-123"); err != nil {
+		t.Errorf("GetByGoogleID: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, "missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID(missing) error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestUserRepositoryAllowsUsersWithoutGoogleID(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	first := createTestUser(t, db, "first@example.com")
+	createTestUser(t, db, "second@example.com")
+
+	user, err := NewUserRepository(db).GetByID(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if user.GoogleID != "" {
+		t.Errorf("GoogleID = %q, want empty", user.GoogleID)
+	}
+}
+
+func TestUserRepositoryPromoteByEmail(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	user := createTestUser(t, db, "Admin@Example.com")
+
+	promoted, err := repo.PromoteByEmail(ctx, "admin@example.com", models.RoleAdmin)
+	if err != nil || !promoted {
+		t.Fatalf("PromoteByEmail = %v, %v; want true, nil", promoted, err)
+	}
+	promoted, err = repo.PromoteByEmail(ctx, "admin@example.com", models.RoleAdmin)
+	if err != nil || promoted {
+		t.Fatalf("second PromoteByEmail = %v, %v; want false, nil", promoted, err)
+	}
+
+	got, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Role != models.RoleAdmin {
+		t.Errorf("Role = %q, want %q", got.Role, models.RoleAdmin)
+	}
+}
+
+func TestUserRepositoryDeletionSchedule(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	due := createTestUser(t, db, "due@example.com")
+	later := createTestUser(t, db, "later@example.com")
+
+	// Scheduled in another zone, to check timestamps compare by instant
+	zone := time.FixedZone("UTC+5", 5*60*60)
+	if err := repo.ScheduleDeletion(ctx, due.ID, testNow.Add(-time.Hour).In(zone)); err != nil {
+		t.Fatalf("ScheduleDeletion: %v", err)
+	}
+	if err := repo.ScheduleDeletion(ctx, later.ID, testNow.Add(time.Hour)); err != nil {
+		t.Fatalf("ScheduleDeletion: %v", err)
+	}
+
+	users, err := repo.GetDueForDeletion(ctx, testNow, 10)
+	if err != nil {
+		t.Fatalf("GetDueForDeletion: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != due.ID {
+		t.Fatalf("GetDueForDeletion returned %d users, want just %s", len(users), due.Email)
+	}
+	if !users[0].DeletionScheduledAt.Equal(testNow.Add(-time.Hour)) {
+		t.Errorf("DeletionScheduledAt = %v", users[0].DeletionScheduledAt)
+	}
+
+	if err := repo.CancelDeletion(ctx, due.ID); err != nil {
+		t.Fatalf("CancelDeletion: %v", err)
+	}
+	if err := repo.CancelDeletion(ctx, due.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second CancelDeletion error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestUserRepositoryPreferences(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	user := createTestUser(t, db, "prefs@example.com")
+
+	prefs := &models.UserPreferences{
+		ID:                    uuid.New().String(),
+		UserID:                user.ID,
+		EmailNotifications:    true,
+		NotificationFrequency: models.NotificationFrequencyImmediate,
+		QuietHoursMode:        models.QuietHoursModeHold,
+		Locale:                "en",
+		CreatedAt:             testNow,
+		UpdatedAt:             testNow,
+	}
+	if err := repo.CreatePreferences(ctx, prefs); err != nil {
+		t.Fatalf("CreatePreferences: %v", err)
+	}
+
+	prefs.NotificationFrequency = models.NotificationFrequencyDaily
+	prefs.Timezone = "Africa/Accra"
+	if err := repo.UpdatePreferences(ctx, prefs); err != nil {
+		t.Fatalf("UpdatePreferences: %v", err)
+	}
+
+	got, err := repo.GetPreferences(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetPreferences: %v", err)
+	}
+	if got.NotificationFrequency != models.NotificationFrequencyDaily || got.Timezone != "Africa/Accra" || !got.EmailNotifications {
+		t.Errorf("GetPreferences returned %+v", got)
+	}
+
+	missing := &models.UserPreferences{UserID: "missing"}
+	if err := repo.UpdatePreferences(ctx, missing); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdatePreferences(missing) error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestUserRepositoryGetByEmailIgnoresCase(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	user := createTestUser(t, db, "Foo@Example.com")
+
+	for _, email := range []string{"foo@example.com", "FOO@EXAMPLE.COM", "Foo@Example.com"} {
+		got, err := NewUserRepository(db).GetByEmail(ctx, email)
+		if err != nil {
+			t.Fatalf("GetByEmail(%q): %v", email, err)
+		}
+		if got.ID != user.ID {
+			t.Errorf("GetByEmail(%q) returned %s, want %s", email, got.ID, user.ID)
+		}
+	}
+}
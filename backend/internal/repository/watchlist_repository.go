@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+)
+
+type WatchlistRepository struct {
+	db *database.DB
+}
+
+func NewWatchlistRepository(db *database.DB) *WatchlistRepository {
+	return &WatchlistRepository{db: db}
+}
+
+const watchlistColumns = `id, user_id, name, position, created_at, updated_at`
+
+func scanWatchlist(scanner interface{ Scan(...interface{}) error }) (*models.Watchlist, error) {
+	watchlist := &models.Watchlist{}
+	err := scanner.Scan(&watchlist.ID, &watchlist.UserID, &watchlist.Name, &watchlist.Position,
+		&watchlist.CreatedAt, &watchlist.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+func insertWatchlistItem(ctx context.Context, ex execer, item *models.WatchlistItem) error {
+	query := `
+		INSERT INTO shares_alert_watchlist_items (watchlist_id, stock_symbol, position, notes, added_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := ex.ExecContext(ctx, query, item.WatchlistID, item.StockSymbol, item.Position, item.Notes, item.AddedAt)
+	return err
+}
+
+// Create records the watchlist along with its initial items
+func (r *WatchlistRepository) Create(ctx context.Context, watchlist *models.Watchlist) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO shares_alert_watchlists (id, user_id, name, position, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`
+		if _, err := r.db.ExecContext(ctx, query, watchlist.ID, watchlist.UserID, watchlist.Name, watchlist.Position,
+			watchlist.CreatedAt, watchlist.UpdatedAt); err != nil {
+			return err
+		}
+		for _, item := range watchlist.Items {
+			if err := insertWatchlistItem(ctx, r.db, item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *WatchlistRepository) GetByID(ctx context.Context, id string) (*models.Watchlist, error) {
+	query := `SELECT ` + watchlistColumns + ` FROM shares_alert_watchlists WHERE id = $1`
+	return scanWatchlist(r.db.QueryRowContext(ctx, query, id))
+}
+
+// GetByUserID returns the user's watchlists in their chosen order
+func (r *WatchlistRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Watchlist, error) {
+	query := `
+		SELECT ` + watchlistColumns + ` FROM shares_alert_watchlists
+		WHERE user_id = $1 ORDER BY position ASC, created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watchlists []*models.Watchlist
+	for rows.Next() {
+		watchlist, err := scanWatchlist(rows)
+		if err != nil {
+			return nil, err
+		}
+		watchlists = append(watchlists, watchlist)
+	}
+
+	return watchlists, rows.Err()
+}
+
+func (r *WatchlistRepository) Update(ctx context.Context, watchlist *models.Watchlist) error {
+	query := `UPDATE shares_alert_watchlists SET name = $1, updated_at = $2 WHERE id = $3`
+	watchlist.UpdatedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, query, watchlist.Name, watchlist.UpdatedAt, watchlist.ID)
+	return err
+}
+
+// Delete removes the watchlist and its items
+func (r *WatchlistRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_watchlist_items WHERE watchlist_id = $1`, id); err != nil {
+			return err
+		}
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_watchlists WHERE id = $1`, id); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// SetPositions orders the user's watchlists as listed by ids
+func (r *WatchlistRepository) SetPositions(ctx context.Context, userID string, ids []string) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `UPDATE shares_alert_watchlists SET position = $1 WHERE id = $2 AND user_id = $3`
+		for position, id := range ids {
+			if _, err := r.db.ExecContext(ctx, query, position, id, userID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetItems returns the watchlist's symbols in order
+func (r *WatchlistRepository) GetItems(ctx context.Context, watchlistID string) ([]*models.WatchlistItem, error) {
+	query := `
+		SELECT watchlist_id, stock_symbol, position, notes, added_at
+		FROM shares_alert_watchlist_items WHERE watchlist_id = $1
+		ORDER BY position ASC
+	`
+	return r.listItems(ctx, query, watchlistID)
+}
+
+// GetItemsByUserID returns the items on all of the user's watchlists
+func (r *WatchlistRepository) GetItemsByUserID(ctx context.Context, userID string) ([]*models.WatchlistItem, error) {
+	query := `
+		SELECT i.watchlist_id, i.stock_symbol, i.position, i.notes, i.added_at
+		FROM shares_alert_watchlist_items i
+		JOIN shares_alert_watchlists w ON w.id = i.watchlist_id
+		WHERE w.user_id = $1
+		ORDER BY i.watchlist_id, i.position ASC
+	`
+	return r.listItems(ctx, query, userID)
+}
+
+func (r *WatchlistRepository) listItems(ctx context.Context, query string, args ...interface{}) ([]*models.WatchlistItem, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.WatchlistItem
+	for rows.Next() {
+		item := &models.WatchlistItem{}
+		if err := rows.Scan(&item.WatchlistID, &item.StockSymbol, &item.Position, &item.Notes, &item.AddedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// AddItem inserts the item at its position, moving later items down one
+func (r *WatchlistRepository) AddItem(ctx context.Context, item *models.WatchlistItem) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		shift := `UPDATE shares_alert_watchlist_items SET position = position + 1 WHERE watchlist_id = $1 AND position >= $2`
+		if _, err := r.db.ExecContext(ctx, shift, item.WatchlistID, item.Position); err != nil {
+			return err
+		}
+		if err := insertWatchlistItem(ctx, r.db, item); err != nil {
+			return err
+		}
+		if err := touchWatchlist(ctx, r.db, item.WatchlistID); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// UpdateItemNotes replaces an item's notes, returning sql.ErrNoRows if the
+// symbol isn't on the watchlist
+func (r *WatchlistRepository) UpdateItemNotes(ctx context.Context, watchlistID, symbol, notes string) error {
+	query := `UPDATE shares_alert_watchlist_items SET notes = $1 WHERE watchlist_id = $2 AND stock_symbol = $3`
+	result, err := r.db.ExecContext(ctx, query, notes, watchlistID, symbol)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RemoveItem takes the symbol off the watchlist and closes the gap it
+// leaves, returning sql.ErrNoRows if it isn't there
+func (r *WatchlistRepository) RemoveItem(ctx context.Context, watchlistID, symbol string) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		var position int
+		query := `SELECT position FROM shares_alert_watchlist_items WHERE watchlist_id = $1 AND stock_symbol = $2`
+		if err := r.db.QueryRowContext(ctx, query, watchlistID, symbol).Scan(&position); err != nil {
+			return err
+		}
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM shares_alert_watchlist_items WHERE watchlist_id = $1 AND stock_symbol = $2`,
+			watchlistID, symbol); err != nil {
+			return err
+		}
+		shift := `UPDATE shares_alert_watchlist_items SET position = position - 1 WHERE watchlist_id = $1 AND position > $2`
+		if _, err := r.db.ExecContext(ctx, shift, watchlistID, position); err != nil {
+			return err
+		}
+		if err := touchWatchlist(ctx, r.db, watchlistID); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// SetItemPositions orders the watchlist's items as listed by symbols
+func (r *WatchlistRepository) SetItemPositions(ctx context.Context, watchlistID string, symbols []string) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `UPDATE shares_alert_watchlist_items SET position = $1 WHERE watchlist_id = $2 AND stock_symbol = $3`
+		for position, symbol := range symbols {
+			if _, err := r.db.ExecContext(ctx, query, position, watchlistID, symbol); err != nil {
+				return err
+			}
+		}
+		if err := touchWatchlist(ctx, r.db, watchlistID); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func touchWatchlist(ctx context.Context, ex execer, id string) error {
+	_, err := ex.ExecContext(ctx, `UPDATE shares_alert_watchlists SET updated_at = $1 WHERE id = $2`, time.Now(), id)
+	return err
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+func watchlistSymbols(t *testing.T, repo *WatchlistRepository, watchlistID string) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	items, err := repo.GetItems(ctx, watchlistID)
+	if err != nil {
+		t.Fatalf("GetItems: %v", err)
+	}
+	symbols := make([]string, len(items))
+	for i, item := range items {
+		if item.Position != i {
+			t.Errorf("%s is at position %d, want %d", item.StockSymbol, item.Position, i)
+		}
+		symbols[i] = item.StockSymbol
+	}
+	return symbols
+}
+
+func TestWatchlistRepositoryItemOrder(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewWatchlistRepository(db)
+	user := createTestUser(t, db, "watch@example.com")
+
+	watchlist := &models.Watchlist{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Name:      "Banks",
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+	}
+	for i, symbol := range []string{"GCB", "SCB", "CAL"} {
+		watchlist.Items = append(watchlist.Items, &models.WatchlistItem{
+			WatchlistID: watchlist.ID, StockSymbol: symbol, Position: i, AddedAt: testNow,
+		})
+	}
+	if err := repo.Create(ctx, watchlist); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	err := repo.AddItem(ctx, &models.WatchlistItem{WatchlistID: watchlist.ID, StockSymbol: "ACCESS", Position: 1, AddedAt: testNow})
+	if err != nil {
+		t.Fatalf("AddItem: %v", err)
+	}
+	if got := watchlistSymbols(t, repo, watchlist.ID); len(got) != 4 || got[1] != "ACCESS" || got[2] != "SCB" {
+		t.Fatalf("after AddItem the order is %v", got)
+	}
+
+	if err := repo.RemoveItem(ctx, watchlist.ID, "GCB"); err != nil {
+		t.Fatalf("RemoveItem: %v", err)
+	}
+	if err := repo.SetItemPositions(ctx, watchlist.ID, []string{"CAL", "SCB", "ACCESS"}); err != nil {
+		t.Fatalf("SetItemPositions: %v", err)
+	}
+	if got := watchlistSymbols(t, repo, watchlist.ID); len(got) != 3 || got[0] != "CAL" || got[2] != "ACCESS" {
+		t.Fatalf("after SetItemPositions the order is %v", got)
+	}
+
+	if err := repo.Delete(ctx, watchlist.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM shares_alert_watchlist_items WHERE watchlist_id = $1`, watchlist.ID); n != 0 {
+		t.Errorf("%d items left after Delete, want 0", n)
+	}
+}
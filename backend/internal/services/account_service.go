@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+// accountPurgeBatchSize caps how many accounts each purge run deletes
+const accountPurgeBatchSize = 50
+
+var ErrDeletionNotScheduled = errors.New("account is not scheduled for deletion")
+
+// AccountService lets users take their data out and delete their account.
+// Deletion waits out a grace period, during which the user can still sign in
+// and cancel it, before everything is purged.
+type AccountService struct {
+	accountRepo      repository.AccountStore
+	userRepo         repository.UserStore
+	alertRepo        repository.AlertStore
+	digestRepo       repository.DigestStore
+	notificationRepo repository.NotificationStore
+	outboxRepo       repository.OutboxStore
+	lifecycleRepo    repository.LifecycleStore
+	identityRepo     repository.IdentityStore
+	apiKeyRepo       repository.APIKeyStore
+	portfolioRepo    repository.PortfolioStore
+	watchlistRepo    repository.WatchlistStore
+	emailService     *EmailService
+	outboxService    *OutboxService
+	config           *config.AccountConfig
+}
+
+func NewAccountService(
+	accountRepo repository.AccountStore,
+	userRepo repository.UserStore,
+	alertRepo repository.AlertStore,
+	digestRepo repository.DigestStore,
+	notificationRepo repository.NotificationStore,
+	outboxRepo repository.OutboxStore,
+	lifecycleRepo repository.LifecycleStore,
+	identityRepo repository.IdentityStore,
+	apiKeyRepo repository.APIKeyStore,
+	portfolioRepo repository.PortfolioStore,
+	watchlistRepo repository.WatchlistStore,
+	emailService *EmailService,
+	outboxService *OutboxService,
+	cfg *config.AccountConfig,
+) *AccountService {
+	return &AccountService{
+		accountRepo:      accountRepo,
+		userRepo:         userRepo,
+		alertRepo:        alertRepo,
+		digestRepo:       digestRepo,
+		notificationRepo: notificationRepo,
+		outboxRepo:       outboxRepo,
+		lifecycleRepo:    lifecycleRepo,
+		identityRepo:     identityRepo,
+		apiKeyRepo:       apiKeyRepo,
+		portfolioRepo:    portfolioRepo,
+		watchlistRepo:    watchlistRepo,
+		emailService:     emailService,
+		outboxService:    outboxService,
+		config:           cfg,
+	}
+}
+
+// Export gathers everything stored about the user
+func (s *AccountService) Export(ctx context.Context, userID string) (*models.AccountExport, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	export := &models.AccountExport{ExportedAt: time.Now().UTC(), User: user}
+
+	prefs, err := s.userRepo.GetPreferences(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	export.Preferences = prefs
+
+	if export.Identities, err = s.identityRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get identities: %w", err)
+	}
+	if export.APIKeys, err = s.apiKeyRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	if export.Alerts, err = s.alertRepo.GetByUserID(ctx, userID, models.AlertFilter{}); err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", err)
+	}
+	if export.AlertEvents, err = s.digestRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get alert events: %w", err)
+	}
+	if export.Notifications, err = s.notificationRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	if export.Emails, err = s.outboxRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get emails: %w", err)
+	}
+	// The rendered emails hold live signed links, which would act for the
+	// user if the export leaked, so only the delivery record goes out
+	for _, msg := range export.Emails {
+		msg.Body, msg.TextBody, msg.UnsubscribeURL = "", "", ""
+	}
+	if export.Portfolios, err = s.portfolioRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get portfolios: %w", err)
+	}
+	for _, portfolio := range export.Portfolios {
+		transactions, err := s.portfolioRepo.GetTransactions(ctx, portfolio.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get portfolio transactions: %w", err)
+		}
+		export.PortfolioTransactions = append(export.PortfolioTransactions, transactions...)
+	}
+	if export.Watchlists, err = s.watchlistRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get watchlists: %w", err)
+	}
+	items, err := s.watchlistRepo.GetItemsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlist items: %w", err)
+	}
+	for _, watchlist := range export.Watchlists {
+		watchlist.Items = []*models.WatchlistItem{}
+		for _, item := range items {
+			if item.WatchlistID == watchlist.ID {
+				watchlist.Items = append(watchlist.Items, item)
+			}
+		}
+	}
+	if export.LifecycleEmails, err = s.lifecycleRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get lifecycle emails: %w", err)
+	}
+
+	return export, nil
+}
+
+// RequestDeletion schedules the user's account for deletion once the grace
+// period is over and emails them a confirmation. Asking again while a
+// deletion is pending keeps the original date.
+func (s *AccountService) RequestDeletion(ctx context.Context, user *models.User) (time.Time, error) {
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+
+	scheduledAt := time.Now().UTC().AddDate(0, 0, s.config.DeletionGraceDays)
+	if err := s.userRepo.ScheduleDeletion(ctx, user.ID, scheduledAt); err != nil {
+		return time.Time{}, fmt.Errorf("failed to schedule deletion: %w", err)
+	}
+
+	prefs, _ := s.userRepo.GetPreferences(ctx, user.ID)
+	email, err := s.emailService.RenderAccountDeletionEmail(user, scheduledAt, preferenceLocale(prefs))
+	if err != nil {
+		log.Printf("Failed to render deletion email for user %s: %v", user.ID, err)
+	} else if err := s.outboxService.Enqueue(ctx, newOutboxEmail(user, models.OutboxKindAccount, email)); err != nil {
+		log.Printf("Failed to queue deletion email for user %s: %v", user.ID, err)
+	}
+
+	log.Printf("Account %s scheduled for deletion on %s", user.ID, scheduledAt.Format(time.RFC3339))
+	return scheduledAt, nil
+}
+
+// CancelDeletion keeps an account that was scheduled for deletion
+func (s *AccountService) CancelDeletion(ctx context.Context, userID string) error {
+	if err := s.userRepo.CancelDeletion(ctx, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDeletionNotScheduled
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *AccountService) StartPurger() {
+	ticker := time.NewTicker(time.Duration(s.config.PurgeIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	log.Println("Starting account deletion purger...")
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.purgeDue(context.Background(), time.Now().UTC()); err != nil {
+				log.Printf("Error purging deleted accounts: %v", err)
+			}
+		}
+	}
+}
+
+func (s *AccountService) purgeDue(ctx context.Context, now time.Time) error {
+	users, err := s.userRepo.GetDueForDeletion(ctx, now, accountPurgeBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get accounts due for deletion: %w", err)
+	}
+
+	for _, user := range users {
+		if err := s.purge(ctx, user); err != nil {
+			log.Printf("Failed to delete account %s: %v", user.ID, err)
+			continue
+		}
+		log.Printf("Deleted account %s", user.ID)
+	}
+	return nil
+}
+
+// purge removes the user's rows, including any undelivered email. Redis only
+// caches shared stock quotes, so it holds nothing of theirs.
+func (s *AccountService) purge(ctx context.Context, user *models.User) error {
+	return s.accountRepo.Purge(ctx, user)
+}
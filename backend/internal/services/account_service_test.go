@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+func TestExportLeavesOutSignedLinks(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	user := createTestUser(t, db, "export@example.com")
+
+	now := time.Now().UTC()
+	msg := &models.OutboxMessage{
+		ID: uuid.New().String(), UserID: user.ID, Kind: models.OutboxKindAlert, Recipient: user.Email,
+		Subject: "Stock Alert", Body: `<a href="https://api.example.com/api/v1/email-actions?token=secret">Pause</a>`,
+		TextBody:       "Pause: https://api.example.com/api/v1/email-actions?token=secret",
+		UnsubscribeURL: "https://api.example.com/api/v1/email-actions?token=secret",
+		Status:         models.OutboxStatusSent, NextAttemptAt: now, CreatedAt: now, UpdatedAt: now,
+	}
+	if err := repository.NewOutboxRepository(db).Create(ctx, msg); err != nil {
+		t.Fatalf("failed to queue email: %v", err)
+	}
+
+	service := NewAccountService(repository.NewAccountRepository(db), repository.NewUserRepository(db), repository.NewAlertRepository(db),
+		repository.NewDigestRepository(db), repository.NewNotificationRepository(db), repository.NewOutboxRepository(db),
+		repository.NewLifecycleRepository(db), repository.NewIdentityRepository(db), repository.NewAPIKeyRepository(db),
+		repository.NewPortfolioRepository(db), repository.NewWatchlistRepository(db), nil, nil, &config.AccountConfig{})
+
+	export, err := service.Export(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(export.Emails) != 1 {
+		t.Fatalf("Export has %d emails, want 1", len(export.Emails))
+	}
+	got := export.Emails[0]
+	if got.Subject != "Stock Alert" || got.Status != models.OutboxStatusSent {
+		t.Errorf("exported email lost its delivery record: %+v", got)
+	}
+	if got.Body != "" || got.TextBody != "" || got.UnsubscribeURL != "" {
+		t.Errorf("exported email still carries its rendered body or links: %+v", got)
+	}
+}
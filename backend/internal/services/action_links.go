@@ -0,0 +1,105 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"shares-alert-backend/internal/config"
+)
+
+// Actions that can be performed from a signed email link
+const (
+	ActionPause       = "pause"
+	ActionRearm       = "rearm"
+	ActionSnooze      = "snooze"
+	ActionUnsubscribe = "unsubscribe"
+)
+
+var (
+	ErrInvalidActionLink = errors.New("invalid action link")
+	ErrExpiredActionLink = errors.New("action link has expired")
+)
+
+// ActionClaims is what a verified action link authorises
+type ActionClaims struct {
+	Action    string
+	UserID    string
+	AlertID   string // empty for unsubscribe
+	ExpiresAt time.Time
+}
+
+// ActionLinks signs and verifies the links in notification emails. A token is
+// the base64url payload and its HMAC-SHA256, so the handlers that act on them
+// need no session.
+type ActionLinks struct {
+	secret  []byte
+	baseURL string
+	ttl     time.Duration
+}
+
+func NewActionLinks(cfg *config.EmailConfig) *ActionLinks {
+	return &ActionLinks{
+		secret:  []byte(cfg.ActionSecret),
+		baseURL: cfg.PublicURL + "/api/v1/email-actions",
+		ttl:     time.Duration(cfg.ActionLinkTTLHours) * time.Hour,
+	}
+}
+
+// URL returns a signed link that performs action for the user
+func (l *ActionLinks) URL(action, userID, alertID string) string {
+	return l.baseURL + "?token=" + url.QueryEscape(l.Sign(action, userID, alertID, time.Now().Add(l.ttl)))
+}
+
+func (l *ActionLinks) Sign(action, userID, alertID string, expiresAt time.Time) string {
+	payload := strings.Join([]string{action, userID, alertID, strconv.FormatInt(expiresAt.Unix(), 10)}, "\n")
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(l.mac(encoded))
+}
+
+// Verify checks a token's signature and expiry and returns its claims
+func (l *ActionLinks) Verify(token string) (*ActionClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidActionLink
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, l.mac(encoded)) {
+		return nil, ErrInvalidActionLink
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidActionLink
+	}
+	fields := strings.Split(string(payload), "\n")
+	if len(fields) != 4 {
+		return nil, ErrInvalidActionLink
+	}
+	expires, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidActionLink
+	}
+
+	claims := &ActionClaims{
+		Action:    fields[0],
+		UserID:    fields[1],
+		AlertID:   fields[2],
+		ExpiresAt: time.Unix(expires, 0),
+	}
+	if time.Now().After(claims.ExpiresAt) {
+		return nil, ErrExpiredActionLink
+	}
+	return claims, nil
+}
+
+func (l *ActionLinks) mac(data string) []byte {
+	h := hmac.New(sha256.New, l.secret)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
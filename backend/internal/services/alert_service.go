@@ -1,8 +1,11 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,28 +14,52 @@ import (
 	"shares-alert-backend/internal/repository"
 )
 
+const (
+	defaultAlertPageSize = 20
+	maxAlertPageSize     = 100
+	defaultAlertSort     = "-" + models.AlertSortCreatedAt
+)
+
+var ErrInvalidAlertQuery = errors.New("invalid alert query")
+
 type AlertService struct {
-	alertRepo    *repository.AlertRepository
-	userRepo     *repository.UserRepository
-	stockService *StockService
-	emailService *EmailService
+	alertRepo           repository.AlertStore
+	userRepo            repository.UserStore
+	stockService        *StockService
+	emailService        *EmailService
+	digestService       *DigestService
+	notificationService *NotificationService
+	portfolioService    *PortfolioService
+	uow                 repository.UnitOfWork
 }
 
 func NewAlertService(
-	alertRepo *repository.AlertRepository,
-	userRepo *repository.UserRepository,
+	alertRepo repository.AlertStore,
+	userRepo repository.UserStore,
 	stockService *StockService,
 	emailService *EmailService,
+	digestService *DigestService,
+	notificationService *NotificationService,
+	portfolioService *PortfolioService,
+	uow repository.UnitOfWork,
 ) *AlertService {
 	return &AlertService{
-		alertRepo:    alertRepo,
-		userRepo:     userRepo,
-		stockService: stockService,
-		emailService: emailService,
+		alertRepo:           alertRepo,
+		userRepo:            userRepo,
+		stockService:        stockService,
+		emailService:        emailService,
+		digestService:       digestService,
+		notificationService: notificationService,
+		portfolioService:    portfolioService,
+		uow:                 uow,
 	}
 }
 
-func (s *AlertService) CreateAlert(userID string, req *models.CreateAlertRequest) (*models.Alert, error) {
+func (s *AlertService) CreateAlert(ctx context.Context, userID string, req *models.CreateAlertRequest) (*models.Alert, error) {
+	if isPortfolioAlertType(req.AlertType) {
+		return s.createPortfolioAlert(ctx, userID, req)
+	}
+
 	// Validate required fields
 	if req.StockSymbol == "" || req.AlertType == "" {
 		return nil, fmt.Errorf("stockSymbol and alertType are required")
@@ -63,29 +90,134 @@ func (s *AlertService) CreateAlert(userID string, req *models.CreateAlertRequest
 	alert := &models.Alert{
 		ID:             uuid.New().String(),
 		UserID:         userID,
+		Scope:          models.AlertScopeStock,
 		StockSymbol:    req.StockSymbol,
 		StockName:      req.StockName,
 		AlertType:      req.AlertType,
 		ThresholdPrice: req.ThresholdPrice,
 		CurrentPrice:   currentPrice,
 		Status:         models.AlertStatusActive,
+		Urgent:         req.Urgent,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
 
-	if err := s.alertRepo.Create(alert); err != nil {
+	if err := s.alertRepo.Create(ctx, alert); err != nil {
 		return nil, fmt.Errorf("failed to create alert: %w", err)
 	}
 
 	return alert, nil
 }
 
-func (s *AlertService) GetUserAlerts(userID string, filters map[string]interface{}) ([]*models.Alert, error) {
-	return s.alertRepo.GetByUserID(userID, filters)
+// ListAlerts returns a page of the user's alerts that match filter. sort is
+// a comma-separated list of fields, each prefixed with - to sort it
+// descending, and defaults to newest first. cursor is the NextCursor of the
+// previous page, and is only valid with the same sort and filter.
+func (s *AlertService) ListAlerts(ctx context.Context, userID string, filter models.AlertFilter, sort, cursor string, limit int) (*models.AlertPage, error) {
+	if limit <= 0 {
+		limit = defaultAlertPageSize
+	}
+	if limit > maxAlertPageSize {
+		limit = maxAlertPageSize
+	}
+
+	keys, sort, err := parseAlertSort(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var after []interface{}
+	if cursor != "" {
+		if after, err = decodeAlertCursor(cursor, sort, filter, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fetch one extra row to learn whether there is another page
+	alerts, err := s.alertRepo.ListByUserID(ctx, userID, filter, keys, after, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+
+	page := &models.AlertPage{Alerts: alerts}
+	if len(alerts) > limit {
+		page.Alerts = alerts[:limit]
+		page.NextCursor = encodeAlertCursor(sort, filter, keys, page.Alerts[limit-1])
+	}
+	if page.Alerts == nil {
+		page.Alerts = []*models.Alert{}
+	}
+
+	return page, nil
+}
+
+// parseAlertSort reads a sort parameter such as "-triggeredAt,stockName" and
+// returns its keys along with the parameter in canonical form
+func parseAlertSort(sort string) ([]models.AlertSort, string, error) {
+	if strings.TrimSpace(sort) == "" {
+		sort = defaultAlertSort
+	}
+
+	var keys []models.AlertSort
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		key := models.AlertSort{Field: strings.TrimPrefix(field, "-"), Descending: strings.HasPrefix(field, "-")}
+		if !isAlertSortField(key.Field) {
+			return nil, "", fmt.Errorf("%w: cannot sort by %q", ErrInvalidAlertQuery, key.Field)
+		}
+		if seen[key.Field] {
+			return nil, "", fmt.Errorf("%w: %s is sorted by twice", ErrInvalidAlertQuery, key.Field)
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+		fields = append(fields, field)
+	}
+
+	return keys, strings.Join(fields, ","), nil
+}
+
+func isAlertSortField(field string) bool {
+	switch field {
+	case models.AlertSortCreatedAt, models.AlertSortUpdatedAt, models.AlertSortTriggeredAt,
+		models.AlertSortStockSymbol, models.AlertSortStockName, models.AlertSortStatus, models.AlertSortAlertType:
+		return true
+	}
+	return false
+}
+
+func isAlertTimeField(field string) bool {
+	return field == models.AlertSortCreatedAt || field == models.AlertSortUpdatedAt || field == models.AlertSortTriggeredAt
+}
+
+// alertSortValue is the alert's value for a sort field, nil for an alert
+// that has never triggered
+func alertSortValue(alert *models.Alert, field string) interface{} {
+	switch field {
+	case models.AlertSortCreatedAt:
+		return alert.CreatedAt
+	case models.AlertSortUpdatedAt:
+		return alert.UpdatedAt
+	case models.AlertSortTriggeredAt:
+		if alert.TriggeredAt == nil {
+			return nil
+		}
+		return *alert.TriggeredAt
+	case models.AlertSortStockSymbol:
+		return alert.StockSymbol
+	case models.AlertSortStockName:
+		return alert.StockName
+	case models.AlertSortStatus:
+		return alert.Status
+	case models.AlertSortAlertType:
+		return alert.AlertType
+	}
+	return nil
 }
 
-func (s *AlertService) GetAlert(alertID, userID string) (*models.Alert, error) {
-	alert, err := s.alertRepo.GetByID(alertID)
+func (s *AlertService) GetAlert(ctx context.Context, alertID, userID string) (*models.Alert, error) {
+	alert, err := s.alertRepo.GetByID(ctx, alertID)
 	if err != nil {
 		return nil, err
 	}
@@ -98,8 +230,8 @@ func (s *AlertService) GetAlert(alertID, userID string) (*models.Alert, error) {
 	return alert, nil
 }
 
-func (s *AlertService) UpdateAlert(alertID, userID string, req *models.UpdateAlertRequest) (*models.Alert, error) {
-	alert, err := s.GetAlert(alertID, userID)
+func (s *AlertService) UpdateAlert(ctx context.Context, alertID, userID string, req *models.UpdateAlertRequest) (*models.Alert, error) {
+	alert, err := s.GetAlert(ctx, alertID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -111,25 +243,39 @@ func (s *AlertService) UpdateAlert(alertID, userID string, req *models.UpdateAle
 	if req.ThresholdPrice != nil {
 		alert.ThresholdPrice = req.ThresholdPrice
 	}
+	if req.ThresholdPercent != nil {
+		alert.ThresholdPercent = req.ThresholdPercent
+	}
+	if req.Direction != nil {
+		alert.Direction = *req.Direction
+	}
 	if req.Status != nil {
 		alert.Status = *req.Status
 	}
+	if req.Urgent != nil {
+		alert.Urgent = *req.Urgent
+	}
+	if alert.Scope == models.AlertScopePortfolio {
+		if err := validatePortfolioAlert(alert); err != nil {
+			return nil, err
+		}
+	}
 
-	if err := s.alertRepo.Update(alert); err != nil {
+	if err := s.alertRepo.Update(ctx, alert); err != nil {
 		return nil, fmt.Errorf("failed to update alert: %w", err)
 	}
 
 	// Fetch updated alert
-	return s.alertRepo.GetByID(alertID)
+	return s.alertRepo.GetByID(ctx, alertID)
 }
 
-func (s *AlertService) DeleteAlert(alertID, userID string) error {
-	alert, err := s.GetAlert(alertID, userID)
+func (s *AlertService) DeleteAlert(ctx context.Context, alertID, userID string) error {
+	alert, err := s.GetAlert(ctx, alertID, userID)
 	if err != nil {
 		return err
 	}
 
-	return s.alertRepo.Delete(alert.ID)
+	return s.alertRepo.Delete(ctx, alert.ID)
 }
 
 func (s *AlertService) StartMonitoring() {
@@ -141,21 +287,21 @@ func (s *AlertService) StartMonitoring() {
 	for {
 		select {
 		case <-ticker.C:
-			if err := s.checkAlerts(); err != nil {
+			if err := s.checkAlerts(context.Background()); err != nil {
 				log.Printf("Error checking alerts: %v", err)
 			}
 		}
 	}
 }
 
-func (s *AlertService) checkAlerts() error {
-	alerts, err := s.alertRepo.GetActiveAlerts()
+func (s *AlertService) checkAlerts(ctx context.Context) error {
+	alerts, err := s.alertRepo.GetActiveAlerts(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get active alerts: %w", err)
 	}
 
 	for _, alert := range alerts {
-		if err := s.processAlert(alert); err != nil {
+		if err := s.processAlert(ctx, alert); err != nil {
 			log.Printf("Error processing alert %s: %v", alert.ID, err)
 		}
 	}
@@ -163,7 +309,16 @@ func (s *AlertService) checkAlerts() error {
 	return nil
 }
 
-func (s *AlertService) processAlert(alert *models.Alert) error {
+func (s *AlertService) processAlert(ctx context.Context, alert *models.Alert) error {
+	// Snoozed alerts aren't checked until the snooze runs out
+	if alert.SnoozedUntil != nil && time.Now().Before(*alert.SnoozedUntil) {
+		return nil
+	}
+
+	if alert.Scope == models.AlertScopePortfolio {
+		return s.processPortfolioAlert(ctx, alert)
+	}
+
 	// Only process price threshold alerts for now
 	if alert.AlertType != models.AlertTypePriceThreshold {
 		return nil
@@ -177,47 +332,96 @@ func (s *AlertService) processAlert(alert *models.Alert) error {
 
 	// Update current price in alert
 	alert.CurrentPrice = &stock.CurrentPrice
-	if err := s.alertRepo.Update(alert); err != nil {
+	if err := s.alertRepo.Update(ctx, alert); err != nil {
 		log.Printf("Failed to update current price for alert %s: %v", alert.ID, err)
 	}
 
 	// Check if threshold is met
 	if alert.ThresholdPrice != nil && stock.CurrentPrice >= *alert.ThresholdPrice {
-		return s.triggerAlert(alert, stock.CurrentPrice)
+		return s.triggerAlert(ctx, alert, stock.CurrentPrice)
 	}
 
 	return nil
 }
 
-func (s *AlertService) triggerAlert(alert *models.Alert, currentPrice float64) error {
-	// Update alert status to triggered
-	if err := s.alertRepo.TriggerAlert(alert.ID); err != nil {
-		return fmt.Errorf("failed to trigger alert: %w", err)
+func (s *AlertService) triggerAlert(ctx context.Context, alert *models.Alert, currentPrice float64) error {
+	notification := newAlertNotification(alert, currentPrice)
+	render := func(user *models.User, locale string) (*RenderedEmail, error) {
+		return s.emailService.RenderAlertEmail(user, alert, locale)
+	}
+	if err := s.dispatchTrigger(ctx, alert, currentPrice, notification, render); err != nil {
+		return err
 	}
 
 	log.Printf("Alert triggered for %s: Price %.2f reached threshold %.2f",
 		alert.StockSymbol, currentPrice, *alert.ThresholdPrice)
 
-	// Get user for notification
-	user, err := s.userRepo.GetByID(alert.UserID)
+	return nil
+}
+
+// dispatchTrigger marks the alert triggered and notifies the user: in the
+// inbox always, and by email now, after quiet hours or in their digest,
+// depending on their preferences. render builds the email for the user's locale.
+func (s *AlertService) dispatchTrigger(ctx context.Context, alert *models.Alert, currentPrice float64, notification *models.Notification,
+	render func(user *models.User, locale string) (*RenderedEmail, error)) error {
+	// Work out who to notify and how before touching the alert, so the
+	// notification can be queued in the same transaction as the trigger
+	var messages []*models.OutboxMessage
+	queueForDigest := false
+
+	user, err := s.userRepo.GetByID(ctx, alert.UserID)
 	if err != nil {
 		log.Printf("Failed to get user for alert notification: %v", err)
-		return nil // Don't fail the alert trigger if we can't send notification
-	}
+	} else {
+		// Check user preferences
+		prefs, err := s.userRepo.GetPreferences(ctx, user.ID)
+		if err != nil {
+			log.Printf("Failed to get user preferences, assuming defaults: %v", err)
+			// Assume email notifications are enabled by default
+		}
 
-	// Check user preferences
-	prefs, err := s.userRepo.GetPreferences(user.ID)
-	if err != nil {
-		log.Printf("Failed to get user preferences, assuming defaults: %v", err)
-		// Assume email notifications are enabled by default
+		if prefs == nil || prefs.EmailNotifications {
+			// Urgent alerts ignore quiet hours entirely
+			quietEnd, inQuiet := s.digestService.QuietHoursEnd(prefs, time.Now())
+			inQuiet = inQuiet && !alert.Urgent
+
+			if prefs != nil && (prefs.NotificationFrequency == models.NotificationFrequencyDaily ||
+				prefs.NotificationFrequency == models.NotificationFrequencyWeekly) {
+				// Digest users get the trigger rolled into their next daily/weekly email
+				queueForDigest = true
+			} else if inQuiet && prefs.QuietHoursMode == models.QuietHoursModeSummary {
+				// Folded into a summary sent when quiet hours end
+				queueForDigest = true
+			} else if email, err := render(user, preferenceLocale(prefs)); err != nil {
+				log.Printf("Failed to render alert email: %v", err)
+			} else {
+				msg := newOutboxEmail(user, models.OutboxKindAlert, email)
+				msg.AlertID = &alert.ID
+				if inQuiet {
+					// Held until the quiet window ends
+					msg.NextAttemptAt = quietEnd
+				}
+				messages = append(messages, msg)
+			}
+		}
 	}
 
-	// Send email notification if enabled
-	if prefs == nil || prefs.EmailNotifications {
-		if err := s.emailService.SendAlertEmail(user, alert); err != nil {
-			log.Printf("Failed to send alert email: %v", err)
+	// Update alert status to triggered and record its notifications, and any
+	// digest entry, atomically; every trigger lands in the user's in-app inbox
+	// regardless of email settings
+	err = s.uow.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.alertRepo.TriggerAlertWithNotifications(ctx, alert.ID, notification, messages); err != nil {
+			return fmt.Errorf("failed to trigger alert: %w", err)
+		}
+		if queueForDigest {
+			return s.digestService.Enqueue(ctx, alert, currentPrice)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.notificationService.Publish(notification)
 
 	return nil
-}
\ No newline at end of file
+}
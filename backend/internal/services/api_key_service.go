@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+const (
+	// apiKeyPrefix marks our keys so they are easy to spot in code and by secret scanners
+	apiKeyPrefix = "sak_"
+
+	defaultAPIKeyDays = 90
+	maxAPIKeyDays     = 365
+
+	// lastUsedPrecision limits how often a busy key's last-used time is written
+	lastUsedPrecision = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+var validAPIKeyScopes = map[string]bool{
+	models.ScopeReadStocks:  true,
+	models.ScopeReadAlerts:  true,
+	models.ScopeWriteAlerts: true,
+}
+
+type APIKeyService struct {
+	apiKeyRepo repository.APIKeyStore
+	userRepo   repository.UserStore
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyStore, userRepo repository.UserStore) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// Create issues a new key. The plaintext key is returned only here.
+func (s *APIKeyService) Create(ctx context.Context, userID string, req *models.CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", fmt.Errorf("name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required")
+	}
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !validAPIKeyScopes[scope] {
+			return nil, "", fmt.Errorf("invalid scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	days := defaultAPIKeyDays
+	if req.ExpiresInDays != nil {
+		days = *req.ExpiresInDays
+	}
+	if days < 1 || days > maxAPIKeyDays {
+		return nil, "", fmt.Errorf("expiresInDays must be between 1 and %d", maxAPIKeyDays)
+	}
+
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	plaintext := apiKeyPrefix + secret
+
+	now := time.Now().UTC()
+	key := &models.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plaintext[:len(apiKeyPrefix)+6],
+		KeyHash:   hashToken(plaintext),
+		Scopes:    scopes,
+		ExpiresAt: now.AddDate(0, 0, days),
+		CreatedAt: now,
+	}
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return key, plaintext, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	return s.apiKeyRepo.GetByUserID(ctx, userID)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, userID, id string) error {
+	err := s.apiKeyRepo.Revoke(ctx, id, userID, time.Now().UTC())
+	if err == sql.ErrNoRows {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// Authenticate resolves a plaintext key to its owner and records its use
+func (s *APIKeyService) Authenticate(ctx context.Context, plaintext string) (*models.User, *models.APIKey, error) {
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.GetByHash(ctx, hashToken(plaintext))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	if key.RevokedAt != nil || now.After(key.ExpiresAt) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.GetByID(ctx, key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedPrecision {
+		if err := s.apiKeyRepo.UpdateLastUsed(ctx, key.ID, now); err != nil {
+			fmt.Printf("Failed to record API key use: %v\n", err)
+		}
+		key.LastUsedAt = &now
+	}
+
+	return user, key, nil
+}
@@ -2,8 +2,14 @@ package services
 
 import (
 	"context"
+	"crypto/subtle"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -16,10 +22,52 @@ import (
 	"shares-alert-backend/internal/repository"
 )
 
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+	ErrSessionRevoked      = errors.New("session has been revoked or has expired")
+
+	ErrMissingOAuthState  = errors.New("state is required")
+	ErrInvalidOAuthState  = errors.New("unknown or already used state, please start the login again")
+	ErrExpiredOAuthState  = errors.New("login attempt has expired, please start the login again")
+	ErrOAuthStateMismatch = errors.New("login was started in another browser, please start the login again")
+
+	ErrInvalidEmail     = errors.New("a valid email address is required")
+	ErrInvalidMagicLink = errors.New("sign-in link is invalid, expired or already used")
+	ErrIdentityInUse    = errors.New("that account is already linked to another user")
+	ErrIdentityNotFound = errors.New("identity not found, or it is the only way to sign in")
+
+	ErrInvalidStreamTicket = errors.New("stream ticket is invalid, expired or already used")
+)
+
+const (
+	// magicLinkRateLimit caps the links sent to one address per magicLinkRateWindow
+	magicLinkRateLimit  = 5
+	magicLinkRateWindow = 15 * time.Minute
+
+	// streamTicketTTL is how long a client has to open the stream with a ticket
+	streamTicketTTL = 30 * time.Second
+
+	// OAuthStateCookieName is the cookie that ties a login attempt to the
+	// browser that started it. Its path covers the login callback and the
+	// link endpoint.
+	OAuthStateCookieName = "oauth_state"
+	oauthStateCookiePath = "/api/v1/auth"
+)
+
 type AuthService struct {
-	userRepo     *repository.UserRepository
-	config       *config.AuthConfig
-	googleConfig *oauth2.Config
+	userRepo         repository.UserStore
+	sessionRepo      repository.SessionStore
+	oauthStateRepo   repository.OAuthStateStore
+	identityRepo     repository.IdentityStore
+	magicLinkRepo    repository.MagicLinkStore
+	streamTicketRepo repository.StreamTicketStore
+	uow              repository.UnitOfWork
+	lifecycleService *LifecycleService
+	emailService     *EmailService
+	keys             *JWTKeySet
+	config           *config.AuthConfig
+	googleConfig     *oauth2.Config
 }
 
 type GoogleUserInfo struct {
@@ -31,13 +79,30 @@ type GoogleUserInfo struct {
 }
 
 type JWTClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func NewAuthService(userRepo *repository.UserRepository, cfg *config.AuthConfig) *AuthService {
-	fmt.Printf("DEBUG: AuthConfig RedirectURL: %s\n", cfg.RedirectURL)
+// TokenPair is what a client receives on login or refresh. The access token
+// is a short-lived JWT; the refresh token is opaque and single-use.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int // access token lifetime in seconds
+}
+
+// ClientInfo describes the device a session was started from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+func NewAuthService(userRepo repository.UserStore, sessionRepo repository.SessionStore, oauthStateRepo repository.OAuthStateStore,
+	identityRepo repository.IdentityStore, magicLinkRepo repository.MagicLinkStore, streamTicketRepo repository.StreamTicketStore, uow repository.UnitOfWork,
+	lifecycleService *LifecycleService, emailService *EmailService, keys *JWTKeySet, cfg *config.AuthConfig) *AuthService {
 	googleConfig := &oauth2.Config{
 		ClientID:     cfg.GoogleClientID,
 		ClientSecret: cfg.GoogleClientSecret,
@@ -50,56 +115,359 @@ func NewAuthService(userRepo *repository.UserRepository, cfg *config.AuthConfig)
 	}
 
 	return &AuthService{
-		userRepo:     userRepo,
-		config:       cfg,
-		googleConfig: googleConfig,
+		userRepo:         userRepo,
+		sessionRepo:      sessionRepo,
+		oauthStateRepo:   oauthStateRepo,
+		identityRepo:     identityRepo,
+		magicLinkRepo:    magicLinkRepo,
+		streamTicketRepo: streamTicketRepo,
+		uow:              uow,
+		lifecycleService: lifecycleService,
+		emailService:     emailService,
+		keys:             keys,
+		config:           cfg,
+		googleConfig:     googleConfig,
+	}
+}
+
+// GetGoogleAuthURL starts a login attempt: it generates a random state and a
+// PKCE code verifier, keeps both server-side, and returns the consent URL
+// along with the state the callback must present.
+func (s *AuthService) GetGoogleAuthURL(ctx context.Context) (string, string, error) {
+	state, err := newOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate state: %w", err)
+	}
+
+	now := time.Now().UTC()
+	entry := &models.OAuthState{
+		State:        state,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(time.Duration(s.config.OAuthStateMinutes) * time.Minute),
+	}
+	if err := s.oauthStateRepo.Create(ctx, entry); err != nil {
+		return "", "", fmt.Errorf("failed to store state: %w", err)
+	}
+
+	authURL := s.googleConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(entry.CodeVerifier))
+	return authURL, state, nil
+}
+
+// OAuthStateCookie is set alongside a new login attempt. It holds a hash of
+// the state, so a code and state replayed from another browser (login CSRF)
+// are turned away at the callback. A frontend on another site needs
+// OAUTH_COOKIE_SAMESITE=none, or the browser won't send it back.
+func (s *AuthService) OAuthStateCookie(state string) *http.Cookie {
+	return s.oauthStateCookie(hashToken(state), int(time.Duration(s.config.OAuthStateMinutes)*time.Minute/time.Second))
+}
+
+// ExpiredOAuthStateCookie clears the cookie set by OAuthStateCookie
+func (s *AuthService) ExpiredOAuthStateCookie() *http.Cookie {
+	return s.oauthStateCookie("", -1)
+}
+
+func (s *AuthService) oauthStateCookie(value string, maxAge int) *http.Cookie {
+	sameSite := http.SameSiteLaxMode
+	if strings.EqualFold(s.config.OAuthCookieSameSite, "none") {
+		sameSite = http.SameSiteNoneMode
+	}
+	return &http.Cookie{
+		Name:     OAuthStateCookieName,
+		Value:    value,
+		Path:     oauthStateCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		// An https frontend can only call an https API, and SameSite=None
+		// cookies must be Secure
+		Secure:   sameSite == http.SameSiteNoneMode || strings.HasPrefix(s.config.RedirectURL, "https://"),
+		SameSite: sameSite,
 	}
 }
 
-func (s *AuthService) GetGoogleAuthURL(state string) string {
-	authURL := s.googleConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
-	fmt.Printf("DEBUG: Google OAuth Config RedirectURL: %s\n", s.googleConfig.RedirectURL)
-	fmt.Printf("DEBUG: Generated Auth URL: %s\n", authURL)
-	return authURL
+// consumeOAuthState checks the state returned to the callback, and the
+// cookie set when it was issued, and hands back the PKCE verifier for its
+// login attempt. Each state works once.
+func (s *AuthService) consumeOAuthState(ctx context.Context, state, stateCookie string) (string, error) {
+	if state == "" {
+		return "", ErrMissingOAuthState
+	}
+	// Checked before the state is used up, so the browser that started the
+	// login can still finish it
+	if subtle.ConstantTimeCompare([]byte(hashToken(state)), []byte(stateCookie)) != 1 {
+		return "", ErrOAuthStateMismatch
+	}
+
+	entry, err := s.oauthStateRepo.Consume(ctx, state)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrInvalidOAuthState
+		}
+		return "", fmt.Errorf("failed to look up state: %w", err)
+	}
+	if time.Now().UTC().After(entry.ExpiresAt) {
+		return "", ErrExpiredOAuthState
+	}
+
+	return entry.CodeVerifier, nil
 }
 
-func (s *AuthService) HandleGoogleCallback(code string) (*models.User, string, error) {
+func (s *AuthService) HandleGoogleCallback(ctx context.Context, code, state, stateCookie string, clientInfo ClientInfo) (*models.User, *TokenPair, error) {
+	googleUser, err := s.fetchGoogleUser(ctx, code, state, stateCookie)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check if user exists
+	var user *models.User
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, models.IdentityProviderGoogle, googleUser.ID)
+	switch {
+	case err == nil:
+		if user, err = s.userRepo.GetByID(ctx, identity.UserID); err != nil {
+			return nil, nil, fmt.Errorf("failed to get user: %w", err)
+		}
+
+		// Refresh the profile, but keep the account's own email: magic links
+		// and identities are keyed on it. The address Google reports now is
+		// recorded on the identity instead.
+		user.Name = googleUser.Name
+		user.Picture = googleUser.Picture
+		if googleUser.VerifiedEmail && strings.EqualFold(googleUser.Email, user.Email) {
+			user.EmailVerified = true
+		}
+		user.UpdatedAt = time.Now()
+
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, nil, fmt.Errorf("failed to update user: %w", err)
+		}
+		if identity.Email != googleUser.Email {
+			if err := s.identityRepo.UpdateEmail(ctx, identity.ID, googleUser.Email); err != nil {
+				return nil, nil, fmt.Errorf("failed to update identity: %w", err)
+			}
+		}
+	case err != sql.ErrNoRows:
+		return nil, nil, fmt.Errorf("failed to look up identity: %w", err)
+	default:
+		// An account that signs in by email with the same, Google-verified,
+		// address is the same person, so link to it rather than duplicate it
+		if googleUser.VerifiedEmail {
+			user, _ = s.userRepo.GetByEmail(ctx, normalizeEmail(googleUser.Email))
+		}
+		if user == nil {
+			// User doesn't exist, create new user
+			user = &models.User{
+				ID:            uuid.New().String(),
+				Email:         normalizeEmail(googleUser.Email),
+				Name:          googleUser.Name,
+				Picture:       googleUser.Picture,
+				GoogleID:      googleUser.ID,
+				EmailVerified: googleUser.VerifiedEmail,
+				CreatedAt:     time.Now(),
+				UpdatedAt:     time.Now(),
+			}
+			if err := s.createUser(ctx, user, models.IdentityProviderGoogle, googleUser.ID, googleUser.Email); err != nil {
+				return nil, nil, err
+			}
+		} else if err := s.linkIdentity(ctx, user, models.IdentityProviderGoogle, googleUser.ID, googleUser.Email); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	tokens, err := s.completeLogin(ctx, user, clientInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+// LinkGoogle attaches the Google account behind code to a signed-in user
+func (s *AuthService) LinkGoogle(ctx context.Context, user *models.User, code, state, stateCookie string) (*models.Identity, error) {
+	googleUser, err := s.fetchGoogleUser(ctx, code, state, stateCookie)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, models.IdentityProviderGoogle, googleUser.ID)
+	if err == nil {
+		if identity.UserID != user.ID {
+			return nil, ErrIdentityInUse
+		}
+		return identity, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to look up identity: %w", err)
+	}
+
+	if err := s.linkIdentity(ctx, user, models.IdentityProviderGoogle, googleUser.ID, googleUser.Email); err != nil {
+		return nil, err
+	}
+	return s.identityRepo.GetByProviderSubject(ctx, models.IdentityProviderGoogle, googleUser.ID)
+}
+
+// fetchGoogleUser checks the login attempt's state and exchanges the
+// authorization code for the Google account's profile
+func (s *AuthService) fetchGoogleUser(ctx context.Context, code, state, stateCookie string) (*GoogleUserInfo, error) {
+	verifier, err := s.consumeOAuthState(ctx, state, stateCookie)
+	if err != nil {
+		return nil, err
+	}
+
 	// Exchange code for token
-	token, err := s.googleConfig.Exchange(context.Background(), code)
+	token, err := s.googleConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to exchange code for token: %w", err)
+		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
 	}
 
 	// Get user info from Google
-	client := s.googleConfig.Client(context.Background(), token)
+	client := s.googleConfig.Client(ctx, token)
 	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get user info: %w", err)
+		return nil, fmt.Errorf("failed to get user info: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var googleUser GoogleUserInfo
 	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
-		return nil, "", fmt.Errorf("failed to decode user info: %w", err)
+		return nil, fmt.Errorf("failed to decode user info: %w", err)
 	}
 
-	// Check if user exists
-	user, err := s.userRepo.GetByGoogleID(googleUser.ID)
+	return &googleUser, nil
+}
+
+// RequestMagicLink emails a single-use sign-in link. To avoid revealing which
+// addresses have accounts, it only fails for malformed addresses; links are
+// sent whether or not an account exists, and silently dropped once an address
+// has had too many recently.
+func (s *AuthService) RequestMagicLink(ctx context.Context, email, locale string) error {
+	email = normalizeEmail(email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+
+	now := time.Now().UTC()
+	recent, err := s.magicLinkRepo.CountSince(ctx, email, now.Add(-magicLinkRateWindow))
 	if err != nil {
-		// User doesn't exist, create new user
-		user = &models.User{
-			ID:            uuid.New().String(),
-			Email:         googleUser.Email,
-			Name:          googleUser.Name,
-			Picture:       googleUser.Picture,
-			GoogleID:      googleUser.ID,
-			EmailVerified: googleUser.VerifiedEmail,
-			CreatedAt:     time.Now(),
-			UpdatedAt:     time.Now(),
+		return fmt.Errorf("failed to check recent links: %w", err)
+	}
+	if recent >= magicLinkRateLimit {
+		fmt.Printf("Magic link rate limit reached for %s\n", email)
+		return nil
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate magic link: %w", err)
+	}
+	link := &models.MagicLink{
+		ID:        uuid.New().String(),
+		Email:     email,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(s.config.MagicLinkMinutes) * time.Minute),
+	}
+	if err := s.magicLinkRepo.Create(ctx, link); err != nil {
+		return fmt.Errorf("failed to store magic link: %w", err)
+	}
+
+	// Existing users get the email in their chosen language
+	if user, err := s.userRepo.GetByEmail(ctx, email); err == nil {
+		if prefs, err := s.userRepo.GetPreferences(ctx, user.ID); err == nil {
+			locale = preferenceLocale(prefs)
+		}
+	}
+
+	rendered, err := s.emailService.RenderMagicLinkEmail(token, s.config.MagicLinkMinutes, locale)
+	if err != nil {
+		return err
+	}
+
+	// Send in the background so response times don't depend on the mail server
+	go func() {
+		if err := s.emailService.Send(email, rendered); err != nil {
+			fmt.Printf("Failed to send magic link email: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// LoginWithMagicLink exchanges a magic link token for a session. The first
+// login with a new address creates the account; an address that already
+// belongs to an account (for example through Google) signs in to it.
+func (s *AuthService) LoginWithMagicLink(ctx context.Context, token string, clientInfo ClientInfo) (*models.User, *TokenPair, error) {
+	link, err := s.magicLinkRepo.Consume(ctx, hashToken(token), time.Now().UTC())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrInvalidMagicLink
+		}
+		return nil, nil, fmt.Errorf("failed to verify magic link: %w", err)
+	}
+
+	var user *models.User
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, models.IdentityProviderEmail, link.Email)
+	switch {
+	case err == nil:
+		if user, err = s.userRepo.GetByID(ctx, identity.UserID); err != nil {
+			return nil, nil, fmt.Errorf("failed to get user: %w", err)
+		}
+	case err != sql.ErrNoRows:
+		return nil, nil, fmt.Errorf("failed to look up identity: %w", err)
+	default:
+		user, _ = s.userRepo.GetByEmail(ctx, link.Email)
+		if user == nil {
+			user = &models.User{
+				ID:            uuid.New().String(),
+				Email:         link.Email,
+				Name:          strings.SplitN(link.Email, "@", 2)[0],
+				EmailVerified: true,
+				CreatedAt:     time.Now(),
+				UpdatedAt:     time.Now(),
+			}
+			if err := s.createUser(ctx, user, models.IdentityProviderEmail, link.Email, link.Email); err != nil {
+				return nil, nil, err
+			}
+		} else if err := s.linkIdentity(ctx, user, models.IdentityProviderEmail, link.Email, link.Email); err != nil {
+			return nil, nil, err
 		}
+	}
 
-		if err := s.userRepo.Create(user); err != nil {
-			return nil, "", fmt.Errorf("failed to create user: %w", err)
+	// Following the link proves the user controls the address
+	if !user.EmailVerified {
+		user.EmailVerified = true
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, nil, fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+
+	tokens, err := s.completeLogin(ctx, user, clientInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+func (s *AuthService) ListIdentities(ctx context.Context, userID string) ([]*models.Identity, error) {
+	return s.identityRepo.GetByUserID(ctx, userID)
+}
+
+// UnlinkIdentity removes a sign-in method. The last one can't be removed.
+func (s *AuthService) UnlinkIdentity(ctx context.Context, userID, identityID string) error {
+	err := s.identityRepo.Delete(ctx, identityID, userID)
+	if err == sql.ErrNoRows {
+		return ErrIdentityNotFound
+	}
+	return err
+}
+
+// createUser stores a new account, its default preferences and its first
+// sign-in identity in one transaction, so a failure part way through leaves
+// no account behind, then queues its welcome email
+func (s *AuthService) createUser(ctx context.Context, user *models.User, provider, subject, email string) error {
+	err := s.uow.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
 		}
 
 		// Create default preferences
@@ -108,43 +476,293 @@ func (s *AuthService) HandleGoogleCallback(code string) (*models.User, string, e
 			UserID:                user.ID,
 			EmailNotifications:    true,
 			PushNotifications:     true,
-			NotificationFrequency: "immediate",
+			NotificationFrequency: models.NotificationFrequencyImmediate,
+			QuietHoursMode:        models.QuietHoursModeHold,
+			Locale:                DefaultLocale,
 			CreatedAt:             time.Now(),
 			UpdatedAt:             time.Now(),
 		}
+		if err := s.userRepo.CreatePreferences(ctx, prefs); err != nil {
+			return fmt.Errorf("failed to create user preferences: %w", err)
+		}
+
+		return s.linkIdentity(ctx, user, provider, subject, email)
+	})
+	if err != nil {
+		return err
+	}
 
-		if err := s.userRepo.CreatePreferences(prefs); err != nil {
-			// Log error but don't fail the login
-			fmt.Printf("Failed to create user preferences: %v\n", err)
+	// Queue the welcome email in the background so sign-up isn't held up. It
+	// outlives the request, so it doesn't take the request's context.
+	go func(user *models.User) {
+		if err := s.lifecycleService.SendWelcome(context.Background(), user); err != nil {
+			fmt.Printf("Failed to queue welcome email: %v\n", err)
 		}
-	} else {
-		// Update existing user info
-		user.Email = googleUser.Email
-		user.Name = googleUser.Name
-		user.Picture = googleUser.Picture
-		user.EmailVerified = googleUser.VerifiedEmail
-		user.UpdatedAt = time.Now()
+	}(user)
+
+	return nil
+}
+
+func (s *AuthService) linkIdentity(ctx context.Context, user *models.User, provider, subject, email string) error {
+	identity := &models.Identity{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Provider:  provider,
+		Subject:   subject,
+		Email:     email,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.identityRepo.Create(ctx, identity); err != nil {
+		return fmt.Errorf("failed to link %s identity: %w", provider, err)
+	}
+	return nil
+}
+
+// completeLogin applies the admin allowlist, records the login and starts a session
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, clientInfo ClientInfo) (*TokenPair, error) {
+	if user.EmailVerified && user.Role != models.RoleAdmin && s.isAdminEmail(user.Email) {
+		if err := s.userRepo.UpdateRole(ctx, user.ID, models.RoleAdmin); err != nil {
+			return nil, fmt.Errorf("failed to promote admin: %w", err)
+		}
+		user.Role = models.RoleAdmin
+	}
+
+	now := time.Now()
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID, now); err != nil {
+		fmt.Printf("Failed to record last login: %v\n", err)
+	}
+	user.LastLoginAt = &now
+
+	return s.StartSession(ctx, user, clientInfo)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// BootstrapAdmins promotes existing users whose email is on the ADMIN_EMAILS
+// allowlist. Users who sign up later are promoted on their first login.
+func (s *AuthService) BootstrapAdmins(ctx context.Context) error {
+	for _, email := range s.config.AdminEmails {
+		promoted, err := s.userRepo.PromoteByEmail(ctx, email, models.RoleAdmin)
+		if err != nil {
+			return fmt.Errorf("failed to promote %s: %w", email, err)
+		}
+		if promoted {
+			fmt.Printf("Promoted %s to admin\n", email)
+		}
+	}
+	return nil
+}
+
+func (s *AuthService) isAdminEmail(email string) bool {
+	for _, admin := range s.config.AdminEmails {
+		if strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// StartSession opens a new session for the user and issues its first token pair
+func (s *AuthService) StartSession(ctx context.Context, user *models.User, clientInfo ClientInfo) (*TokenPair, error) {
+	refreshToken, err := newOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.refreshTokenTTL())
+	session := &models.Session{
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		UserAgent:  clientInfo.UserAgent,
+		IPAddress:  clientInfo.IPAddress,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	token := &models.RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: session.ID,
+		TokenHash: hashToken(refreshToken),
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.sessionRepo.CreateWithToken(ctx, session, token); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.tokenPair(user, session.ID, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// works once; presenting a spent one means it has leaked, so the whole
+// session is revoked and the caller has to log in again.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	current, err := s.sessionRepo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if current.UsedAt != nil {
+		if err := s.sessionRepo.Revoke(ctx, current.SessionID, time.Now().UTC()); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	now := time.Now().UTC()
+	if now.After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.activeSession(ctx, current.SessionID)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := s.userRepo.Update(user); err != nil {
-			return nil, "", fmt.Errorf("failed to update user: %w", err)
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	nextToken, err := newOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	expiresAt := now.Add(s.refreshTokenTTL())
+	next := &models.RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: session.ID,
+		TokenHash: hashToken(nextToken),
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+
+	rotated, err := s.sessionRepo.Rotate(ctx, current.ID, next, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		// A concurrent request spent the same token first
+		if err := s.sessionRepo.Revoke(ctx, session.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
 		}
+		return nil, ErrRefreshTokenReused
 	}
 
-	// Generate JWT token
-	jwtToken, err := s.GenerateJWT(user)
+	return s.tokenPair(user, session.ID, nextToken)
+}
+
+// RevokeSession logs out a single session
+func (s *AuthService) RevokeSession(ctx context.Context, sessionID string) error {
+	return s.sessionRepo.Revoke(ctx, sessionID, time.Now().UTC())
+}
+
+// RevokeAllSessions logs the user out on every device, returning how many
+// sessions were ended
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID string) (int64, error) {
+	return s.sessionRepo.RevokeAllForUser(ctx, userID, time.Now().UTC())
+}
+
+// IssueStreamTicket returns a single-use ticket that opens the notification
+// stream for the session. EventSource can't send an Authorization header, and
+// the access token itself must not go in the URL, where it would be logged.
+func (s *AuthService) IssueStreamTicket(ctx context.Context, userID, sessionID string) (string, int, error) {
+	ticket, err := newOpaqueToken()
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate JWT: %w", err)
+		return "", 0, fmt.Errorf("failed to generate ticket: %w", err)
 	}
 
-	return user, jwtToken, nil
+	now := time.Now().UTC()
+	entry := &models.StreamTicket{
+		TicketHash: hashToken(ticket),
+		UserID:     userID,
+		SessionID:  sessionID,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(streamTicketTTL),
+	}
+	if err := s.streamTicketRepo.Create(ctx, entry); err != nil {
+		return "", 0, fmt.Errorf("failed to store ticket: %w", err)
+	}
+	return ticket, int(streamTicketTTL.Seconds()), nil
+}
+
+// RedeemStreamTicket uses up a stream ticket, returning the user and session
+// it was issued to. The session must still be active.
+func (s *AuthService) RedeemStreamTicket(ctx context.Context, ticket string) (*models.User, string, error) {
+	if ticket == "" {
+		return nil, "", ErrInvalidStreamTicket
+	}
+
+	entry, err := s.streamTicketRepo.Consume(ctx, hashToken(ticket))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", ErrInvalidStreamTicket
+		}
+		return nil, "", fmt.Errorf("failed to look up ticket: %w", err)
+	}
+	if time.Now().UTC().After(entry.ExpiresAt) {
+		return nil, "", ErrInvalidStreamTicket
+	}
+	if _, err := s.activeSession(ctx, entry.SessionID); err != nil {
+		return nil, "", err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, entry.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, entry.SessionID, nil
 }
 
-func (s *AuthService) GenerateJWT(user *models.User) (string, error) {
+func (s *AuthService) activeSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionRevoked
+		}
+		return nil, err
+	}
+	if session.RevokedAt != nil || time.Now().UTC().After(session.ExpiresAt) {
+		return nil, ErrSessionRevoked
+	}
+	return session, nil
+}
+
+func (s *AuthService) tokenPair(user *models.User, sessionID, refreshToken string) (*TokenPair, error) {
+	accessToken, err := s.GenerateJWT(user, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.accessTokenTTL().Seconds()),
+	}, nil
+}
+
+func (s *AuthService) accessTokenTTL() time.Duration {
+	return time.Duration(s.config.AccessTokenMinutes) * time.Minute
+}
+
+func (s *AuthService) refreshTokenTTL() time.Duration {
+	return time.Duration(s.config.RefreshTokenDays) * 24 * time.Hour
+}
+
+func (s *AuthService) GenerateJWT(user *models.User, sessionID string) (string, error) {
 	claims := JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(s.config.JWTExpirationHours) * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "shares-alert-backend",
@@ -152,17 +770,11 @@ func (s *AuthService) GenerateJWT(user *models.User) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.JWTSecret))
+	return s.keys.Sign(claims)
 }
 
 func (s *AuthService) ValidateJWT(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.config.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, s.keys.Keyfunc, jwt.WithValidMethods(s.keys.Methods()))
 
 	if err != nil {
 		return nil, err
@@ -175,11 +787,34 @@ func (s *AuthService) ValidateJWT(tokenString string) (*JWTClaims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
-func (s *AuthService) GetUserFromToken(tokenString string) (*models.User, error) {
+// JWKS returns the public keys clients can use to verify access tokens
+func (s *AuthService) JWKS() JWKS {
+	return s.keys.JWKS()
+}
+
+func (s *AuthService) GetUserFromToken(ctx context.Context, tokenString string) (*models.User, error) {
+	user, _, err := s.Authenticate(ctx, tokenString)
+	return user, err
+}
+
+// Authenticate validates an access token and checks that its session is still
+// live, so revoked sessions are locked out before their JWT expires
+func (s *AuthService) Authenticate(ctx context.Context, tokenString string) (*models.User, *JWTClaims, error) {
 	claims, err := s.ValidateJWT(tokenString)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return s.userRepo.GetByID(claims.UserID)
+	if claims.SessionID == "" {
+		return nil, nil, ErrSessionRevoked
+	}
+	if _, err := s.activeSession(ctx, claims.SessionID); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, claims, nil
 }
\ No newline at end of file
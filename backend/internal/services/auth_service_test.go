@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+// newTestAuthService returns an AuthService over a fresh SQLite database,
+// signing HS256 tokens. It sends no email.
+func newTestAuthService(t *testing.T) (*AuthService, *database.DB) {
+	t.Helper()
+
+	db := newTestDB(t)
+	cfg := &config.AuthConfig{
+		JWTSecret:          "test-secret",
+		AccessTokenMinutes: 15,
+		RefreshTokenDays:   30,
+		OAuthStateMinutes:  10,
+		MagicLinkMinutes:   15,
+		RedirectURL:        "http://localhost:5173/",
+	}
+	keys, err := NewJWTKeySet(cfg)
+	if err != nil {
+		t.Fatalf("NewJWTKeySet: %v", err)
+	}
+
+	service := NewAuthService(repository.NewUserRepository(db), repository.NewSessionRepository(db), repository.NewOAuthStateRepository(db),
+		repository.NewIdentityRepository(db), repository.NewMagicLinkRepository(db),
+		repository.NewStreamTicketRepository(db), db, nil, nil, keys, cfg)
+	return service, db
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	service, db := newTestAuthService(t)
+	user := createTestUser(t, db, "refresh@example.com")
+
+	first, err := service.StartSession(ctx, user, ClientInfo{})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	second, err := service.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Refresh returned the same refresh token")
+	}
+	if _, _, err := service.Authenticate(ctx, second.AccessToken); err != nil {
+		t.Fatalf("Authenticate with the rotated access token: %v", err)
+	}
+
+	// Replaying the spent token is taken as a leak
+	if _, err := service.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replayed Refresh error = %v, want ErrRefreshTokenReused", err)
+	}
+
+	// ...which ends the session for the legitimate holder too
+	if _, _, err := service.Authenticate(ctx, second.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Authenticate after reuse error = %v, want ErrSessionRevoked", err)
+	}
+	if _, err := service.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Refresh after reuse error = %v, want ErrSessionRevoked", err)
+	}
+}
+
+func TestAuthenticateRejectsRevokedSessions(t *testing.T) {
+	ctx := context.Background()
+	service, db := newTestAuthService(t)
+	user := createTestUser(t, db, "revoke@example.com")
+
+	kept, err := service.StartSession(ctx, user, ClientInfo{UserAgent: "laptop"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	revoked, err := service.StartSession(ctx, user, ClientInfo{UserAgent: "phone"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+
+	_, claims, err := service.Authenticate(ctx, revoked.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if err := service.RevokeSession(ctx, claims.SessionID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+
+	if _, _, err := service.Authenticate(ctx, revoked.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Authenticate with a revoked session error = %v, want ErrSessionRevoked", err)
+	}
+	if _, err := service.Refresh(ctx, revoked.RefreshToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Refresh with a revoked session error = %v, want ErrSessionRevoked", err)
+	}
+	if _, _, err := service.Authenticate(ctx, kept.AccessToken); err != nil {
+		t.Errorf("Authenticate with the other session: %v", err)
+	}
+
+	if n, err := service.RevokeAllSessions(ctx, user.ID); err != nil || n != 1 {
+		t.Fatalf("RevokeAllSessions = %d, %v; want 1, nil", n, err)
+	}
+	if _, _, err := service.Authenticate(ctx, kept.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Authenticate after RevokeAllSessions error = %v, want ErrSessionRevoked", err)
+	}
+}
+
+func TestAuthenticateRejectsTokensWithoutSession(t *testing.T) {
+	ctx := context.Background()
+	service, db := newTestAuthService(t)
+	user := createTestUser(t, db, "nosession@example.com")
+
+	token, err := service.GenerateJWT(user, "")
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	if _, _, err := service.Authenticate(ctx, token); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Authenticate without a session error = %v, want ErrSessionRevoked", err)
+	}
+}
+
+func TestGoogleAuthURLCarriesPKCEChallenge(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestAuthService(t)
+
+	authURL, state, err := service.GetGoogleAuthURL(ctx)
+	if err != nil {
+		t.Fatalf("GetGoogleAuthURL: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parsing auth URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("state") != state {
+		t.Errorf("auth URL state = %q, want %q", query.Get("state"), state)
+	}
+	if query.Get("code_challenge_method") != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+
+	// The callback gets back the verifier the challenge was made from
+	verifier, err := service.consumeOAuthState(ctx, state, service.OAuthStateCookie(state).Value)
+	if err != nil {
+		t.Fatalf("consumeOAuthState: %v", err)
+	}
+	if got := oauth2.S256ChallengeFromVerifier(verifier); got != query.Get("code_challenge") {
+		t.Errorf("verifier's challenge = %q, want %q", got, query.Get("code_challenge"))
+	}
+
+	if _, err := service.consumeOAuthState(ctx, state, service.OAuthStateCookie(state).Value); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Errorf("second consumeOAuthState error = %v, want ErrInvalidOAuthState", err)
+	}
+}
+
+func TestGoogleCallbackRejectsBadState(t *testing.T) {
+	ctx := context.Background()
+	service, db := newTestAuthService(t)
+
+	_, state, err := service.GetGoogleAuthURL(ctx)
+	if err != nil {
+		t.Fatalf("GetGoogleAuthURL: %v", err)
+	}
+	cookie := service.OAuthStateCookie(state).Value
+
+	now := time.Now().UTC()
+	expired := &models.OAuthState{
+		State:        "expired-state",
+		CodeVerifier: oauth2.GenerateVerifier(),
+		CreatedAt:    now.Add(-time.Hour),
+		ExpiresAt:    now.Add(-50 * time.Minute),
+	}
+	if err := repository.NewOAuthStateRepository(db).Create(ctx, expired); err != nil {
+		t.Fatalf("failed to store state: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		state  string
+		cookie string
+		want   error
+	}{
+		{"missing", "", "", ErrMissingOAuthState},
+		{"not issued by the server", "forged-state", service.OAuthStateCookie("forged-state").Value, ErrInvalidOAuthState},
+		{"expired", "expired-state", service.OAuthStateCookie("expired-state").Value, ErrExpiredOAuthState},
+		{"expired, replayed", "expired-state", service.OAuthStateCookie("expired-state").Value, ErrInvalidOAuthState},
+		{"no cookie", state, "", ErrOAuthStateMismatch},
+		{"another login's cookie", state, service.OAuthStateCookie("other-state").Value, ErrOAuthStateMismatch},
+	}
+
+	// The state is checked before the code is exchanged, so no request
+	// reaches Google
+	for _, tt := range tests {
+		if _, _, err := service.HandleGoogleCallback(ctx, "code", tt.state, tt.cookie, ClientInfo{}); !errors.Is(err, tt.want) {
+			t.Errorf("%s: HandleGoogleCallback error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// A mismatched cookie doesn't use the state up
+	if _, err := service.consumeOAuthState(ctx, state, cookie); err != nil {
+		t.Errorf("consumeOAuthState with the login's own cookie: %v", err)
+	}
+}
+
+func TestOAuthStateCookie(t *testing.T) {
+	service, _ := newTestAuthService(t)
+
+	cookie := service.OAuthStateCookie("some-state")
+	if cookie.Value == "" || cookie.Value == "some-state" {
+		t.Errorf("cookie value = %q, want a hash of the state", cookie.Value)
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.MaxAge != 600 {
+		t.Errorf("cookie = %+v, want HttpOnly, SameSite=Lax, MaxAge=600", cookie)
+	}
+	if cookie.Secure {
+		t.Error("cookie is Secure for a plain-http frontend")
+	}
+	if cleared := service.ExpiredOAuthStateCookie(); cleared.MaxAge >= 0 || cleared.Path != cookie.Path {
+		t.Errorf("cleared cookie = %+v, want MaxAge < 0 on path %q", cleared, cookie.Path)
+	}
+
+	// A frontend on another site needs SameSite=None, which must be Secure
+	service.config.OAuthCookieSameSite = "none"
+	if cookie := service.OAuthStateCookie("some-state"); cookie.SameSite != http.SameSiteNoneMode || !cookie.Secure {
+		t.Errorf("cookie = %+v, want SameSite=None and Secure", cookie)
+	}
+}
+
+func TestStreamTicketsWorkOnce(t *testing.T) {
+	ctx := context.Background()
+	service, db := newTestAuthService(t)
+	user := createTestUser(t, db, "stream@example.com")
+
+	tokens, err := service.StartSession(ctx, user, ClientInfo{UserAgent: "browser"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	_, claims, err := service.Authenticate(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	issue := func() string {
+		t.Helper()
+		ticket, expiresIn, err := service.IssueStreamTicket(ctx, user.ID, claims.SessionID)
+		if err != nil || expiresIn <= 0 {
+			t.Fatalf("IssueStreamTicket = %d, %v", expiresIn, err)
+		}
+		return ticket
+	}
+
+	ticket := issue()
+	streamUser, sessionID, err := service.RedeemStreamTicket(ctx, ticket)
+	if err != nil {
+		t.Fatalf("RedeemStreamTicket: %v", err)
+	}
+	if streamUser.ID != user.ID || sessionID != claims.SessionID {
+		t.Errorf("ticket opened the stream for user %s, session %s; want %s, %s", streamUser.ID, sessionID, user.ID, claims.SessionID)
+	}
+	if _, _, err := service.RedeemStreamTicket(ctx, ticket); !errors.Is(err, ErrInvalidStreamTicket) {
+		t.Errorf("second RedeemStreamTicket error = %v, want ErrInvalidStreamTicket", err)
+	}
+	for _, bad := range []string{"", "not-a-ticket", tokens.AccessToken} {
+		if _, _, err := service.RedeemStreamTicket(ctx, bad); !errors.Is(err, ErrInvalidStreamTicket) {
+			t.Errorf("RedeemStreamTicket(%.20q) error = %v, want ErrInvalidStreamTicket", bad, err)
+		}
+	}
+
+	expired := issue()
+	if _, err := db.Exec(`UPDATE shares_alert_stream_tickets SET expires_at = $1`, time.Now().UTC().Add(-time.Second)); err != nil {
+		t.Fatalf("expiring ticket: %v", err)
+	}
+	if _, _, err := service.RedeemStreamTicket(ctx, expired); !errors.Is(err, ErrInvalidStreamTicket) {
+		t.Errorf("RedeemStreamTicket with an expired ticket error = %v, want ErrInvalidStreamTicket", err)
+	}
+
+	revoked := issue()
+	if err := service.RevokeSession(ctx, claims.SessionID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if _, _, err := service.RedeemStreamTicket(ctx, revoked); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("RedeemStreamTicket after logout error = %v, want ErrSessionRevoked", err)
+	}
+}
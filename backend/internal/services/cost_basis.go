@@ -0,0 +1,194 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"shares-alert-backend/internal/models"
+)
+
+// quantityEpsilon absorbs floating point error when a sell closes a position
+const quantityEpsilon = 1e-9
+
+var costBasisMethods = map[string]bool{
+	models.CostBasisAverage:     true,
+	models.CostBasisFIFO:        true,
+	models.CostBasisSpecificLot: true,
+}
+
+// lot is shares from one buy that haven't been sold yet
+type lot struct {
+	id           string
+	tradeDate    time.Time
+	quantity     float64
+	costPerShare float64 // including the buy commission
+}
+
+// symbolLedger accumulates one symbol's open lots and P&L while the ledger
+// is replayed
+type symbolLedger struct {
+	symbol    string
+	lots      []*lot // oldest first
+	realized  float64
+	dividends float64
+	fees      float64
+}
+
+func (l *symbolLedger) quantity() float64 {
+	var quantity float64
+	for _, lot := range l.lots {
+		quantity += lot.quantity
+	}
+	return quantity
+}
+
+func (l *symbolLedger) cost() float64 {
+	var cost float64
+	for _, lot := range l.lots {
+		cost += lot.quantity * lot.costPerShare
+	}
+	return cost
+}
+
+// ledgerResult is the state of a portfolio after replaying its whole ledger
+type ledgerResult struct {
+	symbols   []*symbolLedger // in the order they first appear
+	otherFees float64         // standalone fees, not tied to a trade
+}
+
+// open returns the symbols that still have shares
+func (r *ledgerResult) open() []*symbolLedger {
+	var open []*symbolLedger
+	for _, l := range r.symbols {
+		if l.quantity() > 0 {
+			open = append(open, l)
+		}
+	}
+	return open
+}
+
+// sortLedger orders transactions by trade date, then by when they were entered
+func sortLedger(ledger []*models.Transaction) []*models.Transaction {
+	ordered := make([]*models.Transaction, len(ledger))
+	copy(ordered, ledger)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if !ordered[i].TradeDate.Equal(ordered[j].TradeDate) {
+			return ordered[i].TradeDate.Before(ordered[j].TradeDate)
+		}
+		return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+	})
+	return ordered
+}
+
+// replayLedger walks the transactions in date order, matching each sell to
+// the lots it disposes of under the cost-basis method. It fails with
+// ErrInsufficientShares if a sell exceeds the shares (or, for specific_lot,
+// the lot) held at the time.
+func replayLedger(ledger []*models.Transaction, method string) (*ledgerResult, error) {
+	result := &ledgerResult{}
+	bySymbol := make(map[string]*symbolLedger)
+	symbolLedgerFor := func(symbol string) *symbolLedger {
+		l, ok := bySymbol[symbol]
+		if !ok {
+			l = &symbolLedger{symbol: symbol}
+			bySymbol[symbol] = l
+			result.symbols = append(result.symbols, l)
+		}
+		return l
+	}
+
+	for _, t := range sortLedger(ledger) {
+		switch t.Type {
+		case models.TransactionTypeBuy:
+			l := symbolLedgerFor(t.StockSymbol)
+			l.lots = append(l.lots, &lot{
+				id:           t.ID,
+				tradeDate:    t.TradeDate,
+				quantity:     t.Quantity,
+				costPerShare: (t.Quantity*t.Price + t.Fees) / t.Quantity,
+			})
+			l.fees += t.Fees
+		case models.TransactionTypeSell:
+			l := symbolLedgerFor(t.StockSymbol)
+			cost, err := l.dispose(t, method)
+			if err != nil {
+				return nil, err
+			}
+			l.realized += t.Quantity*t.Price - t.Fees - cost
+			l.fees += t.Fees
+		case models.TransactionTypeDividend:
+			l := symbolLedgerFor(t.StockSymbol)
+			l.dividends += t.Quantity*t.Price - t.Fees
+			l.fees += t.Fees
+		case models.TransactionTypeFee:
+			result.otherFees += t.Fees
+		}
+	}
+
+	return result, nil
+}
+
+// dispose removes the sold shares from the lots and returns their cost
+func (l *symbolLedger) dispose(t *models.Transaction, method string) (float64, error) {
+	held := l.quantity()
+	if t.Quantity > held+quantityEpsilon {
+		return 0, fmt.Errorf("%w: selling %g %s on %s", ErrInsufficientShares,
+			t.Quantity, t.StockSymbol, t.TradeDate.Format(snapshotDateLayout))
+	}
+
+	var cost float64
+	switch {
+	case method == models.CostBasisAverage:
+		// Take the same fraction of every lot, so each share costs the average
+		fraction := t.Quantity / held
+		for _, lot := range l.lots {
+			sold := lot.quantity * fraction
+			cost += sold * lot.costPerShare
+			lot.quantity -= sold
+		}
+	case method == models.CostBasisSpecificLot && t.LotID != "":
+		var chosen *lot
+		for _, lot := range l.lots {
+			if lot.id == t.LotID {
+				chosen = lot
+			}
+		}
+		if chosen == nil || t.Quantity > chosen.quantity+quantityEpsilon {
+			return 0, fmt.Errorf("%w: lot %s doesn't hold %g %s on %s", ErrInsufficientShares,
+				t.LotID, t.Quantity, t.StockSymbol, t.TradeDate.Format(snapshotDateLayout))
+		}
+		sold := minFloat(t.Quantity, chosen.quantity)
+		cost = sold * chosen.costPerShare
+		chosen.quantity -= sold
+	default:
+		remaining := t.Quantity
+		for _, lot := range l.lots {
+			if remaining <= 0 {
+				break
+			}
+			sold := minFloat(remaining, lot.quantity)
+			cost += sold * lot.costPerShare
+			lot.quantity -= sold
+			remaining -= sold
+		}
+	}
+
+	// Drop emptied lots
+	open := l.lots[:0]
+	for _, lot := range l.lots {
+		if lot.quantity > quantityEpsilon {
+			open = append(open, lot)
+		}
+	}
+	l.lots = open
+
+	return cost, nil
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
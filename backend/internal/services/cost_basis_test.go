@@ -0,0 +1,156 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"shares-alert-backend/internal/models"
+)
+
+func testTransaction(id, kind string, quantity, price, fees float64, day int, lotID string) *models.Transaction {
+	date := time.Date(2026, 3, day, 0, 0, 0, 0, time.UTC)
+	return &models.Transaction{
+		ID: id, Type: kind, StockSymbol: "MTNGH", Quantity: quantity, Price: price, Fees: fees,
+		TradeDate: date, LotID: lotID, CreatedAt: date,
+	}
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestReplayLedger(t *testing.T) {
+	// 10 shares at 1.10 each including the commission, then 10 at 2.00
+	buys := []*models.Transaction{
+		testTransaction("b1", models.TransactionTypeBuy, 10, 1.00, 1.00, 2, ""),
+		testTransaction("b2", models.TransactionTypeBuy, 10, 2.00, 0, 3, ""),
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		sell         *models.Transaction
+		wantErr      error
+		wantRealized float64 // proceeds less fees and the cost of the shares sold
+		wantQuantity float64
+		wantCost     float64 // of the shares still held
+	}{
+		{
+			name:   "fifo takes all of the first lot and part of the second",
+			method: models.CostBasisFIFO, sell: testTransaction("s", models.TransactionTypeSell, 15, 3, 1.5, 4, ""),
+			wantRealized: 45 - 1.5 - (10*1.10 + 5*2.00), wantQuantity: 5, wantCost: 5 * 2.00,
+		},
+		{
+			name:   "average takes the same fraction of every lot",
+			method: models.CostBasisAverage, sell: testTransaction("s", models.TransactionTypeSell, 15, 3, 1.5, 4, ""),
+			wantRealized: 45 - 1.5 - 0.75*(11+20), wantQuantity: 5, wantCost: 0.25 * (11 + 20),
+		},
+		{
+			name:   "average closing the position",
+			method: models.CostBasisAverage, sell: testTransaction("s", models.TransactionTypeSell, 20, 3, 0, 4, ""),
+			wantRealized: 60 - 31, wantQuantity: 0, wantCost: 0,
+		},
+		{
+			name:   "specific lot takes part of the named lot",
+			method: models.CostBasisSpecificLot, sell: testTransaction("s", models.TransactionTypeSell, 8, 3, 0, 4, "b2"),
+			wantRealized: 24 - 8*2.00, wantQuantity: 12, wantCost: 11 + 2*2.00,
+		},
+		{
+			name:   "specific lot without a lot falls back to fifo",
+			method: models.CostBasisSpecificLot, sell: testTransaction("s", models.TransactionTypeSell, 15, 3, 1.5, 4, ""),
+			wantRealized: 45 - 1.5 - (10*1.10 + 5*2.00), wantQuantity: 5, wantCost: 5 * 2.00,
+		},
+		{
+			name:   "selling more than is held",
+			method: models.CostBasisFIFO, sell: testTransaction("s", models.TransactionTypeSell, 21, 3, 0, 4, ""),
+			wantErr: ErrInsufficientShares,
+		},
+		{
+			name:   "selling more than the named lot holds",
+			method: models.CostBasisSpecificLot, sell: testTransaction("s", models.TransactionTypeSell, 11, 3, 0, 4, "b1"),
+			wantErr: ErrInsufficientShares,
+		},
+		{
+			name:   "selling before the shares were bought",
+			method: models.CostBasisAverage, sell: testTransaction("s", models.TransactionTypeSell, 5, 3, 0, 1, ""),
+			wantErr: ErrInsufficientShares,
+		},
+	}
+
+	for _, tt := range tests {
+		result, err := replayLedger(append(append([]*models.Transaction{}, buys...), tt.sell), tt.method)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		l := result.symbols[0]
+		if !closeTo(l.realized, tt.wantRealized) || !closeTo(l.quantity(), tt.wantQuantity) || !closeTo(l.cost(), tt.wantCost) {
+			t.Errorf("%s: realized %g, holding %g costing %g; want %g, %g, %g", tt.name,
+				l.realized, l.quantity(), l.cost(), tt.wantRealized, tt.wantQuantity, tt.wantCost)
+		}
+		if want := 1.00 + tt.sell.Fees; !closeTo(l.fees, want) {
+			t.Errorf("%s: fees = %g, want %g", tt.name, l.fees, want)
+		}
+	}
+}
+
+func TestReplayLedgerDividendsAndFees(t *testing.T) {
+	result, err := replayLedger([]*models.Transaction{
+		testTransaction("b1", models.TransactionTypeBuy, 10, 1.00, 0, 2, ""),
+		testTransaction("d1", models.TransactionTypeDividend, 10, 0.05, 0.05, 3, ""),
+		testTransaction("f1", models.TransactionTypeFee, 0, 0, 2.50, 4, ""),
+	}, models.CostBasisFIFO)
+	if err != nil {
+		t.Fatalf("replayLedger: %v", err)
+	}
+
+	l := result.symbols[0]
+	if !closeTo(l.dividends, 0.45) || !closeTo(l.fees, 0.05) || !closeTo(result.otherFees, 2.50) {
+		t.Errorf("dividends %g, fees %g, other fees %g; want 0.45, 0.05, 2.5", l.dividends, l.fees, result.otherFees)
+	}
+	if len(result.open()) != 1 {
+		t.Errorf("%d open symbols, want 1", len(result.open()))
+	}
+}
+
+func TestValidateLotReference(t *testing.T) {
+	ledger := []*models.Transaction{
+		testTransaction("b1", models.TransactionTypeBuy, 10, 1.00, 0, 2, ""),
+		testTransaction("d1", models.TransactionTypeDividend, 10, 0.05, 0, 3, ""),
+		testTransaction("b2", models.TransactionTypeBuy, 10, 1.00, 0, 5, ""),
+	}
+
+	tests := []struct {
+		name    string
+		sell    *models.Transaction
+		method  string
+		wantErr bool
+	}{
+		{"earlier buy", testTransaction("s", models.TransactionTypeSell, 5, 2, 0, 4, "b1"), models.CostBasisSpecificLot, false},
+		{"no lot", testTransaction("s", models.TransactionTypeSell, 5, 2, 0, 4, ""), models.CostBasisFIFO, false},
+		{"lot in a fifo portfolio", testTransaction("s", models.TransactionTypeSell, 5, 2, 0, 4, "b1"), models.CostBasisFIFO, true},
+		{"lot in an average portfolio", testTransaction("s", models.TransactionTypeSell, 5, 2, 0, 4, "b1"), models.CostBasisAverage, true},
+		{"later buy", testTransaction("s", models.TransactionTypeSell, 5, 2, 0, 4, "b2"), models.CostBasisSpecificLot, true},
+		{"not a buy", testTransaction("s", models.TransactionTypeSell, 5, 2, 0, 4, "d1"), models.CostBasisSpecificLot, true},
+		{"unknown lot", testTransaction("s", models.TransactionTypeSell, 5, 2, 0, 4, "missing"), models.CostBasisSpecificLot, true},
+	}
+
+	for _, tt := range tests {
+		err := validateLotReference(ledger, tt.sell, tt.method)
+		if tt.wantErr && !errors.Is(err, ErrInvalidTransaction) {
+			t.Errorf("%s: error = %v, want ErrInvalidTransaction", tt.name, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"shares-alert-backend/internal/models"
+)
+
+// ErrInvalidCursor is returned for a pagination cursor that is malformed or
+// was issued by a listing with a different sort or filters
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// listCursor is the sort position of the last item on a page: its value for
+// each sort key, then its id. Times are RFC 3339 strings. Filter is a hash of
+// the listing's filters, so the cursor can't be carried over to a different
+// result set. Alerts and notifications share this format.
+type listCursor struct {
+	Sort   string        `json:"s"`
+	Filter string        `json:"f"`
+	After  []interface{} `json:"a"`
+}
+
+// filterHash identifies a listing's filter values
+func filterHash(values ...interface{}) string {
+	raw, _ := json.Marshal(values)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:8])
+}
+
+// encodeListCursor builds an opaque cursor from the sort position of the
+// last item on a page
+func encodeListCursor(sort, filter string, after []interface{}) string {
+	raw, _ := json.Marshal(listCursor{Sort: sort, Filter: filter, After: after})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeListCursor reverses encodeListCursor, checking the cursor came from
+// a listing with the same sort and filters and holds size values
+func decodeListCursor(cursor, sort, filter string, size int) ([]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c listCursor
+	if err := json.Unmarshal(raw, &c); err != nil || len(c.After) != size {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort {
+		return nil, fmt.Errorf("%w: it belongs to a listing sorted by %q", ErrInvalidCursor, c.Sort)
+	}
+	if c.Filter != filter {
+		return nil, fmt.Errorf("%w: it belongs to a listing with different filters", ErrInvalidCursor)
+	}
+	return c.After, nil
+}
+
+// alertFilterHash identifies a filter set, ignoring the order of values
+// within each filter
+func alertFilterHash(filter models.AlertFilter) string {
+	sorted := func(values []string) []string {
+		values = append([]string{}, values...)
+		sort.Strings(values)
+		return values
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+
+	return filterHash(
+		sorted(filter.Statuses), sorted(filter.StockSymbols), sorted(filter.AlertTypes),
+		sorted(filter.Scopes), sorted(filter.PortfolioIDs), filter.Search,
+		formatTime(filter.CreatedFrom), formatTime(filter.CreatedTo),
+		formatTime(filter.TriggeredFrom), formatTime(filter.TriggeredTo),
+	)
+}
+
+// encodeAlertCursor builds an opaque cursor for the page after alert
+func encodeAlertCursor(sort string, filter models.AlertFilter, keys []models.AlertSort, alert *models.Alert) string {
+	after := make([]interface{}, 0, len(keys)+1)
+	for _, key := range keys {
+		switch value := alertSortValue(alert, key.Field).(type) {
+		case time.Time:
+			after = append(after, value.UTC().Format(time.RFC3339Nano))
+		default:
+			after = append(after, value)
+		}
+	}
+	after = append(after, alert.ID)
+
+	return encodeListCursor(sort, alertFilterHash(filter), after)
+}
+
+// decodeAlertCursor reverses encodeAlertCursor, returning the values to
+// list past. The cursor must come from a listing with the same sort and
+// filters. A null sort value is an alert that has never triggered.
+func decodeAlertCursor(cursor, sort string, filter models.AlertFilter, keys []models.AlertSort) ([]interface{}, error) {
+	invalid := fmt.Errorf("%w: %w", ErrInvalidAlertQuery, ErrInvalidCursor)
+
+	values, err := decodeListCursor(cursor, sort, alertFilterHash(filter), len(keys)+1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAlertQuery, err)
+	}
+
+	after := make([]interface{}, len(values))
+	for i, value := range values {
+		if value == nil && i < len(keys) && keys[i].Field == models.AlertSortTriggeredAt {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		after[i] = text
+		if i < len(keys) && isAlertTimeField(keys[i].Field) {
+			at, err := time.Parse(time.RFC3339Nano, text)
+			if err != nil {
+				return nil, invalid
+			}
+			after[i] = at
+		}
+	}
+	return after, nil
+}
+
+// notificationSort is the only order the inbox is listed in
+const notificationSort = "-createdAt"
+
+// encodeNotificationCursor builds an opaque cursor for the page after n
+func encodeNotificationCursor(unreadOnly bool, n *models.Notification) string {
+	return encodeListCursor(notificationSort, filterHash(unreadOnly), []interface{}{n.CreatedAt.UTC().Format(time.RFC3339Nano), n.ID})
+}
+
+// decodeNotificationCursor reverses encodeNotificationCursor. The cursor must
+// come from a listing with the same unread filter.
+func decodeNotificationCursor(cursor string, unreadOnly bool) (time.Time, string, error) {
+	values, err := decodeListCursor(cursor, notificationSort, filterHash(unreadOnly), 2)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	text, _ := values[0].(string)
+	id, ok := values[1].(string)
+	createdAt, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil || !ok {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
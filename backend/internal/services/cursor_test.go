@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestAlertCursorRoundTrip(t *testing.T) {
+	keys, sort, err := parseAlertSort("-triggeredAt,stockName")
+	if err != nil {
+		t.Fatalf("parseAlertSort: %v", err)
+	}
+	triggeredAt := time.Date(2026, 3, 14, 9, 30, 0, 123, time.UTC)
+	filter := models.AlertFilter{Statuses: []string{"active", "triggered"}, StockSymbols: []string{"MTNGH"}}
+
+	tests := []struct {
+		name  string
+		alert *models.Alert
+		want  []interface{}
+	}{
+		{"triggered", &models.Alert{ID: "a1", StockName: "MTN Ghana", TriggeredAt: &triggeredAt}, []interface{}{triggeredAt, "MTN Ghana", "a1"}},
+		{"never triggered", &models.Alert{ID: "a2", StockName: "GCB Bank"}, []interface{}{nil, "GCB Bank", "a2"}},
+	}
+
+	for _, tt := range tests {
+		cursor := encodeAlertCursor(sort, filter, keys, tt.alert)
+
+		// The order of values within a filter doesn't matter
+		reordered := models.AlertFilter{Statuses: []string{"triggered", "active"}, StockSymbols: []string{"MTNGH"}}
+		after, err := decodeAlertCursor(cursor, sort, reordered, keys)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(after) != len(tt.want) {
+			t.Errorf("%s: decoded %v, want %v", tt.name, after, tt.want)
+			continue
+		}
+		for i := range tt.want {
+			if want, ok := tt.want[i].(time.Time); ok {
+				if got, _ := after[i].(time.Time); !got.Equal(want) {
+					t.Errorf("%s: value %d = %v, want %v", tt.name, i, after[i], want)
+				}
+			} else if after[i] != tt.want[i] {
+				t.Errorf("%s: value %d = %v, want %v", tt.name, i, after[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestDecodeAlertCursorRejectsBadCursors(t *testing.T) {
+	keys, sort, err := parseAlertSort("-createdAt")
+	if err != nil {
+		t.Fatalf("parseAlertSort: %v", err)
+	}
+	filter := models.AlertFilter{Statuses: []string{"active"}}
+	hash := alertFilterHash(filter)
+	encode := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload))
+	}
+	createdAt := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	valid := encodeAlertCursor(sort, filter, keys, &models.Alert{ID: "a1", CreatedAt: createdAt})
+
+	tests := []struct {
+		name   string
+		cursor string
+		filter models.AlertFilter
+	}{
+		{"not base64", "not a cursor!", filter},
+		{"truncated", valid[:len(valid)/2], filter},
+		{"not json", encode("2026-03-14T09:30:00Z|a1"), filter},
+		{"too few values", encode(`{"s":"-createdAt","f":"` + hash + `","a":["a1"]}`), filter},
+		{"too many values", encode(`{"s":"-createdAt","f":"` + hash + `","a":["2026-03-14T09:30:00Z","a1","a2"]}`), filter},
+		{"number for a time", encode(`{"s":"-createdAt","f":"` + hash + `","a":[1773480600,"a1"]}`), filter},
+		{"bad time", encode(`{"s":"-createdAt","f":"` + hash + `","a":["yesterday","a1"]}`), filter},
+		{"null created time", encode(`{"s":"-createdAt","f":"` + hash + `","a":[null,"a1"]}`), filter},
+		{"null id", encode(`{"s":"-createdAt","f":"` + hash + `","a":["2026-03-14T09:30:00Z",null]}`), filter},
+		{"other sort", encode(`{"s":"createdAt","f":"` + hash + `","a":["2026-03-14T09:30:00Z","a1"]}`), filter},
+		{"no filter hash", encode(`{"s":"-createdAt","a":["2026-03-14T09:30:00Z","a1"]}`), filter},
+		{"forged filter hash", encode(`{"s":"-createdAt","f":"0000000000000000","a":["2026-03-14T09:30:00Z","a1"]}`), filter},
+		{"used with other filters", valid, models.AlertFilter{Statuses: []string{"paused"}}},
+		{"used with no filters", valid, models.AlertFilter{}},
+	}
+
+	for _, tt := range tests {
+		if _, err := decodeAlertCursor(tt.cursor, sort, tt.filter, keys); !errors.Is(err, ErrInvalidAlertQuery) {
+			t.Errorf("%s: error = %v, want ErrInvalidAlertQuery", tt.name, err)
+		}
+	}
+}
+
+func TestNotificationCursor(t *testing.T) {
+	createdAt := time.Date(2026, 3, 14, 9, 30, 0, 123, time.UTC)
+	cursor := encodeNotificationCursor(true, &models.Notification{ID: "n1", CreatedAt: createdAt})
+
+	gotAt, gotID, err := decodeNotificationCursor(cursor, true)
+	if err != nil {
+		t.Fatalf("decodeNotificationCursor: %v", err)
+	}
+	if !gotAt.Equal(createdAt) || gotID != "n1" {
+		t.Errorf("decoded (%v, %q), want (%v, %q)", gotAt, gotID, createdAt, "n1")
+	}
+
+	encode := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload))
+	}
+	hash := filterHash(true)
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"old format", encode("2026-03-14T09:30:00Z|n1")},
+		{"alert cursor", encodeAlertCursor("-createdAt", models.AlertFilter{}, []models.AlertSort{{Field: models.AlertSortCreatedAt, Descending: true}}, &models.Alert{ID: "a1", CreatedAt: createdAt})},
+		{"bad time", encode(`{"s":"-createdAt","f":"` + hash + `","a":["yesterday","n1"]}`)},
+		{"null id", encode(`{"s":"-createdAt","f":"` + hash + `","a":["2026-03-14T09:30:00Z",null]}`)},
+		{"other sort", encode(`{"s":"createdAt","f":"` + hash + `","a":["2026-03-14T09:30:00Z","n1"]}`)},
+		{"used without the unread filter", encodeNotificationCursor(false, &models.Notification{ID: "n1", CreatedAt: createdAt})},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := decodeNotificationCursor(tt.cursor, true); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: error = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
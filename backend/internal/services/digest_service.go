@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+type DigestService struct {
+	digestRepo   repository.DigestStore
+	userRepo     repository.UserStore
+	alertRepo    repository.AlertStore
+	stockService *StockService
+	emailService *EmailService
+	config       *config.DigestConfig
+	location     *time.Location
+	weeklyDay    time.Weekday
+}
+
+func NewDigestService(
+	digestRepo repository.DigestStore,
+	userRepo repository.UserStore,
+	alertRepo repository.AlertStore,
+	stockService *StockService,
+	emailService *EmailService,
+	cfg *config.DigestConfig,
+) *DigestService {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		log.Printf("Invalid digest timezone %q, falling back to UTC: %v", cfg.Timezone, err)
+		location = time.UTC
+	}
+
+	weeklyDay := time.Friday
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), cfg.WeeklyDay) {
+			weeklyDay = d
+		}
+	}
+
+	return &DigestService{
+		digestRepo:   digestRepo,
+		userRepo:     userRepo,
+		alertRepo:    alertRepo,
+		stockService: stockService,
+		emailService: emailService,
+		config:       cfg,
+		location:     location,
+		weeklyDay:    weeklyDay,
+	}
+}
+
+// Enqueue holds a triggered alert for the user's next digest instead of emailing it now
+func (s *DigestService) Enqueue(ctx context.Context, alert *models.Alert, triggerPrice float64) error {
+	entry := &models.DigestEntry{
+		ID:             uuid.New().String(),
+		UserID:         alert.UserID,
+		AlertID:        alert.ID,
+		StockSymbol:    alert.StockSymbol,
+		StockName:      alert.StockName,
+		AlertType:      alert.AlertType,
+		ThresholdPrice: alert.ThresholdPrice,
+		TriggerPrice:   triggerPrice,
+		TriggeredAt:    time.Now(),
+	}
+
+	if err := s.digestRepo.Create(ctx, entry); err != nil {
+		return fmt.Errorf("failed to queue digest entry: %w", err)
+	}
+
+	return nil
+}
+
+func (s *DigestService) StartScheduler() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	log.Printf("Starting digest scheduler (%02d:00 %s)...", s.config.Hour, s.location)
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.sendDueDigests(context.Background(), time.Now()); err != nil {
+				log.Printf("Error sending digests: %v", err)
+			}
+		}
+	}
+}
+
+func (s *DigestService) sendDueDigests(ctx context.Context, now time.Time) error {
+	userIDs, err := s.digestRepo.GetUsersWithPending(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get users with pending digests: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if err := s.processUser(ctx, userID, now); err != nil {
+			log.Printf("Error sending digest to user %s: %v", userID, err)
+		}
+	}
+
+	return nil
+}
+
+// QuietHoursEnd reports whether now is inside the user's quiet hours and when they end
+func (s *DigestService) QuietHoursEnd(prefs *models.UserPreferences, now time.Time) (time.Time, bool) {
+	return quietHoursEnd(prefs, now, s.location)
+}
+
+// UserLocation returns the user's timezone, falling back to the digest timezone
+func (s *DigestService) UserLocation(prefs *models.UserPreferences) *time.Location {
+	return preferenceLocation(prefs, s.location)
+}
+
+func (s *DigestService) processUser(ctx context.Context, userID string, now time.Time) error {
+	prefs, err := s.userRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		prefs = nil
+	}
+	frequency := models.NotificationFrequencyDaily
+	if prefs != nil && prefs.NotificationFrequency != "" {
+		frequency = prefs.NotificationFrequency
+	}
+
+	entries, err := s.digestRepo.GetPendingByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get pending entries: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	quietEnd, inQuiet := s.QuietHoursEnd(prefs, now)
+
+	var period string
+	switch frequency {
+	case models.NotificationFrequencyDaily, models.NotificationFrequencyWeekly:
+		// A digest is due once the most recent slot has passed, provided it hasn't
+		// already gone out and something was waiting before that slot
+		slot := s.lastSlot(frequency, now, preferenceLocation(prefs, s.location))
+		if !entries[0].TriggeredAt.Before(slot) {
+			return nil
+		}
+		lastSent, err := s.digestRepo.GetLastSentAt(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get last digest time: %w", err)
+		}
+		if lastSent != nil && !lastSent.Before(slot) {
+			return nil
+		}
+		period = DigestPeriodDaily
+		if frequency == models.NotificationFrequencyWeekly {
+			period = DigestPeriodWeekly
+		}
+	default:
+		// Immediate users only have entries held back by quiet hours in
+		// summary mode; send them as soon as the quiet window is over
+		if inQuiet {
+			return nil
+		}
+		period = DigestPeriodQuietHours
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	data := DigestEmailData{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		Period:    period,
+		Alerts:    entries,
+		Watchlist: s.marketSnapshot(ctx, userID, entries),
+	}
+
+	email, err := s.emailService.RenderDigestEmail(data, preferenceLocale(prefs))
+	if err != nil {
+		return err
+	}
+	msg := newOutboxEmail(user, models.OutboxKindDigest, email)
+	if inQuiet {
+		msg.NextAttemptAt = quietEnd
+	}
+
+	// Queue the email and mark its entries sent together, so a failure can't
+	// leave entries pending that will go out again in the next digest
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	sent, err := s.digestRepo.MarkSentWithMessage(ctx, ids, now, msg)
+	if err != nil {
+		return fmt.Errorf("failed to queue digest: %w", err)
+	}
+	if !sent {
+		return nil
+	}
+
+	log.Printf("Queued %s digest with %d alerts to user %s", period, len(entries), userID)
+	return nil
+}
+
+// lastSlot returns the most recent scheduled digest time at or before now
+func (s *DigestService) lastSlot(frequency string, now time.Time, location *time.Location) time.Time {
+	local := now.In(location)
+	slot := time.Date(local.Year(), local.Month(), local.Day(), s.config.Hour, 0, 0, 0, location)
+
+	if frequency == models.NotificationFrequencyWeekly {
+		slot = slot.AddDate(0, 0, -int((local.Weekday()-s.weeklyDay+7)%7))
+		if slot.After(local) {
+			slot = slot.AddDate(0, 0, -7)
+		}
+		return slot
+	}
+
+	if slot.After(local) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	return slot
+}
+
+// marketSnapshot returns the current board for every symbol the user watches
+func (s *DigestService) marketSnapshot(ctx context.Context, userID string, entries []*models.DigestEntry) []models.EnhancedStock {
+	watched := make(map[string]bool)
+	for _, entry := range entries {
+		watched[strings.ToUpper(entry.StockSymbol)] = true
+	}
+	if alerts, err := s.alertRepo.GetByUserID(ctx, userID, models.AlertFilter{}); err == nil {
+		for _, alert := range alerts {
+			watched[strings.ToUpper(alert.StockSymbol)] = true
+		}
+	}
+
+	stocks, err := s.stockService.GetAllStocks()
+	if err != nil {
+		log.Printf("Failed to get market snapshot for digest: %v", err)
+		return nil
+	}
+
+	var snapshot []models.EnhancedStock
+	for _, stock := range stocks {
+		if watched[strings.ToUpper(stock.Symbol)] {
+			snapshot = append(snapshot, stock)
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+// snoozeDuration is how long the snooze link in alert emails silences an alert
+const snoozeDuration = 24 * time.Hour
+
+// EmailActionService carries out the signed one-click actions linked from
+// notification emails. The token is the only credential, so every action is
+// scoped to the user and alert it was issued for.
+type EmailActionService struct {
+	alertRepo     repository.AlertStore
+	userRepo      repository.UserStore
+	emailService  *EmailService
+	digestService *DigestService
+	links         *ActionLinks
+}
+
+func NewEmailActionService(
+	alertRepo repository.AlertStore,
+	userRepo repository.UserStore,
+	emailService *EmailService,
+	digestService *DigestService,
+	links *ActionLinks,
+) *EmailActionService {
+	return &EmailActionService{
+		alertRepo:     alertRepo,
+		userRepo:      userRepo,
+		emailService:  emailService,
+		digestService: digestService,
+		links:         links,
+	}
+}
+
+// emailAction is a verified token resolved to the records it acts on
+type emailAction struct {
+	claims *ActionClaims
+	user   *models.User
+	prefs  *models.UserPreferences
+	alert  *models.Alert // nil for unsubscribe
+}
+
+// ConfirmPage renders the page asking the user to confirm the token's action.
+// Opening a link never changes anything, so mail scanners that prefetch links
+// can't pause alerts or unsubscribe users by accident.
+func (s *EmailActionService) ConfirmPage(ctx context.Context, token string) (string, error) {
+	action, err := s.load(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	var message string
+	if action.alert != nil {
+		name, label := s.alertLabel(s.locale(action), action.alert)
+		message = s.emailService.ActionMessage(s.locale(action), action.claims.Action+".confirm", name, label)
+	} else {
+		message = s.emailService.ActionMessage(s.locale(action), action.claims.Action+".confirm", action.user.Email)
+	}
+
+	return s.emailService.RenderActionPage(s.locale(action), ActionPageData{
+		Message:   message,
+		ActionURL: "?token=" + url.QueryEscape(token),
+	})
+}
+
+// alertLabel returns the name and symbol an alert is shown with. Portfolio
+// alerts have no symbol, so they're labelled as a portfolio instead.
+func (s *EmailActionService) alertLabel(locale string, alert *models.Alert) (string, string) {
+	if alert.Scope == models.AlertScopePortfolio {
+		return alert.StockName, s.emailService.ActionMessage(locale, "scope.portfolio")
+	}
+	return alert.StockName, alert.StockSymbol
+}
+
+// Perform carries out the token's action and renders the outcome page
+func (s *EmailActionService) Perform(ctx context.Context, token string) (string, error) {
+	action, err := s.load(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	locale := s.locale(action)
+	var message string
+	var name, label string
+	if action.alert != nil {
+		name, label = s.alertLabel(locale, action.alert)
+	}
+
+	switch action.claims.Action {
+	case ActionPause:
+		action.alert.Status = models.AlertStatusPaused
+		action.alert.SnoozedUntil = nil
+		message = s.emailService.ActionMessage(locale, ActionPause+".done", name, label)
+	case ActionRearm:
+		action.alert.Status = models.AlertStatusActive
+		action.alert.SnoozedUntil = nil
+		message = s.emailService.ActionMessage(locale, ActionRearm+".done", name, label)
+	case ActionSnooze:
+		until := time.Now().UTC().Add(snoozeDuration)
+		action.alert.Status = models.AlertStatusActive
+		action.alert.SnoozedUntil = &until
+		local := until.In(s.digestService.UserLocation(action.prefs)).Format("02 Jan 2006 15:04 MST")
+		message = s.emailService.ActionMessage(locale, ActionSnooze+".done", name, label, local)
+	case ActionUnsubscribe:
+		if err := s.unsubscribe(ctx, action); err != nil {
+			return "", err
+		}
+		message = s.emailService.ActionMessage(locale, ActionUnsubscribe+".done", action.user.Email)
+	}
+
+	if action.alert != nil {
+		if err := s.alertRepo.Update(ctx, action.alert); err != nil {
+			return "", fmt.Errorf("failed to update alert: %w", err)
+		}
+	}
+
+	return s.emailService.RenderActionPage(locale, ActionPageData{Message: message})
+}
+
+// ErrorPage renders the page shown when an action can't be carried out
+func (s *EmailActionService) ErrorPage(err error) (string, error) {
+	key := "failed"
+	if errors.Is(err, ErrInvalidActionLink) || errors.Is(err, ErrExpiredActionLink) {
+		key = "invalid"
+	}
+	return s.emailService.RenderActionPage(DefaultLocale, ActionPageData{
+		Message: s.emailService.ActionMessage(DefaultLocale, key),
+	})
+}
+
+func (s *EmailActionService) load(ctx context.Context, token string) (*emailAction, error) {
+	claims, err := s.links.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidActionLink
+		}
+		return nil, err
+	}
+	prefs, _ := s.userRepo.GetPreferences(ctx, user.ID)
+
+	action := &emailAction{claims: claims, user: user, prefs: prefs}
+
+	switch claims.Action {
+	case ActionPause, ActionRearm, ActionSnooze:
+		alert, err := s.alertRepo.GetByID(ctx, claims.AlertID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrInvalidActionLink
+			}
+			return nil, err
+		}
+		// The token names both, but check anyway in case the alert changed hands
+		if alert.UserID != user.ID || alert.Status == models.AlertStatusDeleted {
+			return nil, ErrInvalidActionLink
+		}
+		action.alert = alert
+	case ActionUnsubscribe:
+	default:
+		return nil, ErrInvalidActionLink
+	}
+
+	return action, nil
+}
+
+func (s *EmailActionService) unsubscribe(ctx context.Context, action *emailAction) error {
+	prefs := action.prefs
+	if prefs == nil {
+		now := time.Now()
+		prefs = &models.UserPreferences{
+			ID:                    uuid.New().String(),
+			UserID:                action.user.ID,
+			EmailNotifications:    false,
+			NotificationFrequency: models.NotificationFrequencyImmediate,
+			QuietHoursMode:        models.QuietHoursModeHold,
+			Locale:                DefaultLocale,
+			CreatedAt:             now,
+			UpdatedAt:             now,
+		}
+		return s.userRepo.CreatePreferences(ctx, prefs)
+	}
+
+	prefs.EmailNotifications = false
+	return s.userRepo.UpdatePreferences(ctx, prefs)
+}
+
+func (s *EmailActionService) locale(action *emailAction) string {
+	return preferenceLocale(action.prefs)
+}
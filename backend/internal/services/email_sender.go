@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"shares-alert-backend/internal/config"
+)
+
+// EmailSender hands a fully built RFC 5322 message to a transport
+type EmailSender interface {
+	Send(from string, to []string, msg []byte) error
+}
+
+// Email transports
+const (
+	EmailTransportSMTP   = "smtp"
+	EmailTransportFile   = "file"
+	EmailTransportMemory = "memory"
+)
+
+// NewEmailSender builds the sender selected by cfg.Transport
+func NewEmailSender(cfg *config.EmailConfig) (EmailSender, error) {
+	switch cfg.Transport {
+	case "", EmailTransportSMTP:
+		return NewSMTPSender(cfg)
+	case EmailTransportFile:
+		if err := os.MkdirAll(cfg.CaptureDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create email capture directory: %w", err)
+		}
+		return NewCaptureSender(cfg.CaptureDir), nil
+	case EmailTransportMemory:
+		return NewCaptureSender(""), nil
+	}
+	return nil, fmt.Errorf("unknown email transport %q", cfg.Transport)
+}
+
+// CapturedEmail is a message recorded by a CaptureSender
+type CapturedEmail struct {
+	From       string
+	To         []string
+	Raw        []byte
+	CapturedAt time.Time
+}
+
+// CaptureSender records outgoing mail instead of delivering it, so developers
+// and tests can inspect it without a mail server. Messages are kept in memory
+// and, if dir is set, also written there as .eml files.
+type CaptureSender struct {
+	dir      string
+	mu       sync.Mutex
+	messages []CapturedEmail
+}
+
+func NewCaptureSender(dir string) *CaptureSender {
+	return &CaptureSender{dir: dir}
+}
+
+func (c *CaptureSender) Send(from string, to []string, msg []byte) error {
+	captured := CapturedEmail{
+		From:       from,
+		To:         append([]string(nil), to...),
+		Raw:        append([]byte(nil), msg...),
+		CapturedAt: time.Now(),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, captured)
+
+	if c.dir == "" {
+		return nil
+	}
+	name := fmt.Sprintf("%s-%03d-%s.eml", captured.CapturedAt.Format("20060102T150405.000000000"),
+		len(c.messages), sanitizeFileName(strings.Join(to, ",")))
+	return os.WriteFile(filepath.Join(c.dir, name), msg, 0644)
+}
+
+// Messages returns a copy of everything captured so far, oldest first
+func (c *CaptureSender) Messages() []CapturedEmail {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CapturedEmail(nil), c.messages...)
+}
+
+// Reset forgets captured messages; files already written are kept
+func (c *CaptureSender) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '@', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -3,15 +3,25 @@ package services
 import (
 	"bytes"
 	"fmt"
-	"html/template"
-	"net/smtp"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 
 	"shares-alert-backend/internal/config"
 	"shares-alert-backend/internal/models"
 )
 
 type EmailService struct {
-	config *config.EmailConfig
+	config    *config.EmailConfig
+	templates *emailTemplates
+	links     *ActionLinks
+	sender    EmailSender
 }
 
 type AlertEmailData struct {
@@ -21,24 +31,100 @@ type AlertEmailData struct {
 	CurrentPrice   float64
 	ThresholdPrice float64
 	AlertType      string
+	Links          AlertEmailLinks
 }
 
-func NewEmailService(cfg *config.EmailConfig) *EmailService {
-	return &EmailService{
-		config: cfg,
-	}
+// AlertEmailLinks are the signed one-click actions offered in an alert email
+type AlertEmailLinks struct {
+	Pause       string
+	Rearm       string
+	Snooze      string
+	Unsubscribe string
+}
+
+// PortfolioAlertEmailData fills the portfolio alert template. Measure is the
+// portfolio's value, its drawdown percent or the largest holding's weight,
+// depending on AlertType.
+type PortfolioAlertEmailData struct {
+	UserName      string
+	PortfolioName string
+	AlertType     string
+	Direction     string
+	Value         float64
+	Threshold     float64 // a value in cedis, or a percent
+	Measure       float64
+	Holdings      []*models.Holding // the holdings responsible
+	Links         AlertEmailLinks
+}
+
+type WelcomeEmailData struct {
+	UserName string
+}
+
+// LifecycleEmailData fills the nudge and re-engagement templates
+type LifecycleEmailData struct {
+	UserName       string
+	Days           int // days since sign-up (nudge) or since the last login (re-engagement)
+	AppURL         string
+	UnsubscribeURL string
+}
+
+// MagicLinkEmailData fills the passwordless sign-in email
+type MagicLinkEmailData struct {
+	LoginURL string
+	Minutes  int // how long the link stays valid
+}
+
+// AccountDeletionEmailData fills the email confirming a deletion request
+type AccountDeletionEmailData struct {
+	UserName     string
+	DeletionDate string // YYYY-MM-DD
+	AppURL       string
 }
 
-func (s *EmailService) SendAlertEmail(user *models.User, alert *models.Alert) error {
-	if s.config.SMTPUser == "" || s.config.SMTPPassword == "" {
-		return fmt.Errorf("email service not configured")
+// Digest periods, used to pick the digest's title and footer
+const (
+	DigestPeriodDaily      = "daily"
+	DigestPeriodWeekly     = "weekly"
+	DigestPeriodQuietHours = "quiet_hours"
+)
+
+type DigestEmailData struct {
+	UserID         string
+	UnsubscribeURL string
+	UserName       string
+	Period         string // DigestPeriodDaily, DigestPeriodWeekly or DigestPeriodQuietHours
+	Alerts         []*models.DigestEntry
+	Watchlist      []models.EnhancedStock
+}
+
+func NewEmailService(cfg *config.EmailConfig, links *ActionLinks, sender EmailSender) (*EmailService, error) {
+	templates, err := loadEmailTemplates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email templates: %w", err)
 	}
 
+	return &EmailService{
+		config:    cfg,
+		templates: templates,
+		links:     links,
+		sender:    sender,
+	}, nil
+}
+
+// Locales lists the locales emails can be rendered in
+func (s *EmailService) Locales() []string {
+	return s.templates.locales()
+}
+
+// RenderAlertEmail builds the email for a triggered alert in the given locale
+func (s *EmailService) RenderAlertEmail(user *models.User, alert *models.Alert, locale string) (*RenderedEmail, error) {
 	data := AlertEmailData{
-		UserName:     user.Name,
-		StockSymbol:  alert.StockSymbol,
-		StockName:    alert.StockName,
-		AlertType:    alert.AlertType,
+		UserName:    user.Name,
+		StockSymbol: alert.StockSymbol,
+		StockName:   alert.StockName,
+		AlertType:   alert.AlertType,
+		Links:       s.alertLinks(user, alert),
 	}
 
 	if alert.CurrentPrice != nil {
@@ -48,148 +134,289 @@ func (s *EmailService) SendAlertEmail(user *models.User, alert *models.Alert) er
 		data.ThresholdPrice = *alert.ThresholdPrice
 	}
 
-	subject := fmt.Sprintf("Stock Alert: %s (%s)", alert.StockName, alert.StockSymbol)
-	body, err := s.generateEmailBody(data)
+	locale = s.templates.resolveLocale(locale)
+	subject := s.templates.translate(locale, "alert.subject", alert.StockName, alert.StockSymbol)
+	email, err := s.templates.render(TemplateAlert, locale, subject, data)
 	if err != nil {
-		return fmt.Errorf("failed to generate email body: %w", err)
-	}
-
-	return s.sendEmail(user.Email, subject, body)
-}
-
-func (s *EmailService) SendWelcomeEmail(user *models.User) error {
-	if s.config.SMTPUser == "" || s.config.SMTPPassword == "" {
-		return fmt.Errorf("email service not configured")
-	}
-
-	subject := "Welcome to Shares Alert Ghana!"
-	body := s.generateWelcomeEmailBody(user.Name)
-
-	return s.sendEmail(user.Email, subject, body)
-}
-
-func (s *EmailService) sendEmail(to, subject, body string) error {
-	auth := smtp.PlainAuth("", s.config.SMTPUser, s.config.SMTPPassword, s.config.SMTPHost)
-
-	msg := []byte(fmt.Sprintf(
-		"From: %s <%s>\r\n"+
-			"To: %s\r\n"+
-			"Subject: %s\r\n"+
-			"MIME-Version: 1.0\r\n"+
-			"Content-Type: text/html; charset=UTF-8\r\n"+
-			"\r\n"+
-			"%s\r\n",
-		s.config.FromName, s.config.FromEmail, to, subject, body))
-
-	addr := s.config.SMTPHost + ":" + s.config.SMTPPort
-	return smtp.SendMail(addr, auth, s.config.FromEmail, []string{to}, msg)
-}
-
-func (s *EmailService) generateEmailBody(data AlertEmailData) (string, error) {
-	tmpl := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <title>Stock Alert</title>
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background-color: #2563eb; color: white; padding: 20px; text-align: center; }
-        .content { padding: 20px; background-color: #f9f9f9; }
-        .alert-box { background-color: #fff; border-left: 4px solid #2563eb; padding: 15px; margin: 20px 0; }
-        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
-        .price { font-size: 24px; font-weight: bold; color: #059669; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>Stock Alert Triggered!</h1>
-        </div>
-        <div class="content">
-            <p>Hello {{.UserName}},</p>
-            
-            <div class="alert-box">
-                <h3>{{.StockName}} ({{.StockSymbol}})</h3>
-                {{if eq .AlertType "price_threshold"}}
-                    <p>Your price threshold alert has been triggered!</p>
-                    <p>Current Price: <span class="price">GH₵ {{printf "%.2f" .CurrentPrice}}</span></p>
-                    <p>Your Threshold: GH₵ {{printf "%.2f" .ThresholdPrice}}</p>
-                {{else if eq .AlertType "dividend_announcement"}}
-                    <p>A dividend has been announced for {{.StockName}}!</p>
-                {{else if eq .AlertType "ipo_alert"}}
-                    <p>IPO alert for {{.StockName}} has been triggered!</p>
-                {{end}}
-            </div>
-            
-            <p>You can view more details and manage your alerts by logging into your dashboard.</p>
-            
-            <p>Best regards,<br>The Shares Alert Ghana Team</p>
-        </div>
-        <div class="footer">
-            <p>This is an automated message from Shares Alert Ghana. Please do not reply to this email.</p>
-        </div>
-    </div>
-</body>
-</html>
-`
-
-	t, err := template.New("alert").Parse(tmpl)
+		return nil, fmt.Errorf("failed to generate email body: %w", err)
+	}
+	email.UnsubscribeURL = data.Links.Unsubscribe
+
+	return email, nil
+}
+
+// RenderPortfolioAlertEmail builds the email for a triggered portfolio alert
+func (s *EmailService) RenderPortfolioAlertEmail(user *models.User, alert *models.Alert, measure float64, holdings []*models.Holding, locale string) (*RenderedEmail, error) {
+	data := PortfolioAlertEmailData{
+		UserName:      user.Name,
+		PortfolioName: alert.StockName,
+		AlertType:     alert.AlertType,
+		Direction:     alert.Direction,
+		Measure:       measure,
+		Holdings:      holdings,
+		Links:         s.alertLinks(user, alert),
+	}
+	if alert.CurrentPrice != nil {
+		data.Value = *alert.CurrentPrice
+	}
+	if alert.ThresholdPrice != nil {
+		data.Threshold = *alert.ThresholdPrice
+	}
+	if alert.ThresholdPercent != nil {
+		data.Threshold = *alert.ThresholdPercent
+	}
+
+	locale = s.templates.resolveLocale(locale)
+	subject := s.templates.translate(locale, "portfolio_alert.subject", alert.StockName)
+	email, err := s.templates.render(TemplatePortfolioAlert, locale, subject, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate email body: %w", err)
+	}
+	email.UnsubscribeURL = data.Links.Unsubscribe
+
+	return email, nil
+}
+
+// alertLinks signs the one-click actions for an alert email
+func (s *EmailService) alertLinks(user *models.User, alert *models.Alert) AlertEmailLinks {
+	return AlertEmailLinks{
+		Pause:       s.links.URL(ActionPause, user.ID, alert.ID),
+		Rearm:       s.links.URL(ActionRearm, user.ID, alert.ID),
+		Snooze:      s.links.URL(ActionSnooze, user.ID, alert.ID),
+		Unsubscribe: s.links.URL(ActionUnsubscribe, user.ID, ""),
+	}
+}
+
+// RenderWelcomeEmail builds the welcome email for a new user in the given locale
+func (s *EmailService) RenderWelcomeEmail(user *models.User, locale string) (*RenderedEmail, error) {
+	locale = s.templates.resolveLocale(locale)
+	subject := s.templates.translate(locale, "welcome.subject")
+	email, err := s.templates.render(TemplateWelcome, locale, subject, WelcomeEmailData{UserName: user.Name})
 	if err != nil {
-		return "", err
-	}
-
-	var buf bytes.Buffer
-	if err := t.Execute(&buf, data); err != nil {
-		return "", err
-	}
-
-	return buf.String(), nil
-}
-
-func (s *EmailService) generateWelcomeEmailBody(userName string) string {
-	return fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <title>Welcome to Shares Alert Ghana</title>
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background-color: #2563eb; color: white; padding: 20px; text-align: center; }
-        .content { padding: 20px; background-color: #f9f9f9; }
-        .footer { text-align: center; padding: 20px; font-size: 12px; color: #666; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>Welcome to Shares Alert Ghana!</h1>
-        </div>
-        <div class="content">
-            <p>Hello %s,</p>
-            
-            <p>Welcome to Shares Alert Ghana! We're excited to have you on board.</p>
-            
-            <p>With our platform, you can:</p>
-            <ul>
-                <li>Track Ghana Stock Exchange prices in real-time</li>
-                <li>Set up custom price alerts for your favorite stocks</li>
-                <li>Receive notifications when your alerts are triggered</li>
-                <li>Stay informed about dividend announcements and IPOs</li>
-            </ul>
-            
-            <p>Start by setting up your first stock alert and never miss an important price movement again!</p>
-            
-            <p>Best regards,<br>The Shares Alert Ghana Team</p>
-        </div>
-        <div class="footer">
-            <p>This is an automated message from Shares Alert Ghana.</p>
-        </div>
-    </div>
-</body>
-</html>
-`, userName)
-}
\ No newline at end of file
+		return nil, fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return email, nil
+}
+
+// RenderLifecycleEmail builds a nudge or re-engagement email in the given locale
+func (s *EmailService) RenderLifecycleEmail(name string, user *models.User, days int, locale string) (*RenderedEmail, error) {
+	if name != TemplateNudge && name != TemplateReengagement {
+		return nil, fmt.Errorf("unknown lifecycle email %q", name)
+	}
+
+	locale = s.templates.resolveLocale(locale)
+	data := LifecycleEmailData{
+		UserName:       user.Name,
+		Days:           days,
+		AppURL:         s.config.AppURL,
+		UnsubscribeURL: s.links.URL(ActionUnsubscribe, user.ID, ""),
+	}
+	email, err := s.templates.render(name, locale, s.templates.translate(locale, name+".subject"), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate email body: %w", err)
+	}
+	email.UnsubscribeURL = data.UnsubscribeURL
+
+	return email, nil
+}
+
+// RenderMagicLinkEmail builds the passwordless sign-in email in the given
+// locale. The link opens the frontend, which exchanges the token for a session.
+func (s *EmailService) RenderMagicLinkEmail(token string, minutes int, locale string) (*RenderedEmail, error) {
+	locale = s.templates.resolveLocale(locale)
+	subject := s.templates.translate(locale, "magic_link.subject")
+	data := MagicLinkEmailData{
+		LoginURL: s.config.AppURL + "/auth/magic-link?token=" + url.QueryEscape(token),
+		Minutes:  minutes,
+	}
+	email, err := s.templates.render(TemplateMagicLink, locale, subject, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return email, nil
+}
+
+// RenderAccountDeletionEmail confirms that the user's account is scheduled
+// for deletion and tells them how to keep it
+func (s *EmailService) RenderAccountDeletionEmail(user *models.User, scheduledAt time.Time, locale string) (*RenderedEmail, error) {
+	locale = s.templates.resolveLocale(locale)
+	subject := s.templates.translate(locale, "account_deletion.subject")
+	data := AccountDeletionEmailData{
+		UserName:     user.Name,
+		DeletionDate: scheduledAt.Format("2006-01-02"),
+		AppURL:       s.config.AppURL,
+	}
+	email, err := s.templates.render(TemplateAccountDeletion, locale, subject, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	return email, nil
+}
+
+// RenderDigestEmail builds a daily, weekly or quiet-hours digest in the given locale
+func (s *EmailService) RenderDigestEmail(data DigestEmailData, locale string) (*RenderedEmail, error) {
+	locale = s.templates.resolveLocale(locale)
+	subject := s.templates.translate(locale, "digest.subject."+data.Period)
+	data.UnsubscribeURL = s.links.URL(ActionUnsubscribe, data.UserID, "")
+	email, err := s.templates.render(TemplateDigest, locale, subject, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate email body: %w", err)
+	}
+	email.UnsubscribeURL = data.UnsubscribeURL
+
+	return email, nil
+}
+
+// Preview renders any template with sample data, for checking copy and layout
+func (s *EmailService) Preview(name, locale string) (*RenderedEmail, error) {
+	user := &models.User{ID: "preview", Name: "Ama Mensah", Email: "ama@example.com"}
+	threshold, current := 0.90, 0.92
+
+	switch name {
+	case TemplateAlert:
+		return s.RenderAlertEmail(user, &models.Alert{
+			ID:             "preview",
+			StockSymbol:    "MTN",
+			StockName:      "MTN Ghana",
+			AlertType:      models.AlertTypePriceThreshold,
+			ThresholdPrice: &threshold,
+			CurrentPrice:   &current,
+		}, locale)
+	case TemplatePortfolioAlert:
+		value, limit := 10450.0, 50.0
+		return s.RenderPortfolioAlertEmail(user, &models.Alert{
+			ID:               "preview",
+			Scope:            models.AlertScopePortfolio,
+			StockName:        "Retirement",
+			AlertType:        models.AlertTypePortfolioAllocation,
+			ThresholdPercent: &limit,
+			CurrentPrice:     &value,
+		}, 62.4, []*models.Holding{
+			{StockSymbol: "MTNGH", StockName: "MTN Ghana", MarketValue: 6520.80, Weight: 62.4, DayChange: 85.00},
+		}, locale)
+	case TemplateWelcome:
+		return s.RenderWelcomeEmail(user, locale)
+	case TemplateNudge:
+		return s.RenderLifecycleEmail(TemplateNudge, user, 3, locale)
+	case TemplateReengagement:
+		return s.RenderLifecycleEmail(TemplateReengagement, user, 30, locale)
+	case TemplateMagicLink:
+		return s.RenderMagicLinkEmail("preview", 15, locale)
+	case TemplateAccountDeletion:
+		return s.RenderAccountDeletionEmail(user, time.Now().AddDate(0, 0, 14), locale)
+	case TemplateDigest:
+		now := time.Now()
+		return s.RenderDigestEmail(DigestEmailData{
+			UserID:   user.ID,
+			UserName: user.Name,
+			Period:   DigestPeriodDaily,
+			Alerts: []*models.DigestEntry{
+				{StockSymbol: "MTN", StockName: "MTN Ghana", AlertType: models.AlertTypePriceThreshold,
+					ThresholdPrice: &threshold, TriggerPrice: current, TriggeredAt: now.Add(-3 * time.Hour)},
+				{StockSymbol: "GCB", StockName: "GCB Bank Limited", AlertType: models.AlertTypeDividendAnnouncement,
+					TriggerPrice: 4.20, TriggeredAt: now.Add(-1 * time.Hour)},
+			},
+			Watchlist: []models.EnhancedStock{
+				{Symbol: "MTN", CurrentPrice: current, Change: 0.02, ChangePercent: 2.22, Volume: 125000},
+				{Symbol: "GCB", CurrentPrice: 4.20, Change: -0.05, ChangePercent: -1.18, Volume: 67000},
+			},
+		}, locale)
+	}
+
+	return nil, fmt.Errorf("unknown email template %q", name)
+}
+
+// Send delivers an already rendered email
+func (s *EmailService) Send(to string, email *RenderedEmail) error {
+	msg, err := s.buildMessage(to, email)
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+
+	return s.sender.Send(s.config.FromEmail, []string{to}, msg)
+}
+
+// buildMessage assembles a multipart/alternative message with a plain-text
+// part first and the HTML part last, as RFC 2046 prefers
+func (s *EmailService) buildMessage(to string, email *RenderedEmail) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", email.Text},
+		{"text/html; charset=UTF-8", email.HTML},
+	}
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		w, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s <%s>\r\n", mime.QEncoding.Encode("UTF-8", s.config.FromName), s.config.FromEmail)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", email.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", uuid.New().String(), s.messageIDDomain())
+	if email.UnsubscribeURL != "" {
+		// RFC 8058 one-click unsubscribe
+		fmt.Fprintf(&msg, "List-Unsubscribe: <%s>\r\n", email.UnsubscribeURL)
+		fmt.Fprintf(&msg, "List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	}
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n", mw.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// messageIDDomain is the right-hand side of Message-IDs: the DKIM domain if
+// set, otherwise the sender's domain
+func (s *EmailService) messageIDDomain() string {
+	if s.config.DKIMDomain != "" {
+		return s.config.DKIMDomain
+	}
+	if at := strings.LastIndex(s.config.FromEmail, "@"); at >= 0 {
+		return s.config.FromEmail[at+1:]
+	}
+	return "localhost"
+}
+
+// ActionPageData is shown by the email action handlers, either asking the
+// user to confirm an action or reporting its outcome
+type ActionPageData struct {
+	Message   string
+	ActionURL string // set when the page asks for confirmation
+}
+
+// RenderActionPage renders the HTML page shown when an email action link is opened
+func (s *EmailService) RenderActionPage(locale string, data ActionPageData) (string, error) {
+	return s.templates.renderPage(TemplateActionPage, s.templates.resolveLocale(locale), data)
+}
+
+// ActionMessage returns localised action page text, e.g. key "pause.confirm"
+// for the confirmation prompt or "pause.done" for the outcome
+func (s *EmailService) ActionMessage(locale, key string, args ...interface{}) string {
+	return s.templates.translate(s.templates.resolveLocale(locale), "action."+key, args...)
+}
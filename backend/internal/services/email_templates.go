@@ -0,0 +1,194 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"sort"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl templates/locales/*.json
+var templateFS embed.FS
+
+// DefaultLocale is used when a user has no locale or an unsupported one
+const DefaultLocale = "en"
+
+// Email template names
+const (
+	TemplateAlert           = "alert"
+	TemplateDigest          = "digest"
+	TemplateWelcome         = "welcome"
+	TemplateNudge           = "nudge"
+	TemplateReengagement    = "reengagement"
+	TemplateMagicLink       = "magic_link"
+	TemplateAccountDeletion = "account_deletion"
+	TemplatePortfolioAlert  = "portfolio_alert"
+
+	// TemplateActionPage is a web page, not an email, so it has no text variant
+	TemplateActionPage = "action"
+)
+
+var templateNames = []string{TemplateAlert, TemplateDigest, TemplateWelcome, TemplateNudge, TemplateReengagement, TemplateMagicLink,
+	TemplateAccountDeletion, TemplatePortfolioAlert}
+
+// RenderedEmail is a fully rendered message with HTML and plain-text alternatives
+type RenderedEmail struct {
+	Subject string
+	HTML    string
+	Text    string
+
+	// UnsubscribeURL, when set, is advertised in a List-Unsubscribe header
+	UnsubscribeURL string
+}
+
+// emailTemplates holds every template parsed once per locale, so rendering
+// only needs a map lookup
+type emailTemplates struct {
+	catalogs map[string]map[string]string
+	html     map[string]map[string]*htmltemplate.Template // locale -> name -> template
+	text     map[string]map[string]*texttemplate.Template
+}
+
+func loadEmailTemplates() (*emailTemplates, error) {
+	files, err := templateFS.ReadDir("templates/locales")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &emailTemplates{
+		catalogs: make(map[string]map[string]string),
+		html:     make(map[string]map[string]*htmltemplate.Template),
+		text:     make(map[string]map[string]*texttemplate.Template),
+	}
+
+	for _, file := range files {
+		locale := file.Name()[:len(file.Name())-len(path.Ext(file.Name()))]
+		raw, err := templateFS.ReadFile("templates/locales/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+		catalog := make(map[string]string)
+		if err := json.Unmarshal(raw, &catalog); err != nil {
+			return nil, fmt.Errorf("invalid locale file %s: %w", file.Name(), err)
+		}
+		t.catalogs[locale] = catalog
+	}
+
+	if _, ok := t.catalogs[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("missing %s locale", DefaultLocale)
+	}
+
+	for locale := range t.catalogs {
+		funcs := t.funcs(locale)
+		t.html[locale] = make(map[string]*htmltemplate.Template)
+		t.text[locale] = make(map[string]*texttemplate.Template)
+
+		page, err := htmltemplate.New(TemplateActionPage+".html.tmpl").Funcs(htmltemplate.FuncMap(funcs)).
+			ParseFS(templateFS, "templates/"+TemplateActionPage+".html.tmpl", "templates/layout.html.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s page template: %w", TemplateActionPage, err)
+		}
+		t.html[locale][TemplateActionPage] = page
+
+		for _, name := range templateNames {
+			html, err := htmltemplate.New(name+".html.tmpl").Funcs(htmltemplate.FuncMap(funcs)).
+				ParseFS(templateFS, "templates/"+name+".html.tmpl", "templates/layout.html.tmpl")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s html template: %w", name, err)
+			}
+			text, err := texttemplate.New(name+".txt.tmpl").Funcs(funcs).
+				ParseFS(templateFS, "templates/"+name+".txt.tmpl")
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s text template: %w", name, err)
+			}
+			t.html[locale][name] = html
+			t.text[locale][name] = text
+		}
+	}
+
+	return t, nil
+}
+
+func (t *emailTemplates) funcs(locale string) texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		"t": func(key string, args ...interface{}) string {
+			return t.translate(locale, key, args...)
+		},
+		"locale": func() string { return locale },
+		"cedis":  func(v float64) string { return fmt.Sprintf("GH₵ %.2f", v) },
+		"deref":  func(v *float64) float64 { return *v },
+	}
+}
+
+// translate looks up key in the locale's catalog, falling back to English
+func (t *emailTemplates) translate(locale, key string, args ...interface{}) string {
+	format, ok := t.catalogs[locale][key]
+	if !ok {
+		format, ok = t.catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// resolveLocale maps a user's locale to one we have templates for
+func (t *emailTemplates) resolveLocale(locale string) string {
+	if _, ok := t.catalogs[locale]; ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+func (t *emailTemplates) locales() []string {
+	locales := make([]string, 0, len(t.catalogs))
+	for locale := range t.catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// render executes both alternatives of the named template for the locale
+func (t *emailTemplates) render(name, locale, subject string, data interface{}) (*RenderedEmail, error) {
+	locale = t.resolveLocale(locale)
+	html, ok := t.html[locale][name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var htmlBuf, textBuf bytes.Buffer
+	if err := html.Execute(&htmlBuf, data); err != nil {
+		return nil, err
+	}
+	if err := t.text[locale][name].Execute(&textBuf, data); err != nil {
+		return nil, err
+	}
+
+	return &RenderedEmail{
+		Subject: subject,
+		HTML:    htmlBuf.String(),
+		Text:    textBuf.String(),
+	}, nil
+}
+
+// renderPage executes an HTML-only page template
+func (t *emailTemplates) renderPage(name, locale string, data interface{}) (string, error) {
+	page, ok := t.html[locale][name]
+	if !ok {
+		return "", fmt.Errorf("unknown page template %q", name)
+	}
+
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"shares-alert-backend/internal/config"
+)
+
+// minRSAKeyBits is the smallest RSA key accepted for signing tokens
+const minRSAKeyBits = 2048
+
+// JWTKeySet holds the keys used to sign and verify access tokens. With
+// asymmetric keys, one key signs new tokens while every key in the set still
+// verifies, so a new key can be published before it is switched to and an old
+// one kept until the tokens it signed have expired. Without keys, tokens are
+// signed with HS256 and the JWT secret.
+type JWTKeySet struct {
+	active *jwtKey
+	keys   map[string]*jwtKey
+}
+
+type jwtKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// JWK is a public key in RFC 7517 JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWTKeySet loads every <kid>.pem key in cfg.JWTKeysDir, or falls back to
+// HS256 with cfg.JWTSecret when no directory is configured
+func NewJWTKeySet(cfg *config.AuthConfig) (*JWTKeySet, error) {
+	if cfg.JWTKeysDir == "" {
+		key := &jwtKey{
+			method:  jwt.SigningMethodHS256,
+			private: []byte(cfg.JWTSecret),
+			public:  []byte(cfg.JWTSecret),
+		}
+		return &JWTKeySet{active: key, keys: map[string]*jwtKey{}}, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cfg.JWTKeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no JWT signing keys (*.pem) found in %s", cfg.JWTKeysDir)
+	}
+
+	set := &JWTKeySet{keys: make(map[string]*jwtKey)}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := loadJWTKey(kid, path)
+		if err != nil {
+			return nil, err
+		}
+		set.keys[kid] = key
+	}
+
+	activeID := cfg.JWTActiveKeyID
+	if activeID == "" {
+		if len(set.keys) > 1 {
+			return nil, fmt.Errorf("JWT_ACTIVE_KID is required when %s holds more than one key", cfg.JWTKeysDir)
+		}
+		for kid := range set.keys {
+			activeID = kid
+		}
+	}
+	if set.active = set.keys[activeID]; set.active == nil {
+		return nil, fmt.Errorf("active JWT key %q not found in %s", activeID, cfg.JWTKeysDir)
+	}
+	if set.active.private == nil {
+		return nil, fmt.Errorf("active JWT key %q is a public key, so it can't sign tokens", activeID)
+	}
+
+	return set, nil
+}
+
+// Sign signs claims with the active key, stamping its kid in the header
+func (k *JWTKeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	if k.active.kid != "" {
+		token.Header["kid"] = k.active.kid
+	}
+	return token.SignedString(k.active.private)
+}
+
+// Keyfunc picks the verification key named by the token's kid and rejects
+// tokens signed with any other algorithm than that key's
+func (k *JWTKeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	key := k.active
+	if len(k.keys) > 0 {
+		kid, _ := token.Header["kid"].(string)
+		if key = k.keys[kid]; key == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// Methods lists the algorithms tokens may be signed with
+func (k *JWTKeySet) Methods() []string {
+	if len(k.keys) == 0 {
+		return []string{k.active.method.Alg()}
+	}
+	seen := make(map[string]bool)
+	var methods []string
+	for _, key := range k.keys {
+		if alg := key.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// JWKS returns the public keys for token verification. It is empty in HS256
+// mode, since a shared secret can't be published.
+func (k *JWTKeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// loadJWTKey reads a private key, which signs and verifies, or a public
+// key, which only verifies: a retired key whose tokens haven't all expired
+func loadJWTKey(kid, path string) (*jwtKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key %s: %w", kid, err)
+	}
+
+	if block, _ := pem.Decode(data); block != nil && block.Type == "PUBLIC KEY" {
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %s: invalid public key: %w", kid, err)
+		}
+		return newJWTKey(kid, nil, public)
+	}
+
+	signer, err := parsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("JWT key %s: %w", kid, err)
+	}
+	return newJWTKey(kid, signer, signer.Public())
+}
+
+func newJWTKey(kid string, private crypto.Signer, public crypto.PublicKey) (*jwtKey, error) {
+	key := &jwtKey{kid: kid, public: public}
+	if private != nil {
+		key.private = private
+	}
+
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("JWT key %s: RSA keys must be at least %d bits", kid, minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("JWT key %s: unsupported key type %T, use RSA or Ed25519", kid, public)
+	}
+	return key, nil
+}
+
+// parsePrivateKeyPEM reads a PEM encoded PKCS#1 RSA or PKCS#8 private key
+func parsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"shares-alert-backend/internal/config"
+)
+
+func writeKeyPEM(t *testing.T, dir, kid, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatalf("writing key %s: %v", kid, err)
+	}
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	return private
+}
+
+func TestJWTKeySetVerifiesWithRetiredPublicKey(t *testing.T) {
+	dir := t.TempDir()
+
+	active := newEd25519Key(t)
+	der, err := x509.MarshalPKCS8PrivateKey(active)
+	if err != nil {
+		t.Fatalf("marshalling private key: %v", err)
+	}
+	writeKeyPEM(t, dir, "current", "PRIVATE KEY", der)
+
+	retired := newEd25519Key(t)
+	der, err = x509.MarshalPKIXPublicKey(retired.Public())
+	if err != nil {
+		t.Fatalf("marshalling public key: %v", err)
+	}
+	writeKeyPEM(t, dir, "retired", "PUBLIC KEY", der)
+
+	keys, err := NewJWTKeySet(&config.AuthConfig{JWTKeysDir: dir, JWTActiveKeyID: "current"})
+	if err != nil {
+		t.Fatalf("NewJWTKeySet: %v", err)
+	}
+
+	claims := jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	verify := func(signed string) error {
+		_, err := jwt.ParseWithClaims(signed, &jwt.RegisteredClaims{}, keys.Keyfunc, jwt.WithValidMethods(keys.Methods()))
+		return err
+	}
+
+	signed, err := keys.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := verify(signed); err != nil {
+		t.Errorf("token from the active key: %v", err)
+	}
+
+	// A token the retired key signed before it was retired
+	old := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	old.Header["kid"] = "retired"
+	signed, err = old.SignedString(retired)
+	if err != nil {
+		t.Fatalf("signing with the retired key: %v", err)
+	}
+	if err := verify(signed); err != nil {
+		t.Errorf("token from the retired key: %v", err)
+	}
+
+	if jwks := keys.JWKS(); len(jwks.Keys) != 2 {
+		t.Errorf("JWKS publishes %d keys, want 2", len(jwks.Keys))
+	}
+}
+
+func TestJWTKeySetRejectsPublicActiveKey(t *testing.T) {
+	dir := t.TempDir()
+
+	der, err := x509.MarshalPKIXPublicKey(newEd25519Key(t).Public())
+	if err != nil {
+		t.Fatalf("marshalling public key: %v", err)
+	}
+	writeKeyPEM(t, dir, "retired", "PUBLIC KEY", der)
+
+	if _, err := NewJWTKeySet(&config.AuthConfig{JWTKeysDir: dir}); err == nil {
+		t.Error("NewJWTKeySet accepted a public key as the active key")
+	}
+}
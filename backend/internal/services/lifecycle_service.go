@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+// lifecycleBatchSize caps how many users each check emails per run
+const lifecycleBatchSize = 100
+
+// LifecycleService sends the one-off emails in a user's lifecycle: welcome on
+// sign-up, a nudge if they never create an alert, and re-engagement once they
+// go dormant. Each is sent at most once per user.
+type LifecycleService struct {
+	lifecycleRepo repository.LifecycleStore
+	userRepo      repository.UserStore
+	emailService  *EmailService
+	config        *config.LifecycleConfig
+}
+
+func NewLifecycleService(
+	lifecycleRepo repository.LifecycleStore,
+	userRepo repository.UserStore,
+	emailService *EmailService,
+	cfg *config.LifecycleConfig,
+) *LifecycleService {
+	return &LifecycleService{
+		lifecycleRepo: lifecycleRepo,
+		userRepo:      userRepo,
+		emailService:  emailService,
+		config:        cfg,
+	}
+}
+
+// SendWelcome queues the welcome email for a newly signed-up user
+func (s *LifecycleService) SendWelcome(ctx context.Context, user *models.User) error {
+	prefs, _ := s.userRepo.GetPreferences(ctx, user.ID)
+	email, err := s.emailService.RenderWelcomeEmail(user, preferenceLocale(prefs))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.record(ctx, user, models.LifecycleEmailWelcome, newOutboxEmail(user, models.OutboxKindWelcome, email))
+	return err
+}
+
+func (s *LifecycleService) StartScheduler() {
+	if !s.config.Enabled {
+		log.Println("Lifecycle emails disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.config.CheckIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	log.Println("Starting lifecycle email scheduler...")
+
+	ctx := context.Background()
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			if err := s.sendNudges(ctx, now); err != nil {
+				log.Printf("Error sending lifecycle nudges: %v", err)
+			}
+			if err := s.sendReengagements(ctx, now); err != nil {
+				log.Printf("Error sending re-engagement emails: %v", err)
+			}
+		}
+	}
+}
+
+func (s *LifecycleService) sendNudges(ctx context.Context, now time.Time) error {
+	userIDs, err := s.lifecycleRepo.GetUsersWithoutAlerts(ctx, now.AddDate(0, 0, -s.config.NudgeAfterDays), lifecycleBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get users without alerts: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if err := s.sendLifecycleEmail(ctx, userID, TemplateNudge, models.LifecycleEmailNudge, now); err != nil {
+			log.Printf("Failed to send nudge to user %s: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func (s *LifecycleService) sendReengagements(ctx context.Context, now time.Time) error {
+	userIDs, err := s.lifecycleRepo.GetDormantUsers(ctx, now.AddDate(0, 0, -s.config.DormantAfterDays), lifecycleBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get dormant users: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if err := s.sendLifecycleEmail(ctx, userID, TemplateReengagement, models.LifecycleEmailReengagement, now); err != nil {
+			log.Printf("Failed to send re-engagement email to user %s: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func (s *LifecycleService) sendLifecycleEmail(ctx context.Context, userID, template, kind string, now time.Time) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	prefs, _ := s.userRepo.GetPreferences(ctx, userID)
+	if prefs != nil && !prefs.EmailNotifications {
+		// Opted out of email; they'll be picked up if they opt back in
+		return nil
+	}
+
+	since := user.CreatedAt
+	if kind == models.LifecycleEmailReengagement && user.LastLoginAt != nil {
+		since = *user.LastLoginAt
+	}
+	days := int(now.Sub(since).Hours() / 24)
+
+	email, err := s.emailService.RenderLifecycleEmail(template, user, days, preferenceLocale(prefs))
+	if err != nil {
+		return err
+	}
+
+	sent, err := s.record(ctx, user, kind, newOutboxEmail(user, models.OutboxKindLifecycle, email))
+	if err != nil {
+		return err
+	}
+	if sent {
+		log.Printf("Queued %s email to user %s", kind, userID)
+	}
+	return nil
+}
+
+// record marks the lifecycle email sent and queues it, unless the user already had it
+func (s *LifecycleService) record(ctx context.Context, user *models.User, kind string, msg *models.OutboxMessage) (bool, error) {
+	entry := &models.LifecycleEmail{
+		ID:     uuid.New().String(),
+		UserID: user.ID,
+		Kind:   kind,
+		SentAt: time.Now(),
+	}
+
+	sent, err := s.lifecycleRepo.RecordWithMessage(ctx, entry, msg)
+	if err != nil {
+		return false, fmt.Errorf("failed to queue %s email: %w", kind, err)
+	}
+	return sent, nil
+}
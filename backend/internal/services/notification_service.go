@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+const (
+	defaultNotificationPageSize = 20
+	maxNotificationPageSize     = 100
+)
+
+// NotificationService manages the in-app inbox and pushes new notifications to
+// subscribers (open SSE streams). Subscribers are tracked in process, so a
+// session only receives pushes from the instance it is connected to.
+type NotificationService struct {
+	notificationRepo repository.NotificationStore
+
+	mu          sync.RWMutex
+	subscribers map[string]map[chan *models.Notification]struct{}
+}
+
+func NewNotificationService(notificationRepo repository.NotificationStore) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		subscribers:      make(map[string]map[chan *models.Notification]struct{}),
+	}
+}
+
+// newAlertNotification builds the inbox entry for a triggered alert
+func newAlertNotification(alert *models.Alert, currentPrice float64) *models.Notification {
+	message := fmt.Sprintf("%s is now GH₵ %.2f", alert.StockSymbol, currentPrice)
+	if alert.ThresholdPrice != nil {
+		message = fmt.Sprintf("%s reached GH₵ %.2f (your threshold: GH₵ %.2f)",
+			alert.StockSymbol, currentPrice, *alert.ThresholdPrice)
+	}
+
+	return &models.Notification{
+		ID:        uuid.New().String(),
+		UserID:    alert.UserID,
+		Kind:      models.NotificationKindAlert,
+		Title:     fmt.Sprintf("Stock Alert: %s (%s)", alert.StockName, alert.StockSymbol),
+		Message:   message,
+		AlertID:   &alert.ID,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func newPortfolioAlertNotification(alert *models.Alert, breach *portfolioBreach) *models.Notification {
+	return &models.Notification{
+		ID:        uuid.New().String(),
+		UserID:    alert.UserID,
+		Kind:      models.NotificationKindAlert,
+		Title:     fmt.Sprintf("Portfolio Alert: %s", alert.StockName),
+		Message:   describePortfolioBreach(alert, breach),
+		AlertID:   &alert.ID,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Notify stores a notification and pushes it to the user's open streams
+func (s *NotificationService) Notify(ctx context.Context, n *models.Notification) error {
+	if err := s.notificationRepo.Create(ctx, n); err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+	s.Publish(n)
+	return nil
+}
+
+// Publish pushes an already stored notification to the user's open streams
+func (s *NotificationService) Publish(n *models.Notification) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for ch := range s.subscribers[n.UserID] {
+		select {
+		case ch <- n:
+		default:
+			// Slow consumer; it can catch up from the inbox endpoint
+		}
+	}
+}
+
+// Subscribe registers a stream for the user. The returned function must be
+// called when the stream closes.
+func (s *NotificationService) Subscribe(userID string) (<-chan *models.Notification, func()) {
+	ch := make(chan *models.Notification, 16)
+
+	s.mu.Lock()
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = make(map[chan *models.Notification]struct{})
+	}
+	s.subscribers[userID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers[userID], ch)
+		if len(s.subscribers[userID]) == 0 {
+			delete(s.subscribers, userID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *NotificationService) List(ctx context.Context, userID, cursor string, limit int, unreadOnly bool) (*models.NotificationPage, error) {
+	if limit <= 0 {
+		limit = defaultNotificationPageSize
+	}
+	if limit > maxNotificationPageSize {
+		limit = maxNotificationPageSize
+	}
+
+	var beforeCreatedAt *time.Time
+	var beforeID string
+	if cursor != "" {
+		createdAt, id, err := decodeNotificationCursor(cursor, unreadOnly)
+		if err != nil {
+			return nil, err
+		}
+		beforeCreatedAt, beforeID = &createdAt, id
+	}
+
+	// Fetch one extra row to learn whether there is another page
+	notifications, err := s.notificationRepo.ListByUserID(ctx, userID, beforeCreatedAt, beforeID, unreadOnly, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	unread, err := s.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	page := &models.NotificationPage{
+		Notifications: notifications,
+		UnreadCount:   unread,
+	}
+	if len(notifications) > limit {
+		page.Notifications = notifications[:limit]
+		page.NextCursor = encodeNotificationCursor(unreadOnly, page.Notifications[limit-1])
+	}
+	if page.Notifications == nil {
+		page.Notifications = []*models.Notification{}
+	}
+
+	return page, nil
+}
+
+func (s *NotificationService) UnreadCount(ctx context.Context, userID string) (int, error) {
+	return s.notificationRepo.CountUnread(ctx, userID)
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id string) error {
+	return s.notificationRepo.MarkRead(ctx, userID, id)
+}
+
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID string) error {
+	return s.notificationRepo.MarkAllRead(ctx, userID)
+}
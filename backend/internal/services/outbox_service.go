@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+// staleSendingAfter is how long a message may sit in sending before it is
+// assumed the worker delivering it died and it is retried
+const staleSendingAfter = 10 * time.Minute
+
+type OutboxService struct {
+	outboxRepo   repository.OutboxStore
+	emailService *EmailService
+	config       *config.OutboxConfig
+}
+
+func NewOutboxService(outboxRepo repository.OutboxStore, emailService *EmailService, cfg *config.OutboxConfig) *OutboxService {
+	return &OutboxService{
+		outboxRepo:   outboxRepo,
+		emailService: emailService,
+		config:       cfg,
+	}
+}
+
+// newOutboxEmail builds a pending outbox message ready for immediate delivery
+func newOutboxEmail(user *models.User, kind string, email *RenderedEmail) *models.OutboxMessage {
+	now := time.Now()
+	return &models.OutboxMessage{
+		ID:             uuid.New().String(),
+		UserID:         user.ID,
+		Kind:           kind,
+		Recipient:      user.Email,
+		Subject:        email.Subject,
+		Body:           email.HTML,
+		TextBody:       email.Text,
+		UnsubscribeURL: email.UnsubscribeURL,
+		Status:         models.OutboxStatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// Enqueue stores a message for the worker to deliver
+func (s *OutboxService) Enqueue(ctx context.Context, msg *models.OutboxMessage) error {
+	if err := s.outboxRepo.Create(ctx, msg); err != nil {
+		return fmt.Errorf("failed to queue notification: %w", err)
+	}
+	return nil
+}
+
+func (s *OutboxService) GetMessage(ctx context.Context, id string) (*models.OutboxMessage, error) {
+	return s.outboxRepo.GetByID(ctx, id)
+}
+
+func (s *OutboxService) ListMessages(ctx context.Context, status string, limit int) ([]*models.OutboxMessage, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.outboxRepo.List(ctx, status, limit)
+}
+
+func (s *OutboxService) Replay(ctx context.Context, id string) error {
+	return s.outboxRepo.Replay(ctx, id)
+}
+
+func (s *OutboxService) StartWorker() {
+	ticker := time.NewTicker(time.Duration(s.config.PollIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	log.Println("Starting notification outbox worker...")
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.deliverDue(context.Background()); err != nil {
+				log.Printf("Error delivering outbox messages: %v", err)
+			}
+		}
+	}
+}
+
+func (s *OutboxService) deliverDue(ctx context.Context) error {
+	now := time.Now()
+	if err := s.outboxRepo.ReleaseStale(ctx, now.Add(-staleSendingAfter)); err != nil {
+		log.Printf("Failed to release stale outbox messages: %v", err)
+	}
+
+	messages, err := s.outboxRepo.GetDue(ctx, now, s.config.BatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get due messages: %w", err)
+	}
+
+	for _, msg := range messages {
+		claimed, err := s.outboxRepo.Claim(ctx, msg.ID)
+		if err != nil {
+			log.Printf("Failed to claim outbox message %s: %v", msg.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		s.deliver(ctx, msg)
+	}
+
+	return nil
+}
+
+func (s *OutboxService) deliver(ctx context.Context, msg *models.OutboxMessage) {
+	attempts := msg.Attempts + 1
+
+	sendErr := s.emailService.Send(msg.Recipient, &RenderedEmail{
+		Subject: msg.Subject,
+		HTML:    msg.Body,
+		Text:    msg.TextBody,
+
+		UnsubscribeURL: msg.UnsubscribeURL,
+	})
+	if sendErr == nil {
+		if err := s.outboxRepo.MarkSent(ctx, msg.ID, attempts, time.Now()); err != nil {
+			log.Printf("Failed to mark outbox message %s sent: %v", msg.ID, err)
+		}
+		return
+	}
+
+	dead := attempts >= s.config.MaxAttempts
+	nextAttemptAt := time.Now().Add(s.backoff(attempts))
+	if err := s.outboxRepo.MarkFailed(ctx, msg.ID, attempts, nextAttemptAt, sendErr.Error(), dead); err != nil {
+		log.Printf("Failed to record outbox failure for %s: %v", msg.ID, err)
+	}
+
+	if dead {
+		log.Printf("Outbox message %s dead-lettered after %d attempts: %v", msg.ID, attempts, sendErr)
+	} else {
+		log.Printf("Outbox message %s failed (attempt %d), retrying at %s: %v",
+			msg.ID, attempts, nextAttemptAt.Format(time.RFC3339), sendErr)
+	}
+}
+
+// backoff returns the exponential delay before the next attempt, capped at MaxBackoffMinutes
+func (s *OutboxService) backoff(attempts int) time.Duration {
+	maxDelay := time.Duration(s.config.MaxBackoffMinutes) * time.Minute
+	delay := time.Duration(s.config.BaseBackoffSeconds) * time.Second
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+)
+
+// maxNamedHoldings caps how many movers a portfolio alert names
+const maxNamedHoldings = 3
+
+func isPortfolioAlertType(alertType string) bool {
+	switch alertType {
+	case models.AlertTypePortfolioValue, models.AlertTypePortfolioDrawdown, models.AlertTypePortfolioAllocation:
+		return true
+	}
+	return false
+}
+
+// portfolioBreach is a portfolio alert's condition being met. Measure is
+// the value, the drawdown percent or the largest holding's weight.
+type portfolioBreach struct {
+	measure  float64
+	holdings []*models.Holding
+}
+
+func (s *AlertService) createPortfolioAlert(ctx context.Context, userID string, req *models.CreateAlertRequest) (*models.Alert, error) {
+	if req.PortfolioID == "" {
+		return nil, fmt.Errorf("portfolioId is required for %s alerts", req.AlertType)
+	}
+	portfolio, err := s.portfolioService.Get(ctx, userID, req.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	alert := &models.Alert{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		Scope:            models.AlertScopePortfolio,
+		PortfolioID:      &portfolio.ID,
+		StockName:        portfolio.Name,
+		AlertType:        req.AlertType,
+		ThresholdPrice:   req.ThresholdPrice,
+		ThresholdPercent: req.ThresholdPercent,
+		Direction:        req.Direction,
+		Status:           models.AlertStatusActive,
+		Urgent:           req.Urgent,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	if err := validatePortfolioAlert(alert); err != nil {
+		return nil, err
+	}
+
+	// The value now is where a drawdown is first measured from
+	if valuation, err := s.portfolioService.Value(ctx, userID, portfolio.ID); err == nil {
+		value, netInvested := valuation.MarketValue, valuation.NetInvested
+		alert.CurrentPrice = &value
+		if alert.AlertType == models.AlertTypePortfolioDrawdown {
+			alert.PeakValue = &value
+			alert.NetInvested = &netInvested
+		}
+	}
+
+	if err := s.alertRepo.Create(ctx, alert); err != nil {
+		return nil, fmt.Errorf("failed to create alert: %w", err)
+	}
+
+	return alert, nil
+}
+
+// validatePortfolioAlert checks a portfolio alert's thresholds, defaulting
+// portfolio_value alerts to fire when the value rises above the threshold
+func validatePortfolioAlert(alert *models.Alert) error {
+	switch alert.AlertType {
+	case models.AlertTypePortfolioValue:
+		if alert.ThresholdPrice == nil || !(*alert.ThresholdPrice > 0) {
+			return fmt.Errorf("thresholdPrice is required for portfolio_value alerts")
+		}
+		if alert.Direction == "" {
+			alert.Direction = models.AlertDirectionAbove
+		}
+		if alert.Direction != models.AlertDirectionAbove && alert.Direction != models.AlertDirectionBelow {
+			return fmt.Errorf("direction must be above or below")
+		}
+	case models.AlertTypePortfolioDrawdown, models.AlertTypePortfolioAllocation:
+		if alert.ThresholdPercent == nil || !(*alert.ThresholdPercent > 0) || *alert.ThresholdPercent > 100 {
+			return fmt.Errorf("thresholdPercent between 0 and 100 is required for %s alerts", alert.AlertType)
+		}
+	default:
+		return fmt.Errorf("invalid alert type")
+	}
+	return nil
+}
+
+// processPortfolioAlert values the alert's portfolio at live prices and
+// triggers the alert if its condition is met
+func (s *AlertService) processPortfolioAlert(ctx context.Context, alert *models.Alert) error {
+	if alert.PortfolioID == nil {
+		return fmt.Errorf("portfolio alert has no portfolio")
+	}
+	valuation, err := s.portfolioService.Value(ctx, alert.UserID, *alert.PortfolioID)
+	if err != nil {
+		return fmt.Errorf("failed to value portfolio %s: %w", *alert.PortfolioID, err)
+	}
+
+	value := valuation.MarketValue
+	alert.CurrentPrice = &value
+	breach := evaluatePortfolioAlert(alert, valuation)
+
+	// Record the latest value and, for drawdowns, the peak
+	if err := s.alertRepo.Update(ctx, alert); err != nil {
+		log.Printf("Failed to update portfolio value for alert %s: %v", alert.ID, err)
+	}
+
+	if breach != nil {
+		return s.triggerPortfolioAlert(ctx, alert, breach)
+	}
+
+	return nil
+}
+
+// evaluatePortfolioAlert returns the breach if the alert's condition is met.
+// For drawdowns it also moves the alert's peak forward.
+func evaluatePortfolioAlert(alert *models.Alert, valuation *models.PortfolioValuation) *portfolioBreach {
+	value := valuation.MarketValue
+
+	switch alert.AlertType {
+	case models.AlertTypePortfolioValue:
+		if alert.ThresholdPrice == nil {
+			return nil
+		}
+		if alert.Direction == models.AlertDirectionBelow {
+			if value > *alert.ThresholdPrice {
+				return nil
+			}
+		} else if value < *alert.ThresholdPrice {
+			return nil
+		}
+		return &portfolioBreach{measure: value, holdings: biggestMovers(valuation.Holdings, alert.Direction == models.AlertDirectionBelow)}
+
+	case models.AlertTypePortfolioDrawdown:
+		if alert.ThresholdPercent == nil {
+			return nil
+		}
+		peak := value
+		if alert.PeakValue != nil {
+			peak = *alert.PeakValue
+			// Money paid in or taken out since the peak moves it too, so
+			// selling shares isn't mistaken for a fall in value
+			if alert.NetInvested != nil {
+				peak += valuation.NetInvested - *alert.NetInvested
+			}
+		}
+		peak = math.Max(peak, value)
+		netInvested := valuation.NetInvested
+		alert.PeakValue = &peak
+		alert.NetInvested = &netInvested
+
+		if peak <= 0 {
+			return nil
+		}
+		drawdown := (peak - value) / peak * 100
+		if drawdown < *alert.ThresholdPercent {
+			return nil
+		}
+		return &portfolioBreach{measure: drawdown, holdings: biggestMovers(valuation.Holdings, true)}
+
+	case models.AlertTypePortfolioAllocation:
+		if alert.ThresholdPercent == nil {
+			return nil
+		}
+		// Holdings come largest first
+		var over []*models.Holding
+		for _, holding := range valuation.Holdings {
+			if holding.Weight > *alert.ThresholdPercent {
+				over = append(over, holding)
+			}
+		}
+		if len(over) == 0 {
+			return nil
+		}
+		return &portfolioBreach{measure: over[0].Weight, holdings: over}
+	}
+
+	return nil
+}
+
+// biggestMovers returns the holdings that moved the portfolio most today in
+// one direction: the largest fallers if falling, else the largest risers
+func biggestMovers(holdings []*models.Holding, falling bool) []*models.Holding {
+	var movers []*models.Holding
+	for _, holding := range holdings {
+		if (falling && holding.DayChange < 0) || (!falling && holding.DayChange > 0) {
+			movers = append(movers, holding)
+		}
+	}
+	sort.Slice(movers, func(i, j int) bool {
+		return math.Abs(movers[i].DayChange) > math.Abs(movers[j].DayChange)
+	})
+	if len(movers) > maxNamedHoldings {
+		movers = movers[:maxNamedHoldings]
+	}
+	return movers
+}
+
+func (s *AlertService) triggerPortfolioAlert(ctx context.Context, alert *models.Alert, breach *portfolioBreach) error {
+	notification := newPortfolioAlertNotification(alert, breach)
+	render := func(user *models.User, locale string) (*RenderedEmail, error) {
+		return s.emailService.RenderPortfolioAlertEmail(user, alert, breach.measure, breach.holdings, locale)
+	}
+	if err := s.dispatchTrigger(ctx, alert, *alert.CurrentPrice, notification, render); err != nil {
+		return err
+	}
+
+	log.Printf("Portfolio alert %s triggered for %s: %s", alert.ID, alert.StockName, notification.Message)
+
+	return nil
+}
+
+// describePortfolioBreach says what happened to the portfolio and which
+// holdings are responsible
+func describePortfolioBreach(alert *models.Alert, breach *portfolioBreach) string {
+	var message string
+	switch alert.AlertType {
+	case models.AlertTypePortfolioValue:
+		message = fmt.Sprintf("%s is worth GH₵ %.2f, %s your threshold of GH₵ %.2f.",
+			alert.StockName, breach.measure, alert.Direction, *alert.ThresholdPrice)
+	case models.AlertTypePortfolioDrawdown:
+		message = fmt.Sprintf("%s is %.1f%% below its peak of GH₵ %.2f (your limit: %.1f%%).",
+			alert.StockName, breach.measure, *alert.PeakValue, *alert.ThresholdPercent)
+	case models.AlertTypePortfolioAllocation:
+		over := make([]string, len(breach.holdings))
+		for i, holding := range breach.holdings {
+			over[i] = fmt.Sprintf("%s (%.1f%%)", holding.StockSymbol, holding.Weight)
+		}
+		return fmt.Sprintf("%s exceeds %.1f%% of %s.", strings.Join(over, ", "), *alert.ThresholdPercent, alert.StockName)
+	}
+
+	if len(breach.holdings) > 0 {
+		movers := make([]string, len(breach.holdings))
+		for i, holding := range breach.holdings {
+			movers[i] = fmt.Sprintf("%s (GH₵ %+.2f)", holding.StockSymbol, holding.DayChange)
+		}
+		message += " Biggest moves today: " + strings.Join(movers, ", ") + "."
+	}
+	return message
+}
@@ -0,0 +1,403 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"shares-alert-backend/internal/models"
+)
+
+// maxImportRows caps a statement, since every row replays the ledger
+const maxImportRows = 2000
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// importFormats are the built-in column mappings for broker statements
+var importFormats = map[string]models.ImportMapping{
+	"generic": {
+		Name:        "generic",
+		Date:        "date",
+		DateFormats: []string{time.RFC3339, "2006-01-02"},
+		Type:        "type",
+		Symbol:      "symbol",
+		Quantity:    "quantity",
+		Price:       "price",
+		Fees:        []string{"fees"},
+		Notes:       "notes",
+	},
+	"databank": {
+		Name:        "databank",
+		Date:        "Trade Date",
+		DateFormats: []string{"02/01/2006", "02-Jan-2006", "02-Jan-06", "2006-01-02"},
+		Type:        "Transaction",
+		Symbol:      "Security",
+		Quantity:    "Quantity",
+		Price:       "Price",
+		Amount:      "Consideration",
+		Fees:        []string{"Brokerage", "SEC Levy", "GSE Levy", "CSD Fee", "VAT"},
+		Reference:   "Contract No",
+	},
+	"ic_securities": {
+		Name:        "ic_securities",
+		Date:        "Date",
+		DateFormats: []string{"2006-01-02", "02/01/2006", "02 Jan 2006"},
+		Type:        "Side",
+		Symbol:      "Symbol",
+		Quantity:    "Qty",
+		Price:       "Unit Price",
+		Amount:      "Gross Amount",
+		Fees:        []string{"Fees"},
+		Reference:   "Reference",
+	},
+}
+
+// defaultTypeValues maps the usual ways statements spell transaction types
+var defaultTypeValues = map[string]string{
+	"buy":      models.TransactionTypeBuy,
+	"b":        models.TransactionTypeBuy,
+	"bought":   models.TransactionTypeBuy,
+	"purchase": models.TransactionTypeBuy,
+	"sell":     models.TransactionTypeSell,
+	"s":        models.TransactionTypeSell,
+	"sold":     models.TransactionTypeSell,
+	"sale":     models.TransactionTypeSell,
+	"dividend": models.TransactionTypeDividend,
+	"div":      models.TransactionTypeDividend,
+	"fee":      models.TransactionTypeFee,
+	"charge":   models.TransactionTypeFee,
+}
+
+// ImportFormats lists the built-in statement mappings by name
+func ImportFormats() []models.ImportMapping {
+	formats := make([]models.ImportMapping, 0, len(importFormats))
+	for _, format := range importFormats {
+		formats = append(formats, format)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i].Name < formats[j].Name })
+	return formats
+}
+
+// importColumns is a mapping resolved against a statement's header row
+type importColumns struct {
+	mapping    *models.ImportMapping
+	index      map[string]int
+	typeValues map[string]string
+}
+
+func (c *importColumns) cell(record []string, column string) string {
+	i, ok := c.index[strings.ToLower(strings.TrimSpace(column))]
+	if column == "" || !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// ImportTransactions reads a broker statement into the portfolio's ledger.
+// Every row is validated, checked against the live board and the existing
+// ledger, and either all valid rows are recorded together or none are.
+// Rows already in the ledger are reported as duplicates and skipped. Each
+// recorded transaction matches at most one row, so identical fills within a
+// statement are all imported the first time and all skipped on a re-upload.
+func (s *PortfolioService) ImportTransactions(ctx context.Context, userID, portfolioID string, statement io.Reader, format string, mapping *models.ImportMapping, dryRun bool) (*models.ImportResult, error) {
+	portfolio, err := s.Get(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err = resolveImportMapping(format, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	records, columns, err := readStatement(statement, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := s.portfolioRepo.GetTransactions(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	board, err := s.liveBoard()
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make(map[string]int, len(ledger))
+	for _, t := range ledger {
+		recorded[transactionFingerprint(t)]++
+	}
+
+	result := &models.ImportResult{Format: mapping.Name, DryRun: dryRun, Rows: []*models.ImportRow{}}
+	var accepted []*models.ImportRow
+	createdAt := time.Now()
+	for _, record := range records {
+		row := &models.ImportRow{Row: record.line, Status: models.ImportRowOK}
+		result.Rows = append(result.Rows, row)
+
+		req, rowErrors := parseImportRow(columns, record.fields)
+		if len(rowErrors) == 0 {
+			s.fillDividendPrice(req)
+			t, err := newTransaction(portfolioID, req)
+			if err != nil {
+				rowErrors = append(rowErrors, strings.TrimPrefix(err.Error(), ErrInvalidTransaction.Error()+": "))
+			} else {
+				if _, listed := board[t.StockSymbol]; t.StockSymbol != "" && !listed {
+					rowErrors = append(rowErrors, fmt.Sprintf("%s isn't listed on the live board", t.StockSymbol))
+				}
+				// Keep rows on the same day in file order
+				t.CreatedAt = createdAt.Add(time.Duration(len(result.Rows)) * time.Millisecond)
+				row.Transaction = t
+			}
+		}
+		if len(rowErrors) > 0 {
+			row.Status = models.ImportRowError
+			row.Errors = rowErrors
+			continue
+		}
+
+		if fingerprint := transactionFingerprint(row.Transaction); recorded[fingerprint] > 0 {
+			recorded[fingerprint]--
+			row.Status = models.ImportRowDuplicate
+			continue
+		}
+		accepted = append(accepted, row)
+	}
+
+	// Replay the ledger one imported row at a time, in date order, so a sell
+	// of shares that aren't held is pinned to its own row
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].Transaction.TradeDate.Before(accepted[j].Transaction.TradeDate)
+	})
+	valid := ledger
+	var transactions []*models.Transaction
+	for _, row := range accepted {
+		candidate := append(valid[:len(valid):len(valid)], row.Transaction)
+		if _, err := replayLedger(candidate, portfolio.CostBasisMethod); err != nil {
+			row.Status = models.ImportRowError
+			row.Errors = append(row.Errors, err.Error())
+			continue
+		}
+		valid = candidate
+		transactions = append(transactions, row.Transaction)
+	}
+
+	for _, row := range result.Rows {
+		switch row.Status {
+		case models.ImportRowOK:
+			result.Imported++
+		case models.ImportRowDuplicate:
+			result.Duplicates++
+		case models.ImportRowError:
+			result.Errors++
+		}
+	}
+	if dryRun || result.Errors > 0 || len(transactions) == 0 {
+		return result, nil
+	}
+
+	if err := s.portfolioRepo.CreateTransactions(ctx, transactions); err != nil {
+		return nil, fmt.Errorf("failed to record transactions: %w", err)
+	}
+	result.Committed = true
+	return result, nil
+}
+
+// resolveImportMapping picks the named built-in format, or checks a custom
+// mapping supplied with the upload
+func resolveImportMapping(format string, mapping *models.ImportMapping) (*models.ImportMapping, error) {
+	if mapping == nil {
+		if format == "" {
+			format = "generic"
+		}
+		preset, ok := importFormats[strings.ToLower(format)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidImport, format)
+		}
+		return &preset, nil
+	}
+
+	if mapping.Name == "" {
+		mapping.Name = "custom"
+	}
+	if mapping.Date == "" || mapping.Type == "" || mapping.Symbol == "" || mapping.Quantity == "" {
+		return nil, fmt.Errorf("%w: mapping needs date, type, symbol and quantity columns", ErrInvalidImport)
+	}
+	if mapping.Price == "" && mapping.Amount == "" {
+		return nil, fmt.Errorf("%w: mapping needs a price or amount column", ErrInvalidImport)
+	}
+	if len(mapping.DateFormats) == 0 {
+		mapping.DateFormats = []string{"2006-01-02"}
+	}
+	if utf8.RuneCountInString(mapping.Delimiter) > 1 {
+		return nil, fmt.Errorf("%w: delimiter must be a single character", ErrInvalidImport)
+	}
+	if mapping.SkipRows < 0 {
+		return nil, fmt.Errorf("%w: skipRows cannot be negative", ErrInvalidImport)
+	}
+	return mapping, nil
+}
+
+// statementRecord is one data row and the line it came from
+type statementRecord struct {
+	line   int
+	fields []string
+}
+
+// readStatement parses the CSV, locates the mapped columns in its header row
+// and returns the data rows, leaving out blank and summary lines
+func readStatement(statement io.Reader, mapping *models.ImportMapping) ([]statementRecord, *importColumns, error) {
+	reader := csv.NewReader(statement)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+	if mapping.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(mapping.Delimiter)
+	}
+
+	for i := 0; i < mapping.SkipRows; i++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, nil, fmt.Errorf("%w: statement ends before its header row", ErrInvalidImport)
+		}
+	}
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: statement has no header row", ErrInvalidImport)
+	}
+
+	columns := &importColumns{
+		mapping:    mapping,
+		index:      make(map[string]int, len(header)),
+		typeValues: make(map[string]string, len(defaultTypeValues)+len(mapping.TypeValues)),
+	}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, exists := columns.index[name]; !exists {
+			columns.index[name] = i
+		}
+	}
+	var missing []string
+	for _, column := range append([]string{mapping.Date, mapping.Type, mapping.Symbol, mapping.Quantity, mapping.Price, mapping.Amount}, mapping.Fees...) {
+		if _, ok := columns.index[strings.ToLower(strings.TrimSpace(column))]; column != "" && !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("%w: header is missing columns: %s", ErrInvalidImport, strings.Join(missing, ", "))
+	}
+	for value, kind := range defaultTypeValues {
+		columns.typeValues[value] = kind
+	}
+	for value, kind := range mapping.TypeValues {
+		columns.typeValues[strings.ToLower(strings.TrimSpace(value))] = kind
+	}
+
+	var records []statementRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, line, err)
+		}
+		// Skip blank lines and totals, which have no date, type or symbol
+		if columns.cell(fields, mapping.Date) == "" && columns.cell(fields, mapping.Type) == "" &&
+			columns.cell(fields, mapping.Symbol) == "" {
+			continue
+		}
+		if len(records) == maxImportRows {
+			return nil, nil, fmt.Errorf("%w: statement has more than %d rows", ErrInvalidImport, maxImportRows)
+		}
+		records = append(records, statementRecord{line: line, fields: fields})
+	}
+
+	return records, columns, nil
+}
+
+// parseImportRow turns one statement row into a transaction request,
+// collecting every problem with the row rather than stopping at the first
+func parseImportRow(columns *importColumns, record []string) (*models.CreateTransactionRequest, []string) {
+	mapping := columns.mapping
+	req := &models.CreateTransactionRequest{
+		StockSymbol: strings.ToUpper(columns.cell(record, mapping.Symbol)),
+		Notes:       columns.cell(record, mapping.Notes),
+	}
+	var rowErrors []string
+
+	dateCell := columns.cell(record, mapping.Date)
+	for _, layout := range mapping.DateFormats {
+		if date, err := time.ParseInLocation(layout, dateCell, time.UTC); err == nil {
+			req.TradeDate = date
+			break
+		}
+	}
+	if req.TradeDate.IsZero() {
+		rowErrors = append(rowErrors, fmt.Sprintf("date %q isn't in a recognised format", dateCell))
+	}
+
+	typeCell := columns.cell(record, mapping.Type)
+	if kind, ok := columns.typeValues[strings.ToLower(typeCell)]; ok {
+		req.Type = kind
+	} else {
+		rowErrors = append(rowErrors, fmt.Sprintf("unknown transaction type %q", typeCell))
+	}
+
+	number := func(column, field string) float64 {
+		value, err := parseStatementNumber(columns.cell(record, column))
+		if err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("%s: %v", field, err))
+		}
+		return value
+	}
+	req.Quantity = number(mapping.Quantity, "quantity")
+	req.Price = number(mapping.Price, "price")
+	if req.Price == 0 && mapping.Amount != "" && req.Quantity > 0 {
+		req.Price = number(mapping.Amount, "amount") / req.Quantity
+	}
+	for _, column := range mapping.Fees {
+		req.Fees += number(column, strings.ToLower(column))
+	}
+
+	if reference := columns.cell(record, mapping.Reference); reference != "" {
+		req.Notes = strings.TrimSpace("Ref " + reference + " " + req.Notes)
+	}
+
+	return req, rowErrors
+}
+
+// parseStatementNumber reads an amount as brokers print it: thousands
+// separators, a cedi prefix, and negatives in brackets or with a minus sign.
+// Signs are dropped, since the transaction type carries the direction.
+func parseStatementNumber(cell string) (float64, error) {
+	value := strings.TrimSpace(cell)
+	for _, prefix := range []string{"GH₵", "GHS", "GHC", "₵"} {
+		value = strings.TrimSpace(strings.TrimPrefix(value, prefix))
+	}
+	value = strings.NewReplacer(",", "", " ", "", "(", "", ")", "").Replace(value)
+	if value == "" || value == "-" {
+		return 0, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a number", cell)
+	}
+	if number < 0 {
+		number = -number
+	}
+	return number, nil
+}
+
+// transactionFingerprint identifies a transaction for duplicate detection
+func transactionFingerprint(t *models.Transaction) string {
+	return fmt.Sprintf("%s|%s|%s|%.6f|%.6f|%.2f", t.Type, t.StockSymbol,
+		t.TradeDate.UTC().Format(snapshotDateLayout), t.Quantity, t.Price, t.Fees)
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shares-alert-backend/internal/cache"
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+// newTestPortfolioService returns a service whose live board lists MTNGH
+// and GCB, with a portfolio for a new user
+func newTestPortfolioService(t *testing.T, method string) (*PortfolioService, *models.Portfolio) {
+	t.Helper()
+
+	board := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/live" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode([]models.StockLive{{Name: "MTNGH", Price: 2.50}, {Name: "GCB", Price: 6.00}})
+	}))
+	t.Cleanup(board.Close)
+
+	redisCache, _ := cache.NewRedisCache(&cache.CacheConfig{})
+	stockService := NewStockService(&config.ExternalConfig{GSEBaseURL: board.URL, ProxyURL: board.URL + "/proxy/"}, redisCache, time.Minute)
+
+	db := newTestDB(t)
+	user := createTestUser(t, db, "investor@example.com")
+	service := NewPortfolioService(repository.NewPortfolioRepository(db), stockService, &config.PortfolioConfig{SnapshotTimezone: "UTC"})
+	portfolio, err := service.Create(context.Background(), user.ID, &models.CreatePortfolioRequest{Name: "Main", CostBasisMethod: method})
+	if err != nil {
+		t.Fatalf("failed to create portfolio: %v", err)
+	}
+	return service, portfolio
+}
+
+func TestParseStatementNumber(t *testing.T) {
+	tests := []struct {
+		cell    string
+		want    float64
+		wantErr bool
+	}{
+		{"1250", 1250, false},
+		{" 1,250.50 ", 1250.50, false},
+		{"GH₵ 1,250.50", 1250.50, false},
+		{"GHS1250", 1250, false},
+		{"₵0.75", 0.75, false},
+		{"(1,250.00)", 1250, false},
+		{"-42.5", 42.5, false},
+		{"", 0, false},
+		{"-", 0, false},
+		{"12a", 0, true},
+		{"N/A", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseStatementNumber(tt.cell)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseStatementNumber(%q) = %g, want an error", tt.cell, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseStatementNumber(%q) = %g, %v; want %g", tt.cell, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseImportRow(t *testing.T) {
+	tradeDate := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		format    string
+		statement string
+		want      models.CreateTransactionRequest
+		wantErrs  int
+	}{
+		{
+			name:      "generic",
+			format:    "generic",
+			statement: "date,type,symbol,quantity,price,fees,notes\n2026-03-14,buy,mtngh,100,2.50,1.25,first\n",
+			want: models.CreateTransactionRequest{Type: models.TransactionTypeBuy, StockSymbol: "MTNGH", Quantity: 100,
+				Price: 2.50, Fees: 1.25, TradeDate: tradeDate, Notes: "first"},
+		},
+		{
+			name:   "databank sums the levies and works the price out from the consideration",
+			format: "databank",
+			statement: "Trade Date,Transaction,Security,Quantity,Price,Consideration,Brokerage,SEC Levy,GSE Levy,CSD Fee,VAT,Contract No\n" +
+				`14/03/2026,Bought,MTNGH,"1,000",,"GH₵ 2,500.00",20.00,1.00,1.00,0.50,0.75,C-77` + "\n",
+			want: models.CreateTransactionRequest{Type: models.TransactionTypeBuy, StockSymbol: "MTNGH", Quantity: 1000,
+				Price: 2.50, Fees: 23.25, TradeDate: tradeDate, Notes: "Ref C-77"},
+		},
+		{
+			name:      "ic securities",
+			format:    "ic_securities",
+			statement: "Date,Side,Symbol,Qty,Unit Price,Gross Amount,Fees,Reference\n14 Mar 2026,S,GCB,50,6.00,300.00,(3.00),R-9\n",
+			want: models.CreateTransactionRequest{Type: models.TransactionTypeSell, StockSymbol: "GCB", Quantity: 50,
+				Price: 6.00, Fees: 3.00, TradeDate: tradeDate, Notes: "Ref R-9"},
+		},
+		{
+			name:      "every problem in the row is reported",
+			format:    "generic",
+			statement: "date,type,symbol,quantity,price,fees,notes\n14.03.2026,swap,MTNGH,lots,2.50,x,\n",
+			wantErrs:  4,
+		},
+	}
+
+	for _, tt := range tests {
+		mapping, err := resolveImportMapping(tt.format, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		records, columns, err := readStatement(strings.NewReader(tt.statement), mapping)
+		if err != nil || len(records) != 1 {
+			t.Fatalf("%s: read %d records: %v", tt.name, len(records), err)
+		}
+
+		req, rowErrors := parseImportRow(columns, records[0].fields)
+		if len(rowErrors) != tt.wantErrs {
+			t.Errorf("%s: errors %q, want %d", tt.name, rowErrors, tt.wantErrs)
+			continue
+		}
+		if tt.wantErrs == 0 && (*req != tt.want) {
+			t.Errorf("%s: parsed %+v, want %+v", tt.name, *req, tt.want)
+		}
+	}
+}
+
+func TestReadStatementRejectsMissingColumns(t *testing.T) {
+	mapping, _ := resolveImportMapping("databank", nil)
+	_, _, err := readStatement(strings.NewReader("Trade Date,Transaction,Security,Quantity\n"), mapping)
+	if !errors.Is(err, ErrInvalidImport) || !strings.Contains(err.Error(), "Consideration") {
+		t.Errorf("error = %v, want the missing columns listed", err)
+	}
+}
+
+func TestImportTransactions(t *testing.T) {
+	const header = "date,type,symbol,quantity,price,fees,notes\n"
+	const fills = header +
+		"2026-03-02,buy,MTNGH,100,2.00,0,\n" +
+		"2026-03-02,buy,MTNGH,100,2.00,0,\n" +
+		"2026-03-05,sell,MTNGH,150,2.50,1.00,\n"
+	ctx := context.Background()
+
+	service, portfolio := newTestPortfolioService(t, models.CostBasisFIFO)
+	recorded := func() int {
+		t.Helper()
+		ledger, err := service.portfolioRepo.GetTransactions(ctx, portfolio.ID)
+		if err != nil {
+			t.Fatalf("GetTransactions: %v", err)
+		}
+		return len(ledger)
+	}
+	importStatement := func(statement string, dryRun bool) *models.ImportResult {
+		t.Helper()
+		result, err := service.ImportTransactions(ctx, portfolio.UserID, portfolio.ID, strings.NewReader(statement), "generic", nil, dryRun)
+		if err != nil {
+			t.Fatalf("ImportTransactions: %v", err)
+		}
+		return result
+	}
+
+	// Nothing is recorded on a dry run
+	result := importStatement(fills, true)
+	if result.Imported != 3 || result.Committed || recorded() != 0 {
+		t.Fatalf("dry run imported %d, committed %v, recorded %d", result.Imported, result.Committed, recorded())
+	}
+
+	// One bad row keeps the rest out too, including a sell the ledger can't cover
+	result = importStatement(fills+"2026-03-06,buy,NOTLISTED,10,1.00,0,\n"+"2026-03-07,sell,GCB,10,6.00,0,\n", false)
+	if result.Imported != 3 || result.Errors != 2 || result.Committed || recorded() != 0 {
+		t.Fatalf("bad rows: imported %d, errors %d, committed %v, recorded %d",
+			result.Imported, result.Errors, result.Committed, recorded())
+	}
+
+	// Two identical fills in one statement are both recorded
+	result = importStatement(fills, false)
+	if result.Imported != 3 || result.Duplicates != 0 || !result.Committed || recorded() != 3 {
+		t.Fatalf("import: imported %d, duplicates %d, committed %v, recorded %d",
+			result.Imported, result.Duplicates, result.Committed, recorded())
+	}
+
+	// Uploading it again skips every row, and a third identical fill is new
+	result = importStatement(fills+"2026-03-02,buy,MTNGH,100,2.00,0,\n", false)
+	if result.Imported != 1 || result.Duplicates != 3 || !result.Committed || recorded() != 4 {
+		t.Fatalf("re-upload: imported %d, duplicates %d, committed %v, recorded %d",
+			result.Imported, result.Duplicates, result.Committed, recorded())
+	}
+}
@@ -0,0 +1,677 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+const maxPortfolioNameLength = 100
+
+// snapshotDateLayout is how snapshot days are stored and requested
+const snapshotDateLayout = "2006-01-02"
+
+var (
+	ErrPortfolioNotFound   = errors.New("portfolio not found")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInvalidPortfolio    = errors.New("invalid portfolio")
+	ErrInvalidTransaction  = errors.New("invalid transaction")
+	ErrInsufficientShares  = errors.New("ledger sells more shares than it holds")
+)
+
+// PortfolioService keeps users' transaction ledgers and derives their
+// holdings, profit and loss, and daily value history
+type PortfolioService struct {
+	portfolioRepo repository.PortfolioStore
+	stockService  *StockService
+	config        *config.PortfolioConfig
+	location      *time.Location
+}
+
+func NewPortfolioService(portfolioRepo repository.PortfolioStore, stockService *StockService, cfg *config.PortfolioConfig) *PortfolioService {
+	location, err := time.LoadLocation(cfg.SnapshotTimezone)
+	if err != nil {
+		log.Printf("Invalid portfolio snapshot timezone %q, falling back to UTC: %v", cfg.SnapshotTimezone, err)
+		location = time.UTC
+	}
+
+	return &PortfolioService{
+		portfolioRepo: portfolioRepo,
+		stockService:  stockService,
+		config:        cfg,
+		location:      location,
+	}
+}
+
+func (s *PortfolioService) Create(ctx context.Context, userID string, req *models.CreatePortfolioRequest) (*models.Portfolio, error) {
+	name, err := validatePortfolioName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	method, err := validateCostBasisMethod(req.CostBasisMethod, models.CostBasisAverage)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	portfolio := &models.Portfolio{
+		ID:              uuid.New().String(),
+		UserID:          userID,
+		Name:            name,
+		CostBasisMethod: method,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.portfolioRepo.Create(ctx, portfolio); err != nil {
+		return nil, fmt.Errorf("failed to create portfolio: %w", err)
+	}
+	return portfolio, nil
+}
+
+func (s *PortfolioService) List(ctx context.Context, userID string) ([]*models.Portfolio, error) {
+	return s.portfolioRepo.GetByUserID(ctx, userID)
+}
+
+// Get returns the user's portfolio, or ErrPortfolioNotFound if they don't own it
+func (s *PortfolioService) Get(ctx context.Context, userID, id string) (*models.Portfolio, error) {
+	portfolio, err := s.portfolioRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPortfolioNotFound
+		}
+		return nil, err
+	}
+	if portfolio.UserID != userID {
+		return nil, ErrPortfolioNotFound
+	}
+	return portfolio, nil
+}
+
+// Update renames the portfolio and/or changes its cost-basis method. A new
+// method must be able to replay the existing ledger.
+func (s *PortfolioService) Update(ctx context.Context, userID, id string, req *models.CreatePortfolioRequest) (*models.Portfolio, error) {
+	portfolio, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	name, err := validatePortfolioName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	method, err := validateCostBasisMethod(req.CostBasisMethod, portfolio.CostBasisMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	if method != portfolio.CostBasisMethod {
+		ledger, err := s.portfolioRepo.GetTransactions(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		// Other methods would ignore the lots that sells name
+		if method != models.CostBasisSpecificLot {
+			for _, t := range ledger {
+				if t.LotID != "" {
+					return nil, fmt.Errorf("%w: the sell on %s names a lot, which only specific_lot uses", ErrInvalidPortfolio,
+						t.TradeDate.Format(snapshotDateLayout))
+				}
+			}
+		}
+		if _, err := replayLedger(ledger, method); err != nil {
+			return nil, err
+		}
+	}
+
+	portfolio.Name = name
+	portfolio.CostBasisMethod = method
+	if err := s.portfolioRepo.Update(ctx, portfolio); err != nil {
+		return nil, fmt.Errorf("failed to update portfolio: %w", err)
+	}
+	return portfolio, nil
+}
+
+func (s *PortfolioService) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.portfolioRepo.Delete(ctx, id)
+}
+
+func (s *PortfolioService) ListTransactions(ctx context.Context, userID, portfolioID string) ([]*models.Transaction, error) {
+	if _, err := s.Get(ctx, userID, portfolioID); err != nil {
+		return nil, err
+	}
+	return s.portfolioRepo.GetTransactions(ctx, portfolioID)
+}
+
+// AddTransaction records a transaction, refusing any that would leave the
+// ledger selling shares it doesn't hold at that date. A dividend without a
+// price is paid at the stock's latest published DPS.
+func (s *PortfolioService) AddTransaction(ctx context.Context, userID, portfolioID string, req *models.CreateTransactionRequest) (*models.Transaction, error) {
+	portfolio, err := s.Get(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.fillDividendPrice(req)
+	t, err := newTransaction(portfolioID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := s.portfolioRepo.GetTransactions(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateLotReference(ledger, t, portfolio.CostBasisMethod); err != nil {
+		return nil, err
+	}
+	if _, err := replayLedger(append(ledger, t), portfolio.CostBasisMethod); err != nil {
+		return nil, err
+	}
+
+	if err := s.portfolioRepo.CreateTransaction(ctx, t); err != nil {
+		return nil, fmt.Errorf("failed to record transaction: %w", err)
+	}
+	return t, nil
+}
+
+// fillDividendPrice prices a dividend entered without one at the stock's
+// latest published DPS, if any
+func (s *PortfolioService) fillDividendPrice(req *models.CreateTransactionRequest) {
+	if req.Type != models.TransactionTypeDividend || req.Price != 0 || strings.TrimSpace(req.StockSymbol) == "" {
+		return
+	}
+	if details, err := s.stockService.GetStockDetails(strings.TrimSpace(req.StockSymbol)); err == nil && details.DPS != nil {
+		req.Price = *details.DPS
+	}
+}
+
+// DeleteTransaction removes a transaction, unless later sells depend on it
+func (s *PortfolioService) DeleteTransaction(ctx context.Context, userID, portfolioID, id string) error {
+	portfolio, err := s.Get(ctx, userID, portfolioID)
+	if err != nil {
+		return err
+	}
+
+	ledger, err := s.portfolioRepo.GetTransactions(ctx, portfolioID)
+	if err != nil {
+		return err
+	}
+	remaining := make([]*models.Transaction, 0, len(ledger))
+	for _, t := range ledger {
+		if t.ID == id {
+			continue
+		}
+		if t.LotID == id {
+			return fmt.Errorf("%w: a sell on %s draws from this lot", ErrInsufficientShares,
+				t.TradeDate.Format(snapshotDateLayout))
+		}
+		remaining = append(remaining, t)
+	}
+	if len(remaining) == len(ledger) {
+		return ErrTransactionNotFound
+	}
+	if _, err := replayLedger(remaining, portfolio.CostBasisMethod); err != nil {
+		return err
+	}
+
+	if err := s.portfolioRepo.DeleteTransaction(ctx, portfolioID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTransactionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Value derives the portfolio's holdings from its ledger and prices them
+// from a single fetch of the live board. Symbols missing from the board are
+// carried at cost and marked unpriced.
+func (s *PortfolioService) Value(ctx context.Context, userID, portfolioID string) (*models.PortfolioValuation, error) {
+	portfolio, err := s.Get(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := s.portfolioRepo.GetTransactions(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := replayLedger(ledger, portfolio.CostBasisMethod)
+	if err != nil {
+		return nil, err
+	}
+	board, err := s.liveBoard()
+	if err != nil {
+		return nil, err
+	}
+
+	valuation := &models.PortfolioValuation{
+		Portfolio: portfolio,
+		Holdings:  []*models.Holding{},
+		ValuedAt:  time.Now(),
+	}
+	for _, l := range result.open() {
+		quantity, cost := l.quantity(), l.cost()
+		holding := &models.Holding{
+			StockSymbol: l.symbol,
+			StockName:   l.symbol,
+			Quantity:    quantity,
+			AverageCost: cost / quantity,
+			CostBasis:   cost,
+			MarketValue: cost,
+		}
+		if stock, ok := board[l.symbol]; ok {
+			holding.StockName = stock.Name
+			holding.CurrentPrice = stock.CurrentPrice
+			holding.MarketValue = quantity * stock.CurrentPrice
+			holding.DayChange = quantity * stock.Change
+			holding.Priced = true
+		}
+		holding.UnrealizedGain = holding.MarketValue - holding.CostBasis
+		if holding.CostBasis > 0 {
+			holding.UnrealizedGainPercent = holding.UnrealizedGain / holding.CostBasis * 100
+		}
+
+		valuation.Holdings = append(valuation.Holdings, holding)
+		valuation.CostBasis += holding.CostBasis
+		valuation.MarketValue += holding.MarketValue
+		valuation.DayChange += holding.DayChange
+	}
+	valuation.UnrealizedGain = valuation.MarketValue - valuation.CostBasis
+	for _, t := range ledger {
+		switch t.Type {
+		case models.TransactionTypeBuy:
+			valuation.NetInvested += t.Quantity*t.Price + t.Fees
+		case models.TransactionTypeSell:
+			valuation.NetInvested -= t.Quantity*t.Price - t.Fees
+		}
+	}
+
+	for _, holding := range valuation.Holdings {
+		if valuation.MarketValue > 0 {
+			holding.Weight = holding.MarketValue / valuation.MarketValue * 100
+		}
+	}
+	sort.Slice(valuation.Holdings, func(i, j int) bool {
+		return valuation.Holdings[i].MarketValue > valuation.Holdings[j].MarketValue
+	})
+
+	return valuation, nil
+}
+
+// PnL reports realised and unrealised gains, dividends and fees under the
+// given cost-basis method, or the portfolio's own if method is empty
+func (s *PortfolioService) PnL(ctx context.Context, userID, portfolioID, method string) (*models.PortfolioPnL, error) {
+	portfolio, err := s.Get(ctx, userID, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	method, err = validateCostBasisMethod(method, portfolio.CostBasisMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := s.portfolioRepo.GetTransactions(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := replayLedger(ledger, method)
+	if err != nil {
+		return nil, err
+	}
+	board, err := s.liveBoard()
+	if err != nil {
+		return nil, err
+	}
+
+	pnl := &models.PortfolioPnL{
+		PortfolioID:     portfolio.ID,
+		CostBasisMethod: method,
+		OtherFees:       result.otherFees,
+		Fees:            result.otherFees,
+		Holdings:        []*models.HoldingPnL{},
+		ValuedAt:        time.Now(),
+	}
+	for _, l := range result.symbols {
+		quantity, cost := l.quantity(), l.cost()
+		holding := &models.HoldingPnL{
+			StockSymbol:    l.symbol,
+			Quantity:       quantity,
+			CostBasis:      cost,
+			MarketValue:    cost,
+			RealizedGain:   l.realized,
+			DividendIncome: l.dividends,
+			Fees:           l.fees,
+			Lots:           []*models.Lot{},
+		}
+		for _, lot := range l.lots {
+			holding.Lots = append(holding.Lots, &models.Lot{
+				ID:           lot.id,
+				TradeDate:    lot.tradeDate,
+				Quantity:     lot.quantity,
+				CostPerShare: lot.costPerShare,
+			})
+		}
+		if stock, ok := board[l.symbol]; ok {
+			holding.MarketValue = quantity * stock.CurrentPrice
+			holding.Priced = true
+		}
+		holding.UnrealizedGain = holding.MarketValue - holding.CostBasis
+
+		if quantity > 0 {
+			if details, err := s.stockService.GetStockDetails(l.symbol); err == nil && details.DPS != nil {
+				estimate := quantity * *details.DPS
+				holding.EstimatedAnnualDividend = &estimate
+				pnl.EstimatedAnnualDividends += estimate
+			}
+		}
+
+		pnl.Holdings = append(pnl.Holdings, holding)
+		pnl.CostBasis += holding.CostBasis
+		pnl.MarketValue += holding.MarketValue
+		pnl.UnrealizedGain += holding.UnrealizedGain
+		pnl.RealizedGain += holding.RealizedGain
+		pnl.DividendIncome += holding.DividendIncome
+		pnl.Fees += holding.Fees
+	}
+
+	// Commissions and withholding are already inside the gains and dividends
+	pnl.TotalReturn = pnl.RealizedGain + pnl.UnrealizedGain + pnl.DividendIncome - pnl.OtherFees
+
+	return pnl, nil
+}
+
+// Snapshots returns the portfolio's daily values from the given date
+// (YYYY-MM-DD; a year ago if empty) with its time-weighted return and the
+// market benchmark, both rebased to 100 on the first day
+func (s *PortfolioService) Snapshots(ctx context.Context, userID, portfolioID, from string) ([]*models.PortfolioSnapshot, error) {
+	if _, err := s.Get(ctx, userID, portfolioID); err != nil {
+		return nil, err
+	}
+	if from == "" {
+		from = time.Now().In(s.location).AddDate(-1, 0, 0).Format(snapshotDateLayout)
+	} else if _, err := time.Parse(snapshotDateLayout, from); err != nil {
+		return nil, fmt.Errorf("%w: from must be a date like 2024-01-31", ErrInvalidPortfolio)
+	}
+
+	snapshots, err := s.portfolioRepo.GetSnapshots(ctx, portfolioID, from)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return []*models.PortfolioSnapshot{}, nil
+	}
+
+	market, err := s.portfolioRepo.GetMarketSnapshots(ctx, snapshots[0].SnapshotDate)
+	if err != nil {
+		return nil, err
+	}
+	marketByDate := make(map[string]float64, len(market))
+	for _, m := range market {
+		marketByDate[m.SnapshotDate] = m.IndexValue
+	}
+	marketBase := marketByDate[snapshots[0].SnapshotDate]
+
+	index := 100.0
+	for i, snapshot := range snapshots {
+		// Strip the day's deposits and withdrawals out of the change in value
+		if i > 0 && snapshots[i-1].MarketValue > 0 {
+			index *= (snapshot.MarketValue - snapshot.NetFlow) / snapshots[i-1].MarketValue
+		}
+		snapshot.ReturnIndex = index
+		if value, ok := marketByDate[snapshot.SnapshotDate]; ok && marketBase > 0 {
+			snapshot.MarketIndex = value / marketBase * 100
+		}
+	}
+
+	return snapshots, nil
+}
+
+func (s *PortfolioService) StartSnapshotter() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	log.Printf("Starting portfolio snapshotter (%02d:00 %s)...", s.config.SnapshotHour, s.location)
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.takeSnapshots(context.Background(), time.Now()); err != nil {
+				log.Printf("Error taking portfolio snapshots: %v", err)
+			}
+		}
+	}
+}
+
+// takeSnapshots records every portfolio's closing value once per weekday,
+// after the snapshot hour. The market snapshot marks the day as done, so it
+// is only written once every portfolio has been snapshotted; otherwise the
+// run returns an error and is retried on the next tick.
+func (s *PortfolioService) takeSnapshots(ctx context.Context, now time.Time) error {
+	local := now.In(s.location)
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday || local.Hour() < s.config.SnapshotHour {
+		return nil
+	}
+	date := local.Format(snapshotDateLayout)
+	if _, err := s.portfolioRepo.GetMarketSnapshot(ctx, date); err == nil {
+		return nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	stocks, err := s.stockService.GetAllStocks()
+	if err != nil {
+		return fmt.Errorf("failed to fetch prices: %w", err)
+	}
+	board := boardBySymbol(stocks)
+
+	portfolios, err := s.portfolioRepo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get portfolios: %w", err)
+	}
+	failed := 0
+	for _, portfolio := range portfolios {
+		if err := s.snapshotPortfolio(ctx, portfolio, date, board); err != nil {
+			log.Printf("Failed to snapshot portfolio %s: %v", portfolio.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to snapshot %d of %d portfolios", failed, len(portfolios))
+	}
+
+	previous := 100.0
+	if last, err := s.portfolioRepo.GetMarketSnapshotBefore(ctx, date); err == nil {
+		previous = last.IndexValue
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return s.portfolioRepo.UpsertMarketSnapshot(ctx, &models.MarketSnapshot{
+		SnapshotDate: date,
+		IndexValue:   previous * (1 + averageChangePercent(stocks)/100),
+	})
+}
+
+func (s *PortfolioService) snapshotPortfolio(ctx context.Context, portfolio *models.Portfolio, date string, board map[string]models.EnhancedStock) error {
+	ledger, err := s.portfolioRepo.GetTransactions(ctx, portfolio.ID)
+	if err != nil {
+		return err
+	}
+	result, err := replayLedger(ledger, portfolio.CostBasisMethod)
+	if err != nil {
+		return err
+	}
+
+	snapshot := &models.PortfolioSnapshot{PortfolioID: portfolio.ID, SnapshotDate: date}
+	for _, l := range result.open() {
+		quantity, cost := l.quantity(), l.cost()
+		snapshot.CostBasis += cost
+		if stock, ok := board[l.symbol]; ok {
+			snapshot.MarketValue += quantity * stock.CurrentPrice
+		} else {
+			snapshot.MarketValue += cost
+		}
+	}
+	for _, t := range ledger {
+		if t.TradeDate.In(s.location).Format(snapshotDateLayout) != date {
+			continue
+		}
+		switch t.Type {
+		case models.TransactionTypeBuy:
+			snapshot.NetFlow += t.Quantity*t.Price + t.Fees
+		case models.TransactionTypeSell, models.TransactionTypeDividend:
+			snapshot.NetFlow -= t.Quantity*t.Price - t.Fees
+		}
+	}
+
+	return s.portfolioRepo.UpsertSnapshot(ctx, snapshot)
+}
+
+// liveBoard fetches the live board once, keyed by symbol
+func (s *PortfolioService) liveBoard() (map[string]models.EnhancedStock, error) {
+	stocks, err := s.stockService.GetAllStocks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch prices: %w", err)
+	}
+	return boardBySymbol(stocks), nil
+}
+
+func boardBySymbol(stocks []models.EnhancedStock) map[string]models.EnhancedStock {
+	board := make(map[string]models.EnhancedStock, len(stocks))
+	for _, stock := range stocks {
+		board[strings.ToUpper(stock.Symbol)] = stock
+	}
+	return board
+}
+
+// averageChangePercent is the equal-weighted day's change across traded stocks
+func averageChangePercent(stocks []models.EnhancedStock) float64 {
+	var total float64
+	var count int
+	for _, stock := range stocks {
+		if stock.CurrentPrice > 0 {
+			total += stock.ChangePercent
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+func validatePortfolioName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidPortfolio)
+	}
+	if len(name) > maxPortfolioNameLength {
+		return "", fmt.Errorf("%w: name must be at most %d characters", ErrInvalidPortfolio, maxPortfolioNameLength)
+	}
+	return name, nil
+}
+
+// validateCostBasisMethod checks method, using fallback when it's empty
+func validateCostBasisMethod(method, fallback string) (string, error) {
+	if method == "" {
+		return fallback, nil
+	}
+	if !costBasisMethods[method] {
+		return "", fmt.Errorf("%w: costBasisMethod must be average, fifo or specific_lot", ErrInvalidPortfolio)
+	}
+	return method, nil
+}
+
+// validateLotReference checks that a sell's lotId names an earlier buy of
+// the same stock, in a portfolio that sells by specific lot
+func validateLotReference(ledger []*models.Transaction, t *models.Transaction, method string) error {
+	if t.LotID == "" {
+		return nil
+	}
+	if method != models.CostBasisSpecificLot {
+		return fmt.Errorf("%w: lotId needs the portfolio's cost-basis method to be specific_lot, not %s", ErrInvalidTransaction, method)
+	}
+	for _, existing := range ledger {
+		if existing.ID == t.LotID {
+			if existing.Type != models.TransactionTypeBuy || existing.StockSymbol != t.StockSymbol ||
+				existing.TradeDate.After(t.TradeDate) {
+				break
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: lotId must be an earlier buy of %s", ErrInvalidTransaction, t.StockSymbol)
+}
+
+// newTransaction validates a request and builds the ledger entry for it
+func newTransaction(portfolioID string, req *models.CreateTransactionRequest) (*models.Transaction, error) {
+	t := &models.Transaction{
+		ID:          uuid.New().String(),
+		PortfolioID: portfolioID,
+		Type:        req.Type,
+		StockSymbol: strings.ToUpper(strings.TrimSpace(req.StockSymbol)),
+		Quantity:    req.Quantity,
+		Price:       req.Price,
+		Fees:        req.Fees,
+		TradeDate:   req.TradeDate.UTC(),
+		LotID:       strings.TrimSpace(req.LotID),
+		Notes:       strings.TrimSpace(req.Notes),
+		CreatedAt:   time.Now(),
+	}
+
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidTransaction, fmt.Sprintf(format, args...))
+	}
+
+	switch t.Type {
+	case models.TransactionTypeBuy, models.TransactionTypeSell, models.TransactionTypeDividend:
+		if t.StockSymbol == "" {
+			return nil, invalid("stockSymbol is required for %s transactions", t.Type)
+		}
+		if !(t.Quantity > 0) || math.IsInf(t.Quantity, 0) {
+			return nil, invalid("quantity must be greater than zero")
+		}
+		if !(t.Price > 0) || math.IsInf(t.Price, 0) {
+			if t.Type == models.TransactionTypeDividend {
+				return nil, invalid("price is required; no DPS is published for %s", t.StockSymbol)
+			}
+			return nil, invalid("price must be greater than zero")
+		}
+		if !(t.Fees >= 0) || math.IsInf(t.Fees, 0) {
+			return nil, invalid("fees cannot be negative")
+		}
+	case models.TransactionTypeFee:
+		if !(t.Fees > 0) || math.IsInf(t.Fees, 0) {
+			return nil, invalid("fees must be greater than zero for fee transactions")
+		}
+		t.Quantity, t.Price = 0, 0
+	default:
+		return nil, invalid("type must be buy, sell, dividend or fee")
+	}
+
+	if t.LotID != "" && t.Type != models.TransactionTypeSell {
+		return nil, invalid("lotId is only valid on sell transactions")
+	}
+	if t.TradeDate.IsZero() {
+		return nil, invalid("tradeDate is required")
+	}
+	if t.TradeDate.After(time.Now().Add(24 * time.Hour)) {
+		return nil, invalid("tradeDate cannot be in the future")
+	}
+
+	return t, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestTakeSnapshotsWaitsForEveryPortfolio(t *testing.T) {
+	ctx := context.Background()
+	service, portfolio := newTestPortfolioService(t, models.CostBasisFIFO)
+	monday := time.Date(2026, 3, 16, 18, 0, 0, 0, time.UTC)
+	date := monday.Format(snapshotDateLayout)
+
+	// A sell with nothing to cover it can't be replayed
+	sell := testTransaction("s1", models.TransactionTypeSell, 10, 2.50, 0, 9, "")
+	sell.PortfolioID = portfolio.ID
+	if err := service.portfolioRepo.CreateTransaction(ctx, sell); err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+
+	if err := service.takeSnapshots(ctx, monday); err == nil {
+		t.Fatal("takeSnapshots succeeded with a portfolio that can't be snapshotted")
+	}
+	if _, err := service.portfolioRepo.GetMarketSnapshot(ctx, date); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("market snapshot after a failed run: error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Once the ledger is fixed, the next run records the day
+	if err := service.portfolioRepo.DeleteTransaction(ctx, portfolio.ID, sell.ID); err != nil {
+		t.Fatalf("DeleteTransaction: %v", err)
+	}
+	if err := service.takeSnapshots(ctx, monday); err != nil {
+		t.Fatalf("takeSnapshots: %v", err)
+	}
+	if _, err := service.portfolioRepo.GetMarketSnapshot(ctx, date); err != nil {
+		t.Fatalf("GetMarketSnapshot: %v", err)
+	}
+	snapshots, err := service.portfolioRepo.GetSnapshots(ctx, portfolio.ID, date)
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("GetSnapshots = %d snapshots, %v; want 1", len(snapshots), err)
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"shares-alert-backend/internal/models"
+)
+
+// ValidatePreferences checks user-supplied preferences and fills in defaults
+func ValidatePreferences(prefs *models.UserPreferences) error {
+	switch prefs.NotificationFrequency {
+	case "":
+		prefs.NotificationFrequency = models.NotificationFrequencyImmediate
+	case models.NotificationFrequencyImmediate, models.NotificationFrequencyDaily, models.NotificationFrequencyWeekly:
+	default:
+		return fmt.Errorf("notificationFrequency must be immediate, daily or weekly")
+	}
+
+	if prefs.Timezone != "" {
+		if _, err := time.LoadLocation(prefs.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", prefs.Timezone)
+		}
+	}
+
+	if (prefs.QuietHoursStart == "") != (prefs.QuietHoursEnd == "") {
+		return fmt.Errorf("quietHoursStart and quietHoursEnd must be set together")
+	}
+	if prefs.QuietHoursStart != "" {
+		if _, err := parseClock(prefs.QuietHoursStart); err != nil {
+			return fmt.Errorf("invalid quietHoursStart: %w", err)
+		}
+		if _, err := parseClock(prefs.QuietHoursEnd); err != nil {
+			return fmt.Errorf("invalid quietHoursEnd: %w", err)
+		}
+	}
+
+	if prefs.Locale == "" {
+		prefs.Locale = DefaultLocale
+	} else if !isSupportedLocale(prefs.Locale) {
+		return fmt.Errorf("unsupported locale %q", prefs.Locale)
+	}
+
+	switch prefs.QuietHoursMode {
+	case "":
+		prefs.QuietHoursMode = models.QuietHoursModeHold
+	case models.QuietHoursModeHold, models.QuietHoursModeSummary:
+	default:
+		return fmt.Errorf("quietHoursMode must be hold or summary")
+	}
+
+	return nil
+}
+
+// parseClock parses an HH:MM time of day into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// preferenceLocation returns the user's timezone, or fallback if unset or invalid
+func preferenceLocation(prefs *models.UserPreferences, fallback *time.Location) *time.Location {
+	if prefs == nil || prefs.Timezone == "" {
+		return fallback
+	}
+	location, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		return fallback
+	}
+	return location
+}
+
+// quietHoursEnd reports whether now falls inside the user's quiet window and,
+// if it does, when the window ends. Windows may wrap past midnight.
+func quietHoursEnd(prefs *models.UserPreferences, now time.Time, fallback *time.Location) (time.Time, bool) {
+	if prefs == nil || prefs.QuietHoursStart == "" || prefs.QuietHoursEnd == "" {
+		return time.Time{}, false
+	}
+	start, err := parseClock(prefs.QuietHoursStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := parseClock(prefs.QuietHoursEnd)
+	if err != nil || start == end {
+		return time.Time{}, false
+	}
+
+	local := now.In(preferenceLocation(prefs, fallback))
+	minute := local.Hour()*60 + local.Minute()
+	endToday := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, local.Location())
+
+	if start < end {
+		if minute >= start && minute < end {
+			return endToday, true
+		}
+		return time.Time{}, false
+	}
+
+	// Window wraps midnight, e.g. 22:00-07:00
+	if minute >= start {
+		return endToday.AddDate(0, 0, 1), true
+	}
+	if minute < end {
+		return endToday, true
+	}
+	return time.Time{}, false
+}
+
+// isSupportedLocale reports whether email templates exist for the locale
+func isSupportedLocale(locale string) bool {
+	if strings.ContainsAny(locale, "/.") {
+		return false
+	}
+	_, err := fs.Stat(templateFS, "templates/locales/"+locale+".json")
+	return err == nil
+}
+
+// preferenceLocale returns the user's email locale, or the default if unset
+func preferenceLocale(prefs *models.UserPreferences) string {
+	if prefs == nil || prefs.Locale == "" {
+		return DefaultLocale
+	}
+	return prefs.Locale
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"shares-alert-backend/internal/models"
+)
+
+func TestQuietHoursEnd(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		prefs     *models.UserPreferences
+		fallback  *time.Location
+		now       time.Time
+		wantEnd   time.Time
+		wantQuiet bool
+	}{
+		{"no preferences", nil, time.UTC, at(14, 23, 0), time.Time{}, false},
+		{"quiet hours off", &models.UserPreferences{}, time.UTC, at(14, 23, 0), time.Time{}, false},
+		{"empty window", &models.UserPreferences{QuietHoursStart: "22:00", QuietHoursEnd: "22:00"}, time.UTC, at(14, 22, 0), time.Time{}, false},
+		{"inside a daytime window", &models.UserPreferences{QuietHoursStart: "12:00", QuietHoursEnd: "14:00"}, time.UTC, at(14, 13, 0), at(14, 14, 0), true},
+		{"daytime window ends exclusive", &models.UserPreferences{QuietHoursStart: "12:00", QuietHoursEnd: "14:00"}, time.UTC, at(14, 14, 0), time.Time{}, false},
+		{"before midnight in a wrapping window", &models.UserPreferences{QuietHoursStart: "22:00", QuietHoursEnd: "07:00"}, time.UTC, at(14, 23, 30), at(15, 7, 0), true},
+		{"after midnight in a wrapping window", &models.UserPreferences{QuietHoursStart: "22:00", QuietHoursEnd: "07:00"}, time.UTC, at(15, 2, 0), at(15, 7, 0), true},
+		{"outside a wrapping window", &models.UserPreferences{QuietHoursStart: "22:00", QuietHoursEnd: "07:00"}, time.UTC, at(15, 7, 0), time.Time{}, false},
+		{"start of a wrapping window", &models.UserPreferences{QuietHoursStart: "22:00", QuietHoursEnd: "07:00"}, time.UTC, at(14, 22, 0), at(15, 7, 0), true},
+		{
+			"user's timezone",
+			&models.UserPreferences{QuietHoursStart: "22:00", QuietHoursEnd: "07:00", Timezone: "Europe/Paris"},
+			time.UTC,
+			time.Date(2026, 3, 14, 21, 30, 0, 0, time.UTC), // 22:30 in Paris
+			time.Date(2026, 3, 15, 7, 0, 0, 0, time.FixedZone("CET", 60*60)),
+			true,
+		},
+		{
+			"fallback timezone",
+			&models.UserPreferences{QuietHoursStart: "22:00", QuietHoursEnd: "07:00"},
+			zone,
+			time.Date(2026, 3, 14, 21, 0, 0, 0, time.UTC), // 23:00 at UTC+2
+			time.Date(2026, 3, 15, 7, 0, 0, 0, zone),
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		end, quiet := quietHoursEnd(tt.prefs, tt.now, tt.fallback)
+		if quiet != tt.wantQuiet || !end.Equal(tt.wantEnd) {
+			t.Errorf("%s: quietHoursEnd = %v, %v; want %v, %v", tt.name, end, quiet, tt.wantEnd, tt.wantQuiet)
+		}
+	}
+}
+
+func TestValidatePreferences(t *testing.T) {
+	prefs := &models.UserPreferences{}
+	if err := ValidatePreferences(prefs); err != nil {
+		t.Fatalf("ValidatePreferences(empty): %v", err)
+	}
+	if prefs.NotificationFrequency != models.NotificationFrequencyImmediate || prefs.QuietHoursMode != models.QuietHoursModeHold || prefs.Locale != DefaultLocale {
+		t.Errorf("defaults not filled in: %+v", prefs)
+	}
+
+	for _, bad := range []*models.UserPreferences{
+		{NotificationFrequency: "hourly"},
+		{Timezone: "Mars/Olympus"},
+		{QuietHoursStart: "22:00"},
+		{QuietHoursStart: "25:00", QuietHoursEnd: "07:00"},
+		{QuietHoursMode: "drop"},
+		{Locale: "../en"},
+	} {
+		if err := ValidatePreferences(bad); err == nil {
+			t.Errorf("ValidatePreferences(%+v) succeeded, want an error", bad)
+		}
+	}
+}
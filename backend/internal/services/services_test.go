@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/config"
+	"shares-alert-backend/internal/database"
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+// newTestDB returns an empty, fully migrated SQLite database
+func newTestDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	db, err := database.New(&config.DatabaseConfig{Type: "sqlite", FilePath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func createTestUser(t *testing.T, db *database.DB, email string) *models.User {
+	t.Helper()
+
+	now := time.Now().UTC()
+	user := &models.User{ID: uuid.New().String(), Email: email, Name: "Test User", CreatedAt: now, UpdatedAt: now}
+	if err := repository.NewUserRepository(db).Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
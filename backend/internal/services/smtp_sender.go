@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-msgauth/dkim"
+
+	"shares-alert-backend/internal/config"
+)
+
+// SMTP TLS modes
+const (
+	SMTPTLSModeStartTLS = "starttls"
+	SMTPTLSModeImplicit = "tls"
+	SMTPTLSModeNone     = "none"
+)
+
+// dkimHeaderKeys are the headers covered by the DKIM signature. RFC 8058
+// requires List-Unsubscribe and List-Unsubscribe-Post to be signed.
+var dkimHeaderKeys = []string{
+	"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type",
+	"List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// SMTPSender delivers mail over SMTP, keeping a small pool of authenticated
+// connections open between sends
+type SMTPSender struct {
+	config  *config.EmailConfig
+	timeout time.Duration
+	idleFor time.Duration
+	dkim    *dkim.SignOptions
+
+	mu   sync.Mutex
+	idle []*smtpConn
+}
+
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+func NewSMTPSender(cfg *config.EmailConfig) (*SMTPSender, error) {
+	switch cfg.SMTPTLSMode {
+	case SMTPTLSModeStartTLS, SMTPTLSModeImplicit, SMTPTLSModeNone:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", cfg.SMTPTLSMode)
+	}
+
+	s := &SMTPSender{
+		config:  cfg,
+		timeout: time.Duration(cfg.SMTPTimeoutSeconds) * time.Second,
+		idleFor: time.Duration(cfg.SMTPIdleTimeoutSeconds) * time.Second,
+	}
+
+	if cfg.DKIMDomain != "" {
+		signer, err := loadDKIMKey(cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.dkim = &dkim.SignOptions{
+			Domain:                 cfg.DKIMDomain,
+			Selector:               cfg.DKIMSelector,
+			Signer:                 signer,
+			HeaderKeys:             dkimHeaderKeys,
+			HeaderCanonicalization: dkim.CanonicalizationRelaxed,
+			BodyCanonicalization:   dkim.CanonicalizationRelaxed,
+		}
+	}
+
+	return s, nil
+}
+
+func (s *SMTPSender) Send(from string, to []string, msg []byte) error {
+	if s.config.SMTPUser == "" || s.config.SMTPPassword == "" {
+		return fmt.Errorf("email service not configured")
+	}
+
+	if s.dkim != nil {
+		var signed bytes.Buffer
+		if err := dkim.Sign(&signed, bytes.NewReader(msg), s.dkim); err != nil {
+			return fmt.Errorf("failed to DKIM sign email: %w", err)
+		}
+		msg = signed.Bytes()
+	}
+
+	c, err := s.get()
+	if err != nil {
+		return err
+	}
+	if err := s.deliver(c, from, to, msg); err != nil {
+		// The session may be mid-transaction, so don't hand it back to the pool
+		c.client.Close()
+		return err
+	}
+	s.put(c)
+	return nil
+}
+
+// Close ends every pooled connection
+func (s *SMTPSender) Close() {
+	s.mu.Lock()
+	idle := s.idle
+	s.idle = nil
+	s.mu.Unlock()
+
+	for _, c := range idle {
+		s.quit(c)
+	}
+}
+
+func (s *SMTPSender) deliver(c *smtpConn, from string, to []string, msg []byte) error {
+	c.conn.SetDeadline(time.Now().Add(s.timeout))
+
+	if err := c.client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// get returns a pooled connection that still answers, or dials a new one
+func (s *SMTPSender) get() (*smtpConn, error) {
+	for {
+		s.mu.Lock()
+		if len(s.idle) == 0 {
+			s.mu.Unlock()
+			return s.dial()
+		}
+		c := s.idle[len(s.idle)-1]
+		s.idle = s.idle[:len(s.idle)-1]
+		s.mu.Unlock()
+
+		if time.Since(c.lastUsed) > s.idleFor {
+			s.quit(c)
+			continue
+		}
+		c.conn.SetDeadline(time.Now().Add(s.timeout))
+		if err := c.client.Reset(); err != nil {
+			c.client.Close()
+			continue
+		}
+		return c, nil
+	}
+}
+
+func (s *SMTPSender) put(c *smtpConn) {
+	c.lastUsed = time.Now()
+
+	s.mu.Lock()
+	if len(s.idle) < s.config.SMTPPoolSize {
+		s.idle = append(s.idle, c)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	s.quit(c)
+}
+
+func (s *SMTPSender) quit(c *smtpConn) {
+	c.conn.SetDeadline(time.Now().Add(s.timeout))
+	if err := c.client.Quit(); err != nil {
+		c.client.Close()
+	}
+}
+
+func (s *SMTPSender) dial() (*smtpConn, error) {
+	addr := net.JoinHostPort(s.config.SMTPHost, s.config.SMTPPort)
+	tlsConfig := &tls.Config{ServerName: s.config.SMTPHost}
+	dialer := &net.Dialer{Timeout: s.timeout}
+
+	var conn net.Conn
+	var err error
+	if s.config.SMTPTLSMode == SMTPTLSModeImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	client, err := smtp.NewClient(conn, s.config.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+
+	if s.config.SMTPTLSMode == SMTPTLSModeStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	auth := smtp.PlainAuth("", s.config.SMTPUser, s.config.SMTPPassword, s.config.SMTPHost)
+	if err := client.Auth(auth); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("SMTP authentication failed: %w", err)
+	}
+
+	return &smtpConn{conn: conn, client: client, lastUsed: time.Now()}, nil
+}
+
+// loadDKIMKey reads the PKCS#1 or PKCS#8 RSA/Ed25519 private key used for DKIM
+func loadDKIMKey(cfg *config.EmailConfig) (crypto.Signer, error) {
+	if cfg.DKIMSelector == "" {
+		return nil, fmt.Errorf("DKIM selector is required when a DKIM domain is set")
+	}
+
+	data := []byte(cfg.DKIMPrivateKey)
+	if len(data) == 0 {
+		if cfg.DKIMPrivateKeyFile == "" {
+			return nil, fmt.Errorf("DKIM private key is required when a DKIM domain is set")
+		}
+		var err error
+		if data, err = os.ReadFile(cfg.DKIMPrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read DKIM private key: %w", err)
+		}
+	}
+
+	signer, err := parsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("DKIM %w", err)
+	}
+	return signer, nil
+}
@@ -0,0 +1,476 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shares-alert-backend/internal/models"
+	"shares-alert-backend/internal/repository"
+)
+
+const (
+	maxWatchlistNameLength = 100
+	maxWatchlistItems      = 100
+	maxWatchlistNotes      = 500
+	maxSymbolLength        = 20
+)
+
+// Defaults for the standard alert set created from a watchlist
+const defaultWatchlistRisePercent = 10.0
+
+var (
+	ErrWatchlistNotFound      = errors.New("watchlist not found")
+	ErrWatchlistItemNotFound  = errors.New("symbol is not on this watchlist")
+	ErrInvalidWatchlist       = errors.New("invalid watchlist")
+	ErrDuplicateWatchlistItem = errors.New("symbol is already on this watchlist")
+)
+
+// WatchlistService keeps users' named watchlists and prices them from the
+// live board
+type WatchlistService struct {
+	watchlistRepo repository.WatchlistStore
+	alertRepo     repository.AlertStore
+	stockService  *StockService
+}
+
+func NewWatchlistService(watchlistRepo repository.WatchlistStore, alertRepo repository.AlertStore, stockService *StockService) *WatchlistService {
+	return &WatchlistService{
+		watchlistRepo: watchlistRepo,
+		alertRepo:     alertRepo,
+		stockService:  stockService,
+	}
+}
+
+// List returns the user's watchlists in order, each with its items
+func (s *WatchlistService) List(ctx context.Context, userID string) ([]*models.Watchlist, error) {
+	watchlists, err := s.watchlistRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.watchlistRepo.GetItemsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*models.Watchlist, len(watchlists))
+	for _, watchlist := range watchlists {
+		watchlist.Items = []*models.WatchlistItem{}
+		byID[watchlist.ID] = watchlist
+	}
+	for _, item := range items {
+		if watchlist, ok := byID[item.WatchlistID]; ok {
+			watchlist.Items = append(watchlist.Items, item)
+		}
+	}
+	return watchlists, nil
+}
+
+// Create adds a watchlist after the user's others, with any initial symbols
+// in the order given
+func (s *WatchlistService) Create(ctx context.Context, userID string, req *models.CreateWatchlistRequest) (*models.Watchlist, error) {
+	name, err := validateWatchlistName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Symbols) > maxWatchlistItems {
+		return nil, fmt.Errorf("%w: a watchlist holds at most %d symbols", ErrInvalidWatchlist, maxWatchlistItems)
+	}
+	existing, err := s.watchlistRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	watchlist := &models.Watchlist{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Position:  len(existing),
+		Items:     []*models.WatchlistItem{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	seen := make(map[string]bool, len(req.Symbols))
+	for _, raw := range req.Symbols {
+		symbol, err := normalizeWatchlistSymbol(raw)
+		if err != nil {
+			return nil, err
+		}
+		if seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		watchlist.Items = append(watchlist.Items, &models.WatchlistItem{
+			WatchlistID: watchlist.ID,
+			StockSymbol: symbol,
+			Position:    len(watchlist.Items),
+			AddedAt:     now,
+		})
+	}
+
+	if err := s.watchlistRepo.Create(ctx, watchlist); err != nil {
+		return nil, fmt.Errorf("failed to create watchlist: %w", err)
+	}
+	return watchlist, nil
+}
+
+// Get returns the user's watchlist with its items, or ErrWatchlistNotFound
+// if they don't own it
+func (s *WatchlistService) Get(ctx context.Context, userID, id string) (*models.Watchlist, error) {
+	watchlist, err := s.watchlistRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWatchlistNotFound
+		}
+		return nil, err
+	}
+	if watchlist.UserID != userID {
+		return nil, ErrWatchlistNotFound
+	}
+
+	items, err := s.watchlistRepo.GetItems(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []*models.WatchlistItem{}
+	}
+	watchlist.Items = items
+	return watchlist, nil
+}
+
+func (s *WatchlistService) Rename(ctx context.Context, userID, id string, req *models.UpdateWatchlistRequest) (*models.Watchlist, error) {
+	watchlist, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	name, err := validateWatchlistName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	watchlist.Name = name
+	if err := s.watchlistRepo.Update(ctx, watchlist); err != nil {
+		return nil, fmt.Errorf("failed to update watchlist: %w", err)
+	}
+	return watchlist, nil
+}
+
+func (s *WatchlistService) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.watchlistRepo.Delete(ctx, id)
+}
+
+// Reorder puts the user's watchlists in the order given, which must name
+// every one of them exactly once
+func (s *WatchlistService) Reorder(ctx context.Context, userID string, ids []string) ([]*models.Watchlist, error) {
+	watchlists, err := s.watchlistRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	current := make([]string, len(watchlists))
+	for i, watchlist := range watchlists {
+		current[i] = watchlist.ID
+	}
+	if err := validateOrder(current, ids); err != nil {
+		return nil, err
+	}
+
+	if err := s.watchlistRepo.SetPositions(ctx, userID, ids); err != nil {
+		return nil, fmt.Errorf("failed to reorder watchlists: %w", err)
+	}
+	return s.List(ctx, userID)
+}
+
+// AddItem puts a symbol on the watchlist at the requested position, or at
+// the end
+func (s *WatchlistService) AddItem(ctx context.Context, userID, id string, req *models.AddWatchlistItemRequest) (*models.WatchlistItem, error) {
+	watchlist, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := normalizeWatchlistSymbol(req.StockSymbol)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := validateWatchlistNotes(req.Notes)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range watchlist.Items {
+		if item.StockSymbol == symbol {
+			return nil, ErrDuplicateWatchlistItem
+		}
+	}
+	if len(watchlist.Items) >= maxWatchlistItems {
+		return nil, fmt.Errorf("%w: a watchlist holds at most %d symbols", ErrInvalidWatchlist, maxWatchlistItems)
+	}
+
+	position := len(watchlist.Items)
+	if req.Position != nil {
+		if *req.Position < 0 || *req.Position > len(watchlist.Items) {
+			return nil, fmt.Errorf("%w: position must be between 0 and %d", ErrInvalidWatchlist, len(watchlist.Items))
+		}
+		position = *req.Position
+	}
+
+	item := &models.WatchlistItem{
+		WatchlistID: id,
+		StockSymbol: symbol,
+		Position:    position,
+		Notes:       notes,
+		AddedAt:     time.Now(),
+	}
+	if err := s.watchlistRepo.AddItem(ctx, item); err != nil {
+		return nil, fmt.Errorf("failed to add symbol: %w", err)
+	}
+	return item, nil
+}
+
+func (s *WatchlistService) UpdateItem(ctx context.Context, userID, id, symbol string, req *models.UpdateWatchlistItemRequest) (*models.WatchlistItem, error) {
+	watchlist, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := validateWatchlistNotes(req.Notes)
+	if err != nil {
+		return nil, err
+	}
+
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	for _, item := range watchlist.Items {
+		if item.StockSymbol != symbol {
+			continue
+		}
+		if err := s.watchlistRepo.UpdateItemNotes(ctx, id, symbol, notes); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrWatchlistItemNotFound
+			}
+			return nil, err
+		}
+		item.Notes = notes
+		return item, nil
+	}
+	return nil, ErrWatchlistItemNotFound
+}
+
+func (s *WatchlistService) RemoveItem(ctx context.Context, userID, id, symbol string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := s.watchlistRepo.RemoveItem(ctx, id, strings.ToUpper(strings.TrimSpace(symbol))); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWatchlistItemNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ReorderItems puts the watchlist's symbols in the order given, which must
+// name every one of them exactly once
+func (s *WatchlistService) ReorderItems(ctx context.Context, userID, id string, symbols []string) (*models.Watchlist, error) {
+	watchlist, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	current := make([]string, len(watchlist.Items))
+	for i, item := range watchlist.Items {
+		current[i] = item.StockSymbol
+	}
+	order := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		order[i] = strings.ToUpper(strings.TrimSpace(symbol))
+	}
+	if err := validateOrder(current, order); err != nil {
+		return nil, err
+	}
+
+	if err := s.watchlistRepo.SetItemPositions(ctx, id, order); err != nil {
+		return nil, fmt.Errorf("failed to reorder watchlist: %w", err)
+	}
+	return s.Get(ctx, userID, id)
+}
+
+// Quotes prices the watchlist's symbols from a single fetch of the live
+// board, which is served from the cache when it's fresh
+func (s *WatchlistService) Quotes(ctx context.Context, userID, id string) (*models.WatchlistQuotes, error) {
+	watchlist, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	stocks, err := s.stockService.GetAllStocks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch prices: %w", err)
+	}
+	board := boardBySymbol(stocks)
+
+	quotes := &models.WatchlistQuotes{
+		WatchlistID: id,
+		Quotes:      []models.EnhancedStock{},
+		Unavailable: []string{},
+	}
+	for _, item := range watchlist.Items {
+		if stock, ok := board[item.StockSymbol]; ok {
+			quotes.Quotes = append(quotes.Quotes, stock)
+		} else {
+			quotes.Unavailable = append(quotes.Unavailable, item.StockSymbol)
+		}
+	}
+	return quotes, nil
+}
+
+// CreateAlerts gives every symbol on the watchlist the standard alert set:
+// a price target risePercent above today's price and a dividend announcement
+// alert. Symbols that already have an active or paused alert of a type are
+// skipped for that type. The alerts are created all together or not at all.
+func (s *WatchlistService) CreateAlerts(ctx context.Context, userID, id string, req *models.WatchlistAlertsRequest) (*models.WatchlistAlertsResult, error) {
+	watchlist, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	risePercent := defaultWatchlistRisePercent
+	if req.RisePercent != nil {
+		risePercent = *req.RisePercent
+	}
+	if !(risePercent >= 0) || math.IsInf(risePercent, 0) {
+		return nil, fmt.Errorf("%w: risePercent cannot be negative", ErrInvalidWatchlist)
+	}
+	dividend := req.Dividend == nil || *req.Dividend
+
+	existing, err := s.alertRepo.GetByUserID(ctx, userID, models.AlertFilter{Scopes: []string{models.AlertScopeStock}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", err)
+	}
+	have := make(map[string]bool, len(existing))
+	for _, alert := range existing {
+		if alert.Status == models.AlertStatusActive || alert.Status == models.AlertStatusPaused {
+			have[strings.ToUpper(alert.StockSymbol)+"|"+alert.AlertType] = true
+		}
+	}
+
+	stocks, err := s.stockService.GetAllStocks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch prices: %w", err)
+	}
+	board := boardBySymbol(stocks)
+
+	result := &models.WatchlistAlertsResult{Created: []*models.Alert{}, Skipped: []*models.SkippedAlert{}}
+	skip := func(symbol, alertType, reason string) {
+		result.Skipped = append(result.Skipped, &models.SkippedAlert{StockSymbol: symbol, AlertType: alertType, Reason: reason})
+	}
+	now := time.Now()
+	newAlert := func(stock models.EnhancedStock, symbol, alertType string) *models.Alert {
+		alert := &models.Alert{
+			ID:          uuid.New().String(),
+			UserID:      userID,
+			Scope:       models.AlertScopeStock,
+			StockSymbol: symbol,
+			StockName:   stock.Name,
+			AlertType:   alertType,
+			Status:      models.AlertStatusActive,
+			Urgent:      req.Urgent,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if stock.CurrentPrice > 0 {
+			price := stock.CurrentPrice
+			alert.CurrentPrice = &price
+		}
+		return alert
+	}
+
+	for _, item := range watchlist.Items {
+		symbol := item.StockSymbol
+		stock, onBoard := board[symbol]
+		if !onBoard {
+			stock = models.EnhancedStock{Symbol: symbol, Name: symbol}
+		}
+
+		if risePercent > 0 {
+			switch {
+			case have[symbol+"|"+models.AlertTypePriceThreshold]:
+				skip(symbol, models.AlertTypePriceThreshold, "already has a price alert")
+			case !onBoard || !(stock.CurrentPrice > 0):
+				skip(symbol, models.AlertTypePriceThreshold, "no live price to set a target from")
+			default:
+				alert := newAlert(stock, symbol, models.AlertTypePriceThreshold)
+				target := math.Round(stock.CurrentPrice*(1+risePercent/100)*100) / 100
+				alert.ThresholdPrice = &target
+				result.Created = append(result.Created, alert)
+			}
+		}
+		if dividend {
+			if have[symbol+"|"+models.AlertTypeDividendAnnouncement] {
+				skip(symbol, models.AlertTypeDividendAnnouncement, "already has a dividend alert")
+			} else {
+				result.Created = append(result.Created, newAlert(stock, symbol, models.AlertTypeDividendAnnouncement))
+			}
+		}
+	}
+
+	if len(result.Created) > 0 {
+		if err := s.alertRepo.CreateAlerts(ctx, result.Created); err != nil {
+			return nil, fmt.Errorf("failed to create alerts: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func validateWatchlistName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrInvalidWatchlist)
+	}
+	if len(name) > maxWatchlistNameLength {
+		return "", fmt.Errorf("%w: name must be at most %d characters", ErrInvalidWatchlist, maxWatchlistNameLength)
+	}
+	return name, nil
+}
+
+func validateWatchlistNotes(notes string) (string, error) {
+	notes = strings.TrimSpace(notes)
+	if len(notes) > maxWatchlistNotes {
+		return "", fmt.Errorf("%w: notes must be at most %d characters", ErrInvalidWatchlist, maxWatchlistNotes)
+	}
+	return notes, nil
+}
+
+func normalizeWatchlistSymbol(symbol string) (string, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return "", fmt.Errorf("%w: stockSymbol is required", ErrInvalidWatchlist)
+	}
+	if len(symbol) > maxSymbolLength {
+		return "", fmt.Errorf("%w: stockSymbol must be at most %d characters", ErrInvalidWatchlist, maxSymbolLength)
+	}
+	return symbol, nil
+}
+
+// validateOrder checks that order is a rearrangement of current
+func validateOrder(current, order []string) error {
+	if len(order) != len(current) {
+		return fmt.Errorf("%w: order must list all %d entries", ErrInvalidWatchlist, len(current))
+	}
+	remaining := make(map[string]bool, len(current))
+	for _, key := range current {
+		remaining[key] = true
+	}
+	for _, key := range order {
+		if !remaining[key] {
+			return fmt.Errorf("%w: %q is unknown or listed twice", ErrInvalidWatchlist, key)
+		}
+		delete(remaining, key)
+	}
+	return nil
+}
@@ -32,6 +32,7 @@ const LoginPage: React.FC = () => {
   useEffect(() => {
     const urlParams = new URLSearchParams(window.location.search);
     const code = urlParams.get('code');
+    const state = urlParams.get('state');
     const error = urlParams.get('error');
 
     if (error) {
@@ -43,7 +44,7 @@ const LoginPage: React.FC = () => {
 
     if (code) {
       setIsAuthenticating(true);
-      login(code)
+      login(code, state ?? '')
         .then(() => {
           // Clean up URL
           window.history.replaceState({}, document.title, window.location.pathname);
@@ -65,11 +66,8 @@ const LoginPage: React.FC = () => {
       setError(null);
       setIsAuthenticating(true);
       
-      // Generate a random state for security
-      const state = Math.random().toString(36).substring(2, 15);
-      
       // Get Google OAuth URL from backend
-      const { authUrl } = await authApi.getGoogleAuthUrl(state);
+      const { authUrl } = await authApi.getGoogleAuthUrl();
       
       // Redirect to Google OAuth
       window.location.href = authUrl;
@@ -1,12 +1,12 @@
 import React, { createContext, useContext, useState, useEffect, ReactNode } from 'react';
-import { authApi, User } from '../services/api';
+import { authApi, onTokenRefresh, User } from '../services/api';
 
 interface AuthContextType {
   user: User | null;
   token: string | null;
   isLoading: boolean;
   isAuthenticated: boolean;
-  login: (code: string) => Promise<void>;
+  login: (code: string, state: string) => Promise<void>;
   logout: () => void;
   refreshUser: () => Promise<void>;
 }
@@ -36,12 +36,16 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
       } catch (error) {
         console.error('Failed to parse saved user data:', error);
         localStorage.removeItem('auth_token');
+        localStorage.removeItem('auth_refresh_token');
         localStorage.removeItem('auth_user');
       }
     }
     setIsLoading(false);
   }, []);
 
+  // Pick up access tokens issued when an expired one is refreshed
+  useEffect(() => onTokenRefresh(setToken), []);
+
   // Verify token validity when component mounts
   useEffect(() => {
     if (token && !isLoading) {
@@ -52,16 +56,17 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
     }
   }, [token, isLoading]);
 
-  const login = async (code: string) => {
+  const login = async (code: string, state: string) => {
     try {
       setIsLoading(true);
-      const response = await authApi.googleCallback(code);
+      const response = await authApi.googleCallback(code, state);
       
       setUser(response.user);
       setToken(response.token);
       
       // Save to localStorage
       localStorage.setItem('auth_token', response.token);
+      localStorage.setItem('auth_refresh_token', response.refreshToken);
       localStorage.setItem('auth_user', JSON.stringify(response.user));
     } catch (error) {
       console.error('Login failed:', error);
@@ -75,6 +80,7 @@ export const AuthProvider: React.FC<AuthProviderProps> = ({ children }) => {
     setUser(null);
     setToken(null);
     localStorage.removeItem('auth_token');
+    localStorage.removeItem('auth_refresh_token');
     localStorage.removeItem('auth_user');
     
     // Call backend logout if token exists
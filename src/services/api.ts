@@ -30,6 +30,55 @@ export const setAuthToken = (token: string | null) => {
 
 export const getAuthToken = () => authToken;
 
+// Access tokens are short lived. When one expires, the refresh token saved at
+// login is traded for a new pair and the request is retried.
+type TokenRefreshListener = (token: string) => void;
+const tokenRefreshListeners = new Set<TokenRefreshListener>();
+
+// Subscribe to new access tokens issued by a refresh; returns an unsubscribe function
+export const onTokenRefresh = (listener: TokenRefreshListener) => {
+  tokenRefreshListeners.add(listener);
+  return () => {
+    tokenRefreshListeners.delete(listener);
+  };
+};
+
+// Each refresh token works once, so concurrent 401s share a single refresh
+let pendingRefresh: Promise<string | null> | null = null;
+
+const refreshAccessToken = (): Promise<string | null> => {
+  if (!pendingRefresh) {
+    pendingRefresh = (async () => {
+      const refreshToken = localStorage.getItem('auth_refresh_token');
+      if (!refreshToken) return null;
+
+      try {
+        const response = await fetch(`${API_BASE_URL}/auth/refresh`, {
+          method: 'POST',
+          headers: {
+            'Content-Type': 'application/json',
+          },
+          body: JSON.stringify({ refreshToken }),
+        });
+        if (!response.ok) return null;
+
+        const data: Omit<AuthResponse, 'user'> = await response.json();
+        setAuthToken(data.token);
+        localStorage.setItem('auth_token', data.token);
+        localStorage.setItem('auth_refresh_token', data.refreshToken);
+        tokenRefreshListeners.forEach((listener) => listener(data.token));
+        return data.token;
+      } catch (error) {
+        console.error('Failed to refresh access token:', error);
+        return null;
+      }
+    })().finally(() => {
+      pendingRefresh = null;
+    });
+  }
+  return pendingRefresh;
+};
+
 // Helper function to make authenticated requests
 const makeAuthenticatedRequest = async (url: string, options: RequestInit = {}) => {
   const token = authToken || localStorage.getItem('auth_token');
@@ -43,14 +92,27 @@ const makeAuthenticatedRequest = async (url: string, options: RequestInit = {})
     headers['Authorization'] = `Bearer ${token}`;
   }
 
-  const response = await fetch(url, {
+  let response = await fetch(url, {
     ...options,
     headers,
   });
 
+  if (response.status === 401 && token) {
+    // The access token may just have expired, so refresh it and try once more
+    const refreshed = await refreshAccessToken();
+    if (refreshed) {
+      headers['Authorization'] = `Bearer ${refreshed}`;
+      response = await fetch(url, {
+        ...options,
+        headers,
+      });
+    }
+  }
+
   if (response.status === 401) {
-    // Token expired or invalid
+    // Token expired or invalid, and couldn't be refreshed
     localStorage.removeItem('auth_token');
+    localStorage.removeItem('auth_refresh_token');
     localStorage.removeItem('auth_user');
     window.location.href = '/login';
     throw new Error('Authentication required');
@@ -142,6 +204,8 @@ export interface Alert {
 export interface AuthResponse {
   user: User;
   token: string;
+  refreshToken: string;
+  expiresIn: number;
 }
 
 export interface CreateAlertRequest {
@@ -154,9 +218,11 @@ export interface CreateAlertRequest {
 // Authentication API functions
 export const authApi = {
   // Get Google OAuth URL
-  getGoogleAuthUrl: async (state?: string): Promise<{ authUrl: string }> => {
-    const params = state ? `?state=${encodeURIComponent(state)}` : '';
-    const response = await fetch(`${API_BASE_URL}/auth/google${params}`);
+  // The server issues the state, and a cookie tying it to this browser
+  getGoogleAuthUrl: async (): Promise<{ authUrl: string; state: string }> => {
+    const response = await fetch(`${API_BASE_URL}/auth/google`, {
+      credentials: 'include',
+    });
     if (!response.ok) {
       throw new Error('Failed to get Google auth URL');
     }
@@ -164,13 +230,14 @@ export const authApi = {
   },
 
   // Handle Google OAuth callback
-  googleCallback: async (code: string): Promise<AuthResponse> => {
+  googleCallback: async (code: string, state: string): Promise<AuthResponse> => {
     const response = await fetch(`${API_BASE_URL}/auth/google/callback`, {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
       },
-      body: JSON.stringify({ code }),
+      credentials: 'include',
+      body: JSON.stringify({ code, state }),
     });
     if (!response.ok) {
       const error = await response.text();
@@ -272,22 +339,31 @@ export const alertApi = {
     const params = new URLSearchParams();
     if (status) params.append('status', status);
     if (stockSymbol) params.append('stockSymbol', stockSymbol);
+    params.append('limit', '100');
     
-    const url = `${API_BASE_URL}/alerts${params.toString() ? `?${params.toString()}` : ''}`;
-    console.log('Fetching alerts from:', url);
+    const alerts: Alert[] = [];
     try {
-      const response = await makeAuthenticatedRequest(url);
-      console.log('Alerts response status:', response.status);
-      if (!response.ok) {
-        if (response.status === 401) {
-          console.warn('Authentication failed for alerts - user may need to re-login');
-          return []; // Return empty array instead of throwing error
+      // The API returns alerts a page at a time, so follow nextCursor to the end
+      let cursor = '';
+      do {
+        if (cursor) params.set('cursor', cursor);
+        const url = `${API_BASE_URL}/alerts?${params.toString()}`;
+        console.log('Fetching alerts from:', url);
+        const response = await makeAuthenticatedRequest(url);
+        console.log('Alerts response status:', response.status);
+        if (!response.ok) {
+          if (response.status === 401) {
+            console.warn('Authentication failed for alerts - user may need to re-login');
+            return []; // Return empty array instead of throwing error
+          }
+          throw new Error(`Failed to fetch alerts: ${response.status} ${response.statusText}`);
         }
-        throw new Error(`Failed to fetch alerts: ${response.status} ${response.statusText}`);
-      }
-      const data = await response.json();
-      console.log('Alerts data received:', Array.isArray(data) ? data.length : 'not an array', 'alerts');
-      return Array.isArray(data) ? data : [];
+        const data = await response.json();
+        if (Array.isArray(data?.alerts)) alerts.push(...data.alerts);
+        cursor = data?.nextCursor || '';
+      } while (cursor);
+      console.log('Alerts data received:', alerts.length, 'alerts');
+      return alerts;
     } catch (error) {
       console.error('Error in getAllAlerts:', error);
       // Return empty array instead of throwing to prevent app crash